@@ -19,10 +19,14 @@ func NewContext(ctx debugger.Context, dbg Debugger) Context {
 	return &context{ctx, dbg}
 }
 
+func (ctx *context) Unwrap() debugger.Context {
+	return ctx.Context
+}
+
 func (ctx *context) Errno() Errno {
-	return ctx.dbg.Errno()
+	return ctx.dbg.TaskErrno(ctx.TaskID())
 }
 
 func (ctx *context) SetErrno(err Errno) {
-	ctx.dbg.SetErrno(err)
+	ctx.dbg.SetTaskErrno(ctx.TaskID(), err)
 }
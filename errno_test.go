@@ -0,0 +1,68 @@
+package linux
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestErrnoString(t *testing.T) {
+	tests := []struct {
+		errno Errno
+		name  string
+		msg   string
+	}{
+		{ENOENT, "ENOENT", "No such file or directory"},
+		{EAGAIN, "EAGAIN", "Resource temporarily unavailable"},
+		{EHWPOISON, "EHWPOISON", "Memory page has hardware error"},
+		{Errno(58), "Errno(58)", "errno 58"},
+		{Errno(-1), "Errno(-1)", "errno -1"},
+	}
+	for _, tt := range tests {
+		if got := tt.errno.String(); got != tt.name {
+			t.Errorf("Errno(%d).String() = %q, want %q", int(tt.errno), got, tt.name)
+		}
+		if got := tt.errno.Error(); got != tt.msg {
+			t.Errorf("Errno(%d).Error() = %q, want %q", int(tt.errno), got, tt.msg)
+		}
+	}
+}
+
+func TestToErrno(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Errno
+	}{
+		{"nil", nil, 0},
+		{"errno", ENOTDIR, ENOTDIR},
+		{"wrapped errno", fmt.Errorf("open: %w", EISDIR), EISDIR},
+		{"host errno", syscall.ENOENT, ENOENT},
+		{"path error", &os.PathError{Op: "open", Path: "/x", Err: syscall.EACCES}, EACCES},
+		{"link error", &os.LinkError{Op: "rename", Old: "a", New: "b", Err: syscall.EXDEV}, EXDEV},
+		{"syscall error", os.NewSyscallError("pipe", syscall.EMFILE), EMFILE},
+		{"net op error", &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, ECONNREFUSED},
+		{"net timeout", &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}, ETIMEDOUT},
+		{"not exist", fs.ErrNotExist, ENOENT},
+		{"exist", fmt.Errorf("mkdir: %w", fs.ErrExist), EEXIST},
+		{"permission", fs.ErrPermission, EACCES},
+		{"invalid", fs.ErrInvalid, EINVAL},
+		{"closed", os.ErrClosed, EBADF},
+		{"closed pipe", io.ErrClosedPipe, EPIPE},
+		{"unsupported", errors.ErrUnsupported, EOPNOTSUPP},
+		{"eof", io.EOF, EIO},
+		{"unknown", errors.New("boom"), EIO},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToErrno(tt.err); got != tt.want {
+				t.Fatalf("ToErrno(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package linux
+
+import "fmt"
+
+type ExitStatus struct {
+	TaskID int
+	Code   int
+	Signal int
+	Group  bool
+}
+
+func (s *ExitStatus) Error() string {
+	if s.Signal != 0 {
+		return fmt.Sprintf("killed: task %d, signal %d", s.TaskID, s.Signal)
+	} else if s.Group {
+		return fmt.Sprintf("exit_group: task %d, status %d", s.TaskID, s.Code)
+	}
+	return fmt.Sprintf("exit: task %d, status %d", s.TaskID, s.Code)
+}
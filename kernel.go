@@ -1,12 +1,36 @@
 package linux
 
+type SyscallArgs [6]uint64
+
+type SyscallHandler = func(ctx Context, args *SyscallArgs) uint64
+type SyscallMiddleware = func(next SyscallHandler) SyscallHandler
+
+type SyscallStatus int
+
+const (
+	SyscallStatus_None SyscallStatus = iota
+	SyscallStatus_Implemented
+	SyscallStatus_Rejected
+	SyscallStatus_Ignored
+)
+
 type Syscall interface {
-	Get(nr NR) func(ctx Context, args ...uint64) uint64
+	Get(nr NR) SyscallHandler
+	Builtin(nr NR) SyscallHandler
+	Register(nr NR, handler SyscallHandler)
+	Unregister(nr NR)
+	Wrap(nr NR, middleware SyscallMiddleware) bool
+	Status(nr NR) SyscallStatus
+	List(status SyscallStatus) []NR
 }
 
 type Kernel interface {
 	NR(no uint64) NR
+	No(nr NR) (uint64, bool)
 	Syscall() Syscall
 	Errno() Errno
 	SetErrno(err Errno)
+	TaskErrno(tid int) Errno
+	SetTaskErrno(tid int, err Errno)
+	ExitStatus() *ExitStatus
 }
@@ -0,0 +1,108 @@
+package kernel
+
+import (
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+const (
+	F_OK = 0x0
+	X_OK = 0x1
+	W_OK = 0x2
+	R_OK = 0x4
+
+	AT_EACCESS = 0x200
+
+	OVERFLOWUID = 65534
+	OVERFLOWGID = 65534
+)
+
+type cred struct {
+	uid, euid uid_t
+	gid, egid gid_t
+}
+
+func (f *fcntl) setCred(c cred) {
+	f.rw.Lock()
+	f.cred = c
+	f.rw.Unlock()
+}
+
+func (f *fcntl) getCred() cred {
+	f.rw.RLock()
+	defer f.rw.RUnlock()
+	return f.cred
+}
+
+func (f *fcntl) faccessat2(ctx linux.Context, dfd int32, filename emuptr, mode, flags int32) int32 {
+	if mode&^(R_OK|W_OK|X_OK) != 0 || flags&^(AT_EACCESS|AT_SYMLINK_NOFOLLOW|AT_EMPTY_PATH) != 0 {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	st, errno := f.statat(ctx, dfd, filename, flags&(AT_SYMLINK_NOFOLLOW|AT_EMPTY_PATH))
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	} else if mode == F_OK {
+		return 0
+	}
+	if mode&W_OK != 0 && st.writeChecksFS() {
+		if dir, _, errno := f.pathat(ctx, dfd, filename); errno == 0 && readOnly(dir) {
+			ctx.SetErrno(linux.EROFS)
+			return -1
+		}
+	}
+	c := f.getCred()
+	uid, gid := c.uid, c.gid
+	if flags&AT_EACCESS != 0 {
+		uid, gid = c.euid, c.egid
+	}
+	if !st.permits(uid, gid, mode_t(mode)) {
+		ctx.SetErrno(linux.EACCES)
+		return -1
+	}
+	return 0
+}
+
+// readOnly reports whether fsys lacks the optional mutation interfaces, in
+// which case namespace changes already fail with EROFS.
+func readOnly(fsys filesystem.FS) bool {
+	_, ok := fsys.(RemoveFS)
+	return !ok
+}
+
+func lowUID(uid uid_t) uid_t {
+	if uid > 0xFFFF {
+		return OVERFLOWUID
+	}
+	return uid
+}
+
+func lowGID(gid gid_t) gid_t {
+	if gid > 0xFFFF {
+		return OVERFLOWGID
+	}
+	return gid
+}
+
+func (st *kstat) writeChecksFS() bool {
+	switch st.mode & S_IFMT {
+	case S_IFREG, S_IFDIR, S_IFLNK:
+		return true
+	}
+	return false
+}
+
+func (st *kstat) permits(uid uid_t, gid gid_t, mask mode_t) bool {
+	if uid == 0 {
+		return mask&X_OK == 0 || st.mode&S_IFMT == S_IFDIR || st.mode&0111 != 0
+	}
+	perm := st.mode
+	switch {
+	case uid == st.uid:
+		perm >>= 6
+	case gid == st.gid:
+		perm >>= 3
+	}
+	return mask&^perm&7 == 0
+}
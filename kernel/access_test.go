@@ -0,0 +1,132 @@
+package kernel
+
+import (
+	"testing"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/emulator"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+func TestFaccessat(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	at := uint64(testAT_FDCWD)
+	fd := uint64(tk.create("file", nil))
+	tk.create("exec", nil)
+	tk.call(linux.NR_fchmodat, at, tk.cstring("file"), 0640)
+	tk.call(linux.NR_fchmodat, at, tk.cstring("exec"), 0700)
+	tk.call(linux.NR_mkdirat, at, tk.cstring("dir"), 0755)
+	tk.call(linux.NR_symlinkat, tk.cstring("missing"), at, tk.cstring("dangling"))
+	tk.call(linux.NR_fchownat, at, tk.cstring("file"), 1000, 100, 0)
+	access := func(name string, mode, flags uint64) linux.Errno {
+		_, errno := tk.call(linux.NR_faccessat2, at, tk.cstring(name), mode, flags)
+		return errno
+	}
+
+	root := []struct {
+		name  string
+		path  string
+		mode  uint64
+		errno linux.Errno
+	}{
+		{"exists", "file", F_OK, 0},
+		{"read write", "file", R_OK | W_OK, 0},
+		{"exec", "file", X_OK, linux.EACCES},
+		{"exec bit", "exec", R_OK | X_OK, 0},
+		{"search dir", "dir", X_OK, 0},
+		{"missing", "missing", F_OK, linux.ENOENT},
+		{"dangling", "dangling", F_OK, linux.ENOENT},
+	}
+	for _, tt := range root {
+		t.Run(tt.name, func(t *testing.T) {
+			if errno := access(tt.path, tt.mode, 0); errno != tt.errno {
+				t.Fatalf("errno = %v, want %v", errno, tt.errno)
+			}
+		})
+	}
+
+	tk.SetCred(1000, 100, 1000, 100)
+	user := []struct {
+		name  string
+		path  string
+		mode  uint64
+		errno linux.Errno
+	}{
+		{"owner", "file", R_OK | W_OK, 0},
+		{"owner exec", "file", X_OK, linux.EACCES},
+		{"other read", "exec", R_OK, linux.EACCES},
+		{"other search", "dir", R_OK | X_OK, 0},
+		{"other write", "dir", W_OK, linux.EACCES},
+	}
+	for _, tt := range user {
+		t.Run(tt.name, func(t *testing.T) {
+			if errno := access(tt.path, tt.mode, 0); errno != tt.errno {
+				t.Fatalf("errno = %v, want %v", errno, tt.errno)
+			}
+		})
+	}
+
+	tk.SetCred(2000, 100, 2000, 100)
+	if errno := access("file", R_OK, 0); errno != 0 {
+		t.Fatalf("group read errno = %v", errno)
+	}
+	if errno := access("file", W_OK, 0); errno != linux.EACCES {
+		t.Fatalf("group write errno = %v, want EACCES", errno)
+	}
+	tk.SetCred(2000, 200, 0, 0)
+	if errno := access("exec", R_OK, 0); errno != linux.EACCES {
+		t.Fatalf("real uid errno = %v, want EACCES", errno)
+	}
+	if errno := access("exec", R_OK, AT_EACCESS); errno != 0 {
+		t.Fatalf("AT_EACCESS errno = %v", errno)
+	}
+	if errno := access("dangling", F_OK, AT_SYMLINK_NOFOLLOW); errno != 0 {
+		t.Fatalf("AT_SYMLINK_NOFOLLOW errno = %v", errno)
+	}
+	if _, errno := tk.call(linux.NR_faccessat2, fd, tk.cstring(""), W_OK, AT_EMPTY_PATH); errno != linux.EACCES {
+		t.Fatalf("AT_EMPTY_PATH errno = %v, want EACCES", errno)
+	}
+	if errno := access("file", 8, 0); errno != linux.EINVAL {
+		t.Fatalf("bad mode errno = %v, want EINVAL", errno)
+	}
+	if errno := access("file", F_OK, 1); errno != linux.EINVAL {
+		t.Fatalf("bad flags errno = %v, want EINVAL", errno)
+	}
+	if _, errno := tk.call(linux.NR_faccessat, at, tk.cstring("exec"), X_OK); errno != linux.EACCES {
+		t.Fatalf("faccessat errno = %v, want EACCES", errno)
+	}
+}
+
+func TestFaccessatReadOnlyFS(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	tk.create("file", nil)
+	tk.dbg.fs = filesystem.SysDirFS(tk.dbg.root)
+	at := uint64(testAT_FDCWD)
+	if _, errno := tk.call(linux.NR_faccessat, at, tk.cstring("file"), R_OK); errno != 0 {
+		t.Fatalf("R_OK errno = %v", errno)
+	}
+	if _, errno := tk.call(linux.NR_faccessat, at, tk.cstring("file"), W_OK); errno != linux.EROFS {
+		t.Fatalf("W_OK errno = %v, want EROFS", errno)
+	}
+}
+
+func TestGetuid(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM)
+	tk.SetCred(1000, 100, 70000, 0)
+	tests := []struct {
+		nr   linux.NR
+		want uint64
+	}{
+		{linux.NR_getuid, 1000},
+		{linux.NR_getgid, 100},
+		{linux.NR_geteuid, 70000},
+		{linux.NR_getegid, 0},
+		{linux.NR_getuid16, 1000},
+		{linux.NR_geteuid16, OVERFLOWUID},
+	}
+	for _, tt := range tests {
+		if r, errno := tk.call(tt.nr); errno != 0 || r != tt.want {
+			t.Errorf("%v = %d, errno = %v, want %d", tt.nr, r, errno, tt.want)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package kernel
+
+import linux "github.com/wnxd/microdbg-linux"
+
+var arm64NR = [...]linux.NR{
+	0:   linux.NR_io_setup,
+	1:   linux.NR_io_destroy,
+	2:   linux.NR_io_submit,
+	3:   linux.NR_io_cancel,
+	4:   linux.NR_io_getevents,
+	5:   linux.NR_setxattr,
+	6:   linux.NR_lsetxattr,
+	7:   linux.NR_fsetxattr,
+	8:   linux.NR_getxattr,
+	9:   linux.NR_lgetxattr,
+	10:  linux.NR_fgetxattr,
+	11:  linux.NR_listxattr,
+	12:  linux.NR_llistxattr,
+	13:  linux.NR_flistxattr,
+	14:  linux.NR_removexattr,
+	15:  linux.NR_lremovexattr,
+	16:  linux.NR_fremovexattr,
+	17:  linux.NR_getcwd,
+	18:  linux.NR_lookup_dcookie,
+	19:  linux.NR_eventfd2,
+	20:  linux.NR_epoll_create1,
+	21:  linux.NR_epoll_ctl,
+	22:  linux.NR_epoll_pwait,
+	23:  linux.NR_dup,
+	24:  linux.NR_dup3,
+	25:  linux.NR_fcntl,
+	26:  linux.NR_inotify_init1,
+	27:  linux.NR_inotify_add_watch,
+	28:  linux.NR_inotify_rm_watch,
+	29:  linux.NR_ioctl,
+	30:  linux.NR_ioprio_set,
+	31:  linux.NR_ioprio_get,
+	32:  linux.NR_flock,
+	33:  linux.NR_mknodat,
+	34:  linux.NR_mkdirat,
+	35:  linux.NR_unlinkat,
+	36:  linux.NR_symlinkat,
+	37:  linux.NR_linkat,
+	38:  linux.NR_renameat,
+	39:  linux.NR_umount2,
+	40:  linux.NR_mount,
+	41:  linux.NR_pivot_root,
+	42:  linux.NR_nfsservctl,
+	43:  linux.NR_statfs,
+	44:  linux.NR_fstatfs,
+	45:  linux.NR_truncate,
+	46:  linux.NR_ftruncate,
+	47:  linux.NR_fallocate,
+	48:  linux.NR_faccessat,
+	49:  linux.NR_chdir,
+	50:  linux.NR_fchdir,
+	51:  linux.NR_chroot,
+	52:  linux.NR_fchmod,
+	53:  linux.NR_fchmodat,
+	54:  linux.NR_fchownat,
+	55:  linux.NR_fchown,
+	56:  linux.NR_openat,
+	57:  linux.NR_close,
+	58:  linux.NR_vhangup,
+	59:  linux.NR_pipe2,
+	60:  linux.NR_quotactl,
+	61:  linux.NR_getdents64,
+	62:  linux.NR_lseek,
+	63:  linux.NR_read,
+	64:  linux.NR_write,
+	65:  linux.NR_readv,
+	66:  linux.NR_writev,
+	67:  linux.NR_pread64,
+	68:  linux.NR_pwrite64,
+	69:  linux.NR_preadv,
+	70:  linux.NR_pwritev,
+	71:  linux.NR_sendfile,
+	72:  linux.NR_pselect6,
+	73:  linux.NR_ppoll,
+	74:  linux.NR_signalfd4,
+	75:  linux.NR_vmsplice,
+	76:  linux.NR_splice,
+	77:  linux.NR_tee,
+	78:  linux.NR_readlinkat,
+	79:  linux.NR_fstatat64,
+	80:  linux.NR_fstat64,
+	81:  linux.NR_sync,
+	82:  linux.NR_fsync,
+	83:  linux.NR_fdatasync,
+	84:  linux.NR_sync_file_range,
+	85:  linux.NR_timerfd_create,
+	86:  linux.NR_timerfd_settime,
+	87:  linux.NR_timerfd_gettime,
+	88:  linux.NR_utimensat,
+	89:  linux.NR_acct,
+	90:  linux.NR_capget,
+	91:  linux.NR_capset,
+	92:  linux.NR_personality,
+	93:  linux.NR_exit,
+	94:  linux.NR_exit_group,
+	95:  linux.NR_waitid,
+	96:  linux.NR_set_tid_address,
+	97:  linux.NR_unshare,
+	98:  linux.NR_futex,
+	99:  linux.NR_set_robust_list,
+	100: linux.NR_get_robust_list,
+	101: linux.NR_nanosleep,
+	102: linux.NR_getitimer,
+	103: linux.NR_setitimer,
+	104: linux.NR_kexec_load,
+	105: linux.NR_init_module,
+	106: linux.NR_delete_module,
+	107: linux.NR_timer_create,
+	108: linux.NR_timer_gettime,
+	109: linux.NR_timer_getoverrun,
+	110: linux.NR_timer_settime,
+	111: linux.NR_timer_delete,
+	112: linux.NR_clock_settime,
+	113: linux.NR_clock_gettime,
+	114: linux.NR_clock_getres,
+	115: linux.NR_clock_nanosleep,
+	116: linux.NR_syslog,
+	117: linux.NR_ptrace,
+	118: linux.NR_sched_setparam,
+	119: linux.NR_sched_setscheduler,
+	120: linux.NR_sched_getscheduler,
+	121: linux.NR_sched_getparam,
+	122: linux.NR_sched_setaffinity,
+	123: linux.NR_sched_getaffinity,
+	124: linux.NR_sched_yield,
+	125: linux.NR_sched_get_priority_max,
+	126: linux.NR_sched_get_priority_min,
+	127: linux.NR_sched_rr_get_interval,
+	128: linux.NR_restart_syscall,
+	129: linux.NR_kill,
+	130: linux.NR_tkill,
+	131: linux.NR_tgkill,
+	132: linux.NR_sigaltstack,
+	133: linux.NR_rt_sigsuspend,
+	134: linux.NR_rt_sigaction,
+	135: linux.NR_rt_sigprocmask,
+	136: linux.NR_rt_sigpending,
+	137: linux.NR_rt_sigtimedwait,
+	138: linux.NR_rt_sigqueueinfo,
+	139: linux.NR_rt_sigreturn,
+	140: linux.NR_setpriority,
+	141: linux.NR_getpriority,
+	142: linux.NR_reboot,
+	143: linux.NR_setregid,
+	144: linux.NR_setgid,
+	145: linux.NR_setreuid,
+	146: linux.NR_setuid,
+	147: linux.NR_setresuid,
+	148: linux.NR_getresuid,
+	149: linux.NR_setresgid,
+	150: linux.NR_getresgid,
+	151: linux.NR_setfsuid,
+	152: linux.NR_setfsgid,
+	153: linux.NR_times,
+	154: linux.NR_setpgid,
+	155: linux.NR_getpgid,
+	156: linux.NR_getsid,
+	157: linux.NR_setsid,
+	158: linux.NR_getgroups,
+	159: linux.NR_setgroups,
+	160: linux.NR_uname,
+	161: linux.NR_sethostname,
+	162: linux.NR_setdomainname,
+	163: linux.NR_getrlimit,
+	164: linux.NR_setrlimit,
+	165: linux.NR_getrusage,
+	166: linux.NR_umask,
+	167: linux.NR_prctl,
+	168: linux.NR_getcpu,
+	169: linux.NR_gettimeofday,
+	170: linux.NR_settimeofday,
+	171: linux.NR_adjtimex,
+	172: linux.NR_getpid,
+	173: linux.NR_getppid,
+	174: linux.NR_getuid,
+	175: linux.NR_geteuid,
+	176: linux.NR_getgid,
+	177: linux.NR_getegid,
+	178: linux.NR_gettid,
+	179: linux.NR_sysinfo,
+	180: linux.NR_mq_open,
+	181: linux.NR_mq_unlink,
+	182: linux.NR_mq_timedsend,
+	183: linux.NR_mq_timedreceive,
+	184: linux.NR_mq_notify,
+	185: linux.NR_mq_getsetattr,
+	186: linux.NR_msgget,
+	187: linux.NR_msgctl,
+	188: linux.NR_msgrcv,
+	189: linux.NR_msgsnd,
+	190: linux.NR_semget,
+	191: linux.NR_semctl,
+	192: linux.NR_semtimedop,
+	193: linux.NR_semop,
+	194: linux.NR_shmget,
+	195: linux.NR_shmctl,
+	196: linux.NR_shmat,
+	197: linux.NR_shmdt,
+	198: linux.NR_socket,
+	199: linux.NR_socketpair,
+	200: linux.NR_bind,
+	201: linux.NR_listen,
+	202: linux.NR_accept,
+	203: linux.NR_connect,
+	204: linux.NR_getsockname,
+	205: linux.NR_getpeername,
+	206: linux.NR_sendto,
+	207: linux.NR_recvfrom,
+	208: linux.NR_setsockopt,
+	209: linux.NR_getsockopt,
+	210: linux.NR_shutdown,
+	211: linux.NR_sendmsg,
+	212: linux.NR_recvmsg,
+	213: linux.NR_readahead,
+	214: linux.NR_brk,
+	215: linux.NR_munmap,
+	216: linux.NR_mremap,
+	217: linux.NR_add_key,
+	218: linux.NR_request_key,
+	219: linux.NR_keyctl,
+	220: linux.NR_clone,
+	221: linux.NR_execve,
+	222: linux.NR_mmap,
+	223: linux.NR_fadvise64,
+	224: linux.NR_swapon,
+	225: linux.NR_swapoff,
+	226: linux.NR_mprotect,
+	227: linux.NR_msync,
+	228: linux.NR_mlock,
+	229: linux.NR_munlock,
+	230: linux.NR_mlockall,
+	231: linux.NR_munlockall,
+	232: linux.NR_mincore,
+	233: linux.NR_madvise,
+	234: linux.NR_remap_file_pages,
+	235: linux.NR_mbind,
+	236: linux.NR_get_mempolicy,
+	237: linux.NR_set_mempolicy,
+	238: linux.NR_migrate_pages,
+	239: linux.NR_move_pages,
+	240: linux.NR_rt_tgsigqueueinfo,
+	241: linux.NR_perf_event_open,
+	242: linux.NR_accept4,
+	243: linux.NR_recvmmsg,
+	244: linux.NR_arch_specific_syscall,
+	260: linux.NR_wait4,
+	261: linux.NR_prlimit64,
+	262: linux.NR_fanotify_init,
+	263: linux.NR_fanotify_mark,
+	264: linux.NR_name_to_handle_at,
+	265: linux.NR_open_by_handle_at,
+	266: linux.NR_clock_adjtime,
+	267: linux.NR_syncfs,
+	268: linux.NR_setns,
+	269: linux.NR_sendmmsg,
+	270: linux.NR_process_vm_readv,
+	271: linux.NR_process_vm_writev,
+	272: linux.NR_kcmp,
+	273: linux.NR_finit_module,
+	274: linux.NR_sched_setattr,
+	275: linux.NR_sched_getattr,
+	276: linux.NR_renameat2,
+	277: linux.NR_seccomp,
+	278: linux.NR_getrandom,
+	279: linux.NR_memfd_create,
+	280: linux.NR_bpf,
+	281: linux.NR_execveat,
+	282: linux.NR_userfaultfd,
+	283: linux.NR_membarrier,
+	284: linux.NR_mlock2,
+	285: linux.NR_copy_file_range,
+	286: linux.NR_preadv2,
+	287: linux.NR_pwritev2,
+	288: linux.NR_pkey_mprotect,
+	289: linux.NR_pkey_alloc,
+	290: linux.NR_pkey_free,
+	291: linux.NR_statx,
+	292: linux.NR_io_pgetevents,
+	293: linux.NR_rseq,
+	294: linux.NR_kexec_file_load,
+	424: linux.NR_pidfd_send_signal,
+	425: linux.NR_io_uring_setup,
+	426: linux.NR_io_uring_enter,
+	427: linux.NR_io_uring_register,
+	428: linux.NR_open_tree,
+	429: linux.NR_move_mount,
+	430: linux.NR_fsopen,
+	431: linux.NR_fsconfig,
+	432: linux.NR_fsmount,
+	433: linux.NR_fspick,
+	434: linux.NR_pidfd_open,
+	435: linux.NR_clone3,
+	436: linux.NR_close_range,
+	437: linux.NR_openat2,
+	438: linux.NR_pidfd_getfd,
+	439: linux.NR_faccessat2,
+	440: linux.NR_process_madvise,
+	441: linux.NR_epoll_pwait2,
+	442: linux.NR_mount_setattr,
+	443: linux.NR_quotactl_fd,
+	444: linux.NR_landlock_create_ruleset,
+	445: linux.NR_landlock_add_rule,
+	446: linux.NR_landlock_restrict_self,
+	447: linux.NR_memfd_secret,
+	448: linux.NR_process_mrelease,
+	449: linux.NR_futex_waitv,
+	450: linux.NR_set_mempolicy_home_node,
+	451: linux.NR_cachestat,
+	452: linux.NR_fchmodat2,
+	453: linux.NR_map_shadow_stack,
+	454: linux.NR_futex_wake,
+	455: linux.NR_futex_wait,
+	456: linux.NR_futex_requeue,
+	457: linux.NR_statmount,
+	458: linux.NR_listmount,
+	459: linux.NR_lsm_get_self_attr,
+	460: linux.NR_lsm_set_self_attr,
+	461: linux.NR_lsm_list_modules,
+	462: linux.NR_mseal,
+	463: linux.NR_setxattrat,
+	464: linux.NR_getxattrat,
+	465: linux.NR_listxattrat,
+	466: linux.NR_removexattrat,
+}
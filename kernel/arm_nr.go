@@ -0,0 +1,425 @@
+package kernel
+
+import linux "github.com/wnxd/microdbg-linux"
+
+var armNR = [...]linux.NR{
+	0:   linux.NR_restart_syscall,
+	1:   linux.NR_exit,
+	2:   linux.NR_fork,
+	3:   linux.NR_read,
+	4:   linux.NR_write,
+	5:   linux.NR_open,
+	6:   linux.NR_close,
+	8:   linux.NR_creat,
+	9:   linux.NR_link,
+	10:  linux.NR_unlink,
+	11:  linux.NR_execve,
+	12:  linux.NR_chdir,
+	14:  linux.NR_mknod,
+	15:  linux.NR_chmod,
+	16:  linux.NR_lchown16,
+	19:  linux.NR_lseek,
+	20:  linux.NR_getpid,
+	21:  linux.NR_mount,
+	23:  linux.NR_setuid16,
+	24:  linux.NR_getuid16,
+	26:  linux.NR_ptrace,
+	29:  linux.NR_pause,
+	33:  linux.NR_access,
+	34:  linux.NR_nice,
+	36:  linux.NR_sync,
+	37:  linux.NR_kill,
+	38:  linux.NR_rename,
+	39:  linux.NR_mkdir,
+	40:  linux.NR_rmdir,
+	41:  linux.NR_dup,
+	42:  linux.NR_pipe,
+	43:  linux.NR_times,
+	45:  linux.NR_brk,
+	46:  linux.NR_setgid16,
+	47:  linux.NR_getgid16,
+	49:  linux.NR_geteuid16,
+	50:  linux.NR_getegid16,
+	51:  linux.NR_acct,
+	52:  linux.NR_umount2,
+	54:  linux.NR_ioctl,
+	55:  linux.NR_fcntl,
+	57:  linux.NR_setpgid,
+	60:  linux.NR_umask,
+	61:  linux.NR_chroot,
+	62:  linux.NR_ustat,
+	63:  linux.NR_dup2,
+	64:  linux.NR_getppid,
+	65:  linux.NR_getpgrp,
+	66:  linux.NR_setsid,
+	67:  linux.NR_sigaction,
+	70:  linux.NR_setreuid16,
+	71:  linux.NR_setregid16,
+	72:  linux.NR_sigsuspend,
+	73:  linux.NR_sigpending,
+	74:  linux.NR_sethostname,
+	75:  linux.NR_setrlimit,
+	77:  linux.NR_getrusage,
+	78:  linux.NR_gettimeofday,
+	79:  linux.NR_settimeofday,
+	80:  linux.NR_getgroups16,
+	81:  linux.NR_setgroups16,
+	83:  linux.NR_symlink,
+	85:  linux.NR_readlink,
+	86:  linux.NR_uselib,
+	87:  linux.NR_swapon,
+	88:  linux.NR_reboot,
+	91:  linux.NR_munmap,
+	92:  linux.NR_truncate,
+	93:  linux.NR_ftruncate,
+	94:  linux.NR_fchmod,
+	95:  linux.NR_fchown16,
+	96:  linux.NR_getpriority,
+	97:  linux.NR_setpriority,
+	99:  linux.NR_statfs,
+	100: linux.NR_fstatfs,
+	103: linux.NR_syslog,
+	104: linux.NR_setitimer,
+	105: linux.NR_getitimer,
+	106: linux.NR_stat,
+	107: linux.NR_lstat,
+	108: linux.NR_fstat,
+	111: linux.NR_vhangup,
+	114: linux.NR_wait4,
+	115: linux.NR_swapoff,
+	116: linux.NR_sysinfo,
+	118: linux.NR_fsync,
+	119: linux.NR_sigreturn,
+	120: linux.NR_clone,
+	121: linux.NR_setdomainname,
+	122: linux.NR_uname,
+	124: linux.NR_adjtimex,
+	125: linux.NR_mprotect,
+	126: linux.NR_sigprocmask,
+	128: linux.NR_init_module,
+	129: linux.NR_delete_module,
+	131: linux.NR_quotactl,
+	132: linux.NR_getpgid,
+	133: linux.NR_fchdir,
+	134: linux.NR_bdflush,
+	135: linux.NR_sysfs,
+	136: linux.NR_personality,
+	138: linux.NR_setfsuid16,
+	139: linux.NR_setfsgid16,
+	140: linux.NR_llseek,
+	141: linux.NR_getdents,
+	142: linux.NR_newselect,
+	143: linux.NR_flock,
+	144: linux.NR_msync,
+	145: linux.NR_readv,
+	146: linux.NR_writev,
+	147: linux.NR_getsid,
+	148: linux.NR_fdatasync,
+	149: linux.NR_sysctl,
+	150: linux.NR_mlock,
+	151: linux.NR_munlock,
+	152: linux.NR_mlockall,
+	153: linux.NR_munlockall,
+	154: linux.NR_sched_setparam,
+	155: linux.NR_sched_getparam,
+	156: linux.NR_sched_setscheduler,
+	157: linux.NR_sched_getscheduler,
+	158: linux.NR_sched_yield,
+	159: linux.NR_sched_get_priority_max,
+	160: linux.NR_sched_get_priority_min,
+	161: linux.NR_sched_rr_get_interval,
+	162: linux.NR_nanosleep,
+	163: linux.NR_mremap,
+	164: linux.NR_setresuid16,
+	165: linux.NR_getresuid16,
+	168: linux.NR_poll,
+	169: linux.NR_nfsservctl,
+	170: linux.NR_setresgid16,
+	171: linux.NR_getresgid16,
+	172: linux.NR_prctl,
+	173: linux.NR_rt_sigreturn,
+	174: linux.NR_rt_sigaction,
+	175: linux.NR_rt_sigprocmask,
+	176: linux.NR_rt_sigpending,
+	177: linux.NR_rt_sigtimedwait,
+	178: linux.NR_rt_sigqueueinfo,
+	179: linux.NR_rt_sigsuspend,
+	180: linux.NR_pread64,
+	181: linux.NR_pwrite64,
+	182: linux.NR_chown16,
+	183: linux.NR_getcwd,
+	184: linux.NR_capget,
+	185: linux.NR_capset,
+	186: linux.NR_sigaltstack,
+	187: linux.NR_sendfile,
+	190: linux.NR_vfork,
+	191: linux.NR_getrlimit,
+	192: linux.NR_mmap2,
+	193: linux.NR_truncate64,
+	194: linux.NR_ftruncate64,
+	195: linux.NR_stat64,
+	196: linux.NR_lstat64,
+	197: linux.NR_fstat64,
+	198: linux.NR_lchown,
+	199: linux.NR_getuid,
+	200: linux.NR_getgid,
+	201: linux.NR_geteuid,
+	202: linux.NR_getegid,
+	203: linux.NR_setreuid,
+	204: linux.NR_setregid,
+	205: linux.NR_getgroups,
+	206: linux.NR_setgroups,
+	207: linux.NR_fchown,
+	208: linux.NR_setresuid,
+	209: linux.NR_getresuid,
+	210: linux.NR_setresgid,
+	211: linux.NR_getresgid,
+	212: linux.NR_chown,
+	213: linux.NR_setuid,
+	214: linux.NR_setgid,
+	215: linux.NR_setfsuid,
+	216: linux.NR_setfsgid,
+	217: linux.NR_getdents64,
+	218: linux.NR_pivot_root,
+	219: linux.NR_mincore,
+	220: linux.NR_madvise,
+	221: linux.NR_fcntl64,
+	224: linux.NR_gettid,
+	225: linux.NR_readahead,
+	226: linux.NR_setxattr,
+	227: linux.NR_lsetxattr,
+	228: linux.NR_fsetxattr,
+	229: linux.NR_getxattr,
+	230: linux.NR_lgetxattr,
+	231: linux.NR_fgetxattr,
+	232: linux.NR_listxattr,
+	233: linux.NR_llistxattr,
+	234: linux.NR_flistxattr,
+	235: linux.NR_removexattr,
+	236: linux.NR_lremovexattr,
+	237: linux.NR_fremovexattr,
+	238: linux.NR_tkill,
+	239: linux.NR_sendfile64,
+	240: linux.NR_futex,
+	241: linux.NR_sched_setaffinity,
+	242: linux.NR_sched_getaffinity,
+	243: linux.NR_io_setup,
+	244: linux.NR_io_destroy,
+	245: linux.NR_io_getevents,
+	246: linux.NR_io_submit,
+	247: linux.NR_io_cancel,
+	248: linux.NR_exit_group,
+	249: linux.NR_lookup_dcookie,
+	250: linux.NR_epoll_create,
+	251: linux.NR_epoll_ctl,
+	252: linux.NR_epoll_wait,
+	253: linux.NR_remap_file_pages,
+	256: linux.NR_set_tid_address,
+	257: linux.NR_timer_create,
+	258: linux.NR_timer_settime,
+	259: linux.NR_timer_gettime,
+	260: linux.NR_timer_getoverrun,
+	261: linux.NR_timer_delete,
+	262: linux.NR_clock_settime,
+	263: linux.NR_clock_gettime,
+	264: linux.NR_clock_getres,
+	265: linux.NR_clock_nanosleep,
+	266: linux.NR_statfs64,
+	267: linux.NR_fstatfs64,
+	268: linux.NR_tgkill,
+	269: linux.NR_utimes,
+	270: linux.NR_fadvise64_64,
+	271: linux.NR_pciconfig_iobase,
+	272: linux.NR_pciconfig_read,
+	273: linux.NR_pciconfig_write,
+	274: linux.NR_mq_open,
+	275: linux.NR_mq_unlink,
+	276: linux.NR_mq_timedsend,
+	277: linux.NR_mq_timedreceive,
+	278: linux.NR_mq_notify,
+	279: linux.NR_mq_getsetattr,
+	280: linux.NR_waitid,
+	281: linux.NR_socket,
+	282: linux.NR_bind,
+	283: linux.NR_connect,
+	284: linux.NR_listen,
+	285: linux.NR_accept,
+	286: linux.NR_getsockname,
+	287: linux.NR_getpeername,
+	288: linux.NR_socketpair,
+	289: linux.NR_send,
+	290: linux.NR_sendto,
+	291: linux.NR_recv,
+	292: linux.NR_recvfrom,
+	293: linux.NR_shutdown,
+	294: linux.NR_setsockopt,
+	295: linux.NR_getsockopt,
+	296: linux.NR_sendmsg,
+	297: linux.NR_recvmsg,
+	298: linux.NR_semop,
+	299: linux.NR_semget,
+	300: linux.NR_semctl,
+	301: linux.NR_msgsnd,
+	302: linux.NR_msgrcv,
+	303: linux.NR_msgget,
+	304: linux.NR_msgctl,
+	305: linux.NR_shmat,
+	306: linux.NR_shmdt,
+	307: linux.NR_shmget,
+	308: linux.NR_shmctl,
+	309: linux.NR_add_key,
+	310: linux.NR_request_key,
+	311: linux.NR_keyctl,
+	312: linux.NR_semtimedop,
+	313: linux.NR_vserver,
+	314: linux.NR_ioprio_set,
+	315: linux.NR_ioprio_get,
+	316: linux.NR_inotify_init,
+	317: linux.NR_inotify_add_watch,
+	318: linux.NR_inotify_rm_watch,
+	319: linux.NR_mbind,
+	320: linux.NR_get_mempolicy,
+	321: linux.NR_set_mempolicy,
+	322: linux.NR_openat,
+	323: linux.NR_mkdirat,
+	324: linux.NR_mknodat,
+	325: linux.NR_fchownat,
+	326: linux.NR_futimesat,
+	327: linux.NR_fstatat64,
+	328: linux.NR_unlinkat,
+	329: linux.NR_renameat,
+	330: linux.NR_linkat,
+	331: linux.NR_symlinkat,
+	332: linux.NR_readlinkat,
+	333: linux.NR_fchmodat,
+	334: linux.NR_faccessat,
+	335: linux.NR_pselect6,
+	336: linux.NR_ppoll,
+	337: linux.NR_unshare,
+	338: linux.NR_set_robust_list,
+	339: linux.NR_get_robust_list,
+	340: linux.NR_splice,
+	341: linux.NR_sync_file_range2,
+	342: linux.NR_tee,
+	343: linux.NR_vmsplice,
+	344: linux.NR_move_pages,
+	345: linux.NR_getcpu,
+	346: linux.NR_epoll_pwait,
+	347: linux.NR_kexec_load,
+	348: linux.NR_utimensat,
+	349: linux.NR_signalfd,
+	350: linux.NR_timerfd_create,
+	351: linux.NR_eventfd,
+	352: linux.NR_fallocate,
+	353: linux.NR_timerfd_settime,
+	354: linux.NR_timerfd_gettime,
+	355: linux.NR_signalfd4,
+	356: linux.NR_eventfd2,
+	357: linux.NR_epoll_create1,
+	358: linux.NR_dup3,
+	359: linux.NR_pipe2,
+	360: linux.NR_inotify_init1,
+	361: linux.NR_preadv,
+	362: linux.NR_pwritev,
+	363: linux.NR_rt_tgsigqueueinfo,
+	364: linux.NR_perf_event_open,
+	365: linux.NR_recvmmsg,
+	366: linux.NR_accept4,
+	367: linux.NR_fanotify_init,
+	368: linux.NR_fanotify_mark,
+	369: linux.NR_prlimit64,
+	370: linux.NR_name_to_handle_at,
+	371: linux.NR_open_by_handle_at,
+	372: linux.NR_clock_adjtime,
+	373: linux.NR_syncfs,
+	374: linux.NR_sendmmsg,
+	375: linux.NR_setns,
+	376: linux.NR_process_vm_readv,
+	377: linux.NR_process_vm_writev,
+	378: linux.NR_kcmp,
+	379: linux.NR_finit_module,
+	380: linux.NR_sched_setattr,
+	381: linux.NR_sched_getattr,
+	382: linux.NR_renameat2,
+	383: linux.NR_seccomp,
+	384: linux.NR_getrandom,
+	385: linux.NR_memfd_create,
+	386: linux.NR_bpf,
+	387: linux.NR_execveat,
+	388: linux.NR_userfaultfd,
+	389: linux.NR_membarrier,
+	390: linux.NR_mlock2,
+	391: linux.NR_copy_file_range,
+	392: linux.NR_preadv2,
+	393: linux.NR_pwritev2,
+	394: linux.NR_pkey_mprotect,
+	395: linux.NR_pkey_alloc,
+	396: linux.NR_pkey_free,
+	397: linux.NR_statx,
+	398: linux.NR_rseq,
+	399: linux.NR_io_pgetevents,
+	400: linux.NR_migrate_pages,
+	401: linux.NR_kexec_file_load,
+	403: linux.NR_clock_gettime64,
+	404: linux.NR_clock_settime64,
+	405: linux.NR_clock_adjtime64,
+	406: linux.NR_clock_getres_time64,
+	407: linux.NR_clock_nanosleep_time64,
+	408: linux.NR_timer_gettime64,
+	409: linux.NR_timer_settime64,
+	410: linux.NR_timerfd_gettime64,
+	411: linux.NR_timerfd_settime64,
+	412: linux.NR_utimensat_time64,
+	413: linux.NR_pselect6_time64,
+	414: linux.NR_ppoll_time64,
+	416: linux.NR_io_pgetevents_time64,
+	417: linux.NR_recvmmsg_time64,
+	418: linux.NR_mq_timedsend_time64,
+	419: linux.NR_mq_timedreceive_time64,
+	420: linux.NR_semtimedop_time64,
+	421: linux.NR_rt_sigtimedwait_time64,
+	422: linux.NR_futex_time64,
+	423: linux.NR_sched_rr_get_interval_time64,
+	424: linux.NR_pidfd_send_signal,
+	425: linux.NR_io_uring_setup,
+	426: linux.NR_io_uring_enter,
+	427: linux.NR_io_uring_register,
+	428: linux.NR_open_tree,
+	429: linux.NR_move_mount,
+	430: linux.NR_fsopen,
+	431: linux.NR_fsconfig,
+	432: linux.NR_fsmount,
+	433: linux.NR_fspick,
+	434: linux.NR_pidfd_open,
+	435: linux.NR_clone3,
+	436: linux.NR_close_range,
+	437: linux.NR_openat2,
+	438: linux.NR_pidfd_getfd,
+	439: linux.NR_faccessat2,
+	440: linux.NR_process_madvise,
+	441: linux.NR_epoll_pwait2,
+	442: linux.NR_mount_setattr,
+	443: linux.NR_quotactl_fd,
+	444: linux.NR_landlock_create_ruleset,
+	445: linux.NR_landlock_add_rule,
+	446: linux.NR_landlock_restrict_self,
+	448: linux.NR_process_mrelease,
+	449: linux.NR_futex_waitv,
+	450: linux.NR_set_mempolicy_home_node,
+	451: linux.NR_cachestat,
+	452: linux.NR_fchmodat2,
+	453: linux.NR_map_shadow_stack,
+	454: linux.NR_futex_wake,
+	455: linux.NR_futex_wait,
+	456: linux.NR_futex_requeue,
+	457: linux.NR_statmount,
+	458: linux.NR_listmount,
+	459: linux.NR_lsm_get_self_attr,
+	460: linux.NR_lsm_set_self_attr,
+	461: linux.NR_lsm_list_modules,
+	462: linux.NR_mseal,
+	463: linux.NR_setxattrat,
+	464: linux.NR_getxattrat,
+	465: linux.NR_listxattrat,
+	466: linux.NR_removexattrat,
+}
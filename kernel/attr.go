@@ -0,0 +1,362 @@
+package kernel
+
+import (
+	"errors"
+	"io/fs"
+	"time"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+const (
+	UTIME_NOW  = 0x3FFFFFFF
+	UTIME_OMIT = 0x3FFFFFFE
+
+	S_IALLUGO = S_ISUID | S_ISGID | S_ISVTX | 0777
+)
+
+type ChmodFS interface {
+	filesystem.FS
+	Chmod(name string, mode fs.FileMode) error
+}
+
+type ChownFS interface {
+	filesystem.FS
+	Lchown(name string, uid, gid int) error
+}
+
+type ChtimesFS interface {
+	filesystem.FS
+	Chtimes(name string, atime, mtime time.Time) error
+}
+
+type ChmodFile interface {
+	filesystem.File
+	Chmod(mode fs.FileMode) error
+}
+
+type ChownFile interface {
+	filesystem.File
+	Chown(uid, gid int) error
+}
+
+type inodeKey struct {
+	dev, ino uint64
+}
+
+type inodeAttr struct {
+	mask             uint32
+	mode             mode_t
+	uid              uid_t
+	gid              gid_t
+	atim, mtim, ctim timespec
+}
+
+type attrTarget struct {
+	file filesystem.File
+	dir  filesystem.FS
+	name string
+	st   *kstat
+}
+
+func (f *fcntl) umask(ctx linux.Context, mask int32) int32 {
+	f.rw.Lock()
+	defer f.rw.Unlock()
+	old := f.cmask
+	f.cmask = mode_t(mask) & 0777
+	return int32(old)
+}
+
+func (f *fcntl) fchmod(ctx linux.Context, fd uint32, mode mode_t) int32 {
+	t, errno := f.attrFd(ctx, int32(fd))
+	if errno == 0 {
+		errno = f.chmodTarget(t, mode)
+	}
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	return 0
+}
+
+func (f *fcntl) fchmodat2(ctx linux.Context, dfd int32, filename emuptr, mode mode_t, flags int32) int32 {
+	if flags&^(AT_SYMLINK_NOFOLLOW|AT_EMPTY_PATH) != 0 {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	t, errno := f.attrAt(ctx, dfd, filename, flags)
+	if errno == 0 {
+		errno = f.chmodTarget(t, mode)
+	}
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	return 0
+}
+
+func (f *fcntl) fchown(ctx linux.Context, fd uint32, uid uid_t, gid gid_t) int32 {
+	t, errno := f.attrFd(ctx, int32(fd))
+	if errno == 0 {
+		errno = f.chownTarget(t, uid, gid)
+	}
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	return 0
+}
+
+func (f *fcntl) fchownat(ctx linux.Context, dfd int32, filename emuptr, uid uid_t, gid gid_t, flag int32) int32 {
+	if flag&^(AT_SYMLINK_NOFOLLOW|AT_EMPTY_PATH) != 0 {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	t, errno := f.attrAt(ctx, dfd, filename, flag)
+	if errno == 0 {
+		errno = f.chownTarget(t, uid, gid)
+	}
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	return 0
+}
+
+func (f *fcntl) utimensat(ctx linux.Context, dfd int32, filename, utimes emuptr, flags int32, wide bool) int32 {
+	if flags&^(AT_SYMLINK_NOFOLLOW|AT_EMPTY_PATH) != 0 {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	times := [2]timespec{{tv_nsec: UTIME_NOW}, {tv_nsec: UTIME_NOW}}
+	if utimes != emunullptr {
+		var err error
+		if wide {
+			var ts [2]timespec64
+			err = memExtractArray(ctx, utimes, ts[:])
+			for i := range ts {
+				times[i] = timespec{tv_sec: time_t(ts[i].tv_sec), tv_nsec: long_t(ts[i].tv_nsec)}
+			}
+		} else {
+			err = memExtractArray(ctx, utimes, times[:])
+		}
+		if err != nil {
+			ctx.SetErrno(linux.EFAULT)
+			return -1
+		}
+		for _, ts := range times {
+			if ts.tv_nsec != UTIME_NOW && ts.tv_nsec != UTIME_OMIT && (ts.tv_nsec < 0 || ts.tv_nsec >= 1e9) {
+				ctx.SetErrno(linux.EINVAL)
+				return -1
+			}
+		}
+		if times[0].tv_nsec == UTIME_OMIT && times[1].tv_nsec == UTIME_OMIT {
+			return 0
+		}
+	}
+	var t *attrTarget
+	var errno linux.Errno
+	if filename == emunullptr && dfd != AT_FDCWD {
+		if flags != 0 {
+			errno = linux.EINVAL
+		} else {
+			t, errno = f.attrFd(ctx, dfd)
+		}
+	} else {
+		t, errno = f.attrAt(ctx, dfd, filename, flags)
+	}
+	if errno == 0 {
+		errno = f.chtimesTarget(t, times)
+	}
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	return 0
+}
+
+func (f *fcntl) attrFd(ctx linux.Context, fd int32) (*attrTarget, linux.Errno) {
+	dbg := ctx.Debugger()
+	file, err := f.getFile(dbg, int(fd))
+	if err != nil {
+		return nil, linux.EBADF
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return nil, linux.ToErrno(err)
+	}
+	t := &attrTarget{file: file, st: newKstat(info, f.inoName(int(fd)))}
+	f.rw.RLock()
+	name, ok := f.paths[int(fd)]
+	f.rw.RUnlock()
+	if ok {
+		t.dir, t.name = dbg.GetFS(), name
+	} else if dir, ok := file.(filesystem.FS); ok {
+		t.dir, t.name = dir, "."
+	}
+	return t, 0
+}
+
+func (f *fcntl) attrAt(ctx linux.Context, dfd int32, filename emuptr, flag int32) (*attrTarget, linux.Errno) {
+	name, err := ctx.ToPointer(filename).MemReadString()
+	if err != nil {
+		return nil, linux.EFAULT
+	} else if name == "" {
+		if flag&AT_EMPTY_PATH == 0 {
+			return nil, linux.ENOENT
+		} else if dfd != AT_FDCWD {
+			return f.attrFd(ctx, dfd)
+		}
+		name = "."
+	}
+	dir, name, errno := f.lookup(ctx, dfd, name)
+	if errno == 0 && flag&AT_SYMLINK_NOFOLLOW == 0 {
+		name, errno = f.followLink(dir, name)
+	}
+	if errno != 0 {
+		return nil, errno
+	}
+	info, err := lstat(dir, name)
+	if err != nil {
+		return nil, linux.ToErrno(err)
+	}
+	return &attrTarget{dir: dir, name: name, st: newKstat(info, name)}, 0
+}
+
+func (f *fcntl) chmodTarget(t *attrTarget, mode mode_t) linux.Errno {
+	if t.st.mode&S_IFMT == S_IFLNK {
+		return linux.EOPNOTSUPP
+	}
+	mode &= S_IALLUGO
+	var err error = errors.ErrUnsupported
+	if file, ok := t.file.(ChmodFile); ok {
+		err = file.Chmod(fromMode(mode))
+	} else if dir, ok := t.dir.(ChmodFS); ok {
+		err = dir.Chmod(t.name, fromMode(mode))
+	}
+	return f.setattr(t.st, err, inodeAttr{mask: STATX_MODE, mode: mode})
+}
+
+func (f *fcntl) chownTarget(t *attrTarget, uid uid_t, gid gid_t) linux.Errno {
+	attr := inodeAttr{uid: uid, gid: gid}
+	if uid != ^uid_t(0) {
+		attr.mask |= STATX_UID
+	}
+	if gid != ^gid_t(0) {
+		attr.mask |= STATX_GID
+	}
+	if attr.mask == 0 {
+		return 0
+	}
+	var err error = errors.ErrUnsupported
+	if file, ok := t.file.(ChownFile); ok {
+		err = file.Chown(int(int32(uid)), int(int32(gid)))
+	} else if dir, ok := t.dir.(ChownFS); ok {
+		err = dir.Lchown(t.name, int(int32(uid)), int(int32(gid)))
+	}
+	return f.setattr(t.st, err, attr)
+}
+
+func (f *fcntl) chtimesTarget(t *attrTarget, times [2]timespec) linux.Errno {
+	var attr inodeAttr
+	var atime, mtime time.Time
+	now := time.Now()
+	for i, ts := range times {
+		var tm time.Time
+		switch ts.tv_nsec {
+		case UTIME_OMIT:
+			continue
+		case UTIME_NOW:
+			tm = now
+		default:
+			tm = time.Unix(int64(ts.tv_sec), int64(ts.tv_nsec))
+		}
+		if i == 0 {
+			attr.mask |= STATX_ATIME
+			attr.atim, atime = toTimespec(tm), tm
+		} else {
+			attr.mask |= STATX_MTIME
+			attr.mtim, mtime = toTimespec(tm), tm
+		}
+	}
+	var err error = errors.ErrUnsupported
+	if dir, ok := t.dir.(ChtimesFS); ok && t.st.mode&S_IFMT != S_IFLNK {
+		err = dir.Chtimes(t.name, atime, mtime)
+	}
+	return f.setattr(t.st, err, attr)
+}
+
+func (f *fcntl) setattr(st *kstat, err error, attr inodeAttr) linux.Errno {
+	if err != nil && !errors.Is(err, errors.ErrUnsupported) && !errors.Is(err, fs.ErrPermission) {
+		return linux.ToErrno(err)
+	}
+	key := inodeKey{st.dev, st.ino}
+	f.rw.Lock()
+	defer f.rw.Unlock()
+	a, ok := f.attrs[key]
+	if err == nil {
+		if ok {
+			a.mask &^= attr.mask
+			if a.mask&^STATX_CTIME == 0 {
+				delete(f.attrs, key)
+			}
+		}
+		return 0
+	} else if !ok {
+		a = new(inodeAttr)
+		f.attrs[key] = a
+	}
+	a.mask |= attr.mask | STATX_CTIME
+	if attr.mask&STATX_MODE != 0 {
+		a.mode = attr.mode
+	}
+	if attr.mask&STATX_UID != 0 {
+		a.uid = attr.uid
+	}
+	if attr.mask&STATX_GID != 0 {
+		a.gid = attr.gid
+	}
+	if attr.mask&STATX_ATIME != 0 {
+		a.atim = attr.atim
+	}
+	if attr.mask&STATX_MTIME != 0 {
+		a.mtim = attr.mtim
+	}
+	a.ctim = toTimespec(time.Now())
+	return 0
+}
+
+func (f *fcntl) applyAttrs(st *kstat) *kstat {
+	f.rw.RLock()
+	defer f.rw.RUnlock()
+	a, ok := f.attrs[inodeKey{st.dev, st.ino}]
+	if !ok {
+		return st
+	}
+	if a.mask&STATX_MODE != 0 {
+		st.mode = st.mode&S_IFMT | a.mode
+	}
+	if a.mask&STATX_UID != 0 {
+		st.uid = a.uid
+	}
+	if a.mask&STATX_GID != 0 {
+		st.gid = a.gid
+	}
+	if a.mask&STATX_ATIME != 0 {
+		st.atim = a.atim
+	}
+	if a.mask&STATX_MTIME != 0 {
+		st.mtim = a.mtim
+	}
+	if a.mask&STATX_CTIME != 0 {
+		st.ctim = a.ctim
+	}
+	return st
+}
+
+func (f *fcntl) creatMode(mode mode_t) fs.FileMode {
+	f.rw.RLock()
+	defer f.rw.RUnlock()
+	return fs.FileMode(mode &^ f.cmask)
+}
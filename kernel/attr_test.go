@@ -0,0 +1,187 @@
+package kernel
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/emulator"
+)
+
+func (tk *testKernel) statAt(dfd uint64, name string, flags uint64) statx {
+	tk.tb.Helper()
+	var stx statx
+	buf := tk.alloc(256)
+	if _, errno := tk.call(linux.NR_statx, dfd, tk.cstring(name), flags, STATX_BASIC_STATS, buf); errno != 0 {
+		tk.tb.Fatalf("statx(%q) errno = %v", name, errno)
+	}
+	tk.decode(buf, &stx)
+	return stx
+}
+
+func TestUmask(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	at := uint64(testAT_FDCWD)
+	if old, _ := tk.call(linux.NR_umask, 077); old != 022 {
+		t.Fatalf("umask = %#o, want 022", old)
+	}
+	if old, _ := tk.call(linux.NR_umask, 01077); old != 077 {
+		t.Fatalf("umask = %#o, want 077", old)
+	}
+	tk.create("file", nil)
+	tk.call(linux.NR_mkdirat, at, tk.cstring("dir"), 0777)
+	if stx := tk.statAt(at, "file", 0); stx.stx_mode != S_IFREG|0600 {
+		t.Fatalf("file mode = %#o, want %#o", stx.stx_mode, S_IFREG|0600)
+	}
+	if stx := tk.statAt(at, "dir", 0); stx.stx_mode != S_IFDIR|0700 {
+		t.Fatalf("dir mode = %#o, want %#o", stx.stx_mode, S_IFDIR|0700)
+	}
+}
+
+func TestChmod(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	at := uint64(testAT_FDCWD)
+	fd := uint64(tk.create("file", nil))
+	tk.call(linux.NR_symlinkat, tk.cstring("file"), at, tk.cstring("link"))
+	if _, errno := tk.call(linux.NR_fchmodat, at, tk.cstring("link"), 0640); errno != 0 {
+		t.Fatalf("fchmodat errno = %v", errno)
+	}
+	if info, _ := os.Stat(filepath.Join(tk.dbg.root, "file")); info.Mode().Perm() != 0640 {
+		t.Fatalf("host mode = %v, want 0640", info.Mode())
+	}
+	if _, errno := tk.call(linux.NR_fchmod, fd, 04751); errno != 0 || tk.statAt(fd, "", AT_EMPTY_PATH).stx_mode != S_IFREG|04751 {
+		t.Fatalf("fchmod errno = %v, mode = %#o", errno, tk.statAt(fd, "", AT_EMPTY_PATH).stx_mode)
+	}
+	tests := []struct {
+		name  string
+		nr    linux.NR
+		args  []uint64
+		errno linux.Errno
+	}{
+		{"nofollow symlink", linux.NR_fchmodat2, []uint64{at, tk.cstring("link"), 0600, AT_SYMLINK_NOFOLLOW}, linux.EOPNOTSUPP},
+		{"empty path", linux.NR_fchmodat2, []uint64{fd, tk.cstring(""), 0600, AT_EMPTY_PATH}, 0},
+		{"bad flags", linux.NR_fchmodat2, []uint64{at, tk.cstring("file"), 0600, 1}, linux.EINVAL},
+		{"missing", linux.NR_fchmodat, []uint64{at, tk.cstring("missing"), 0600}, linux.ENOENT},
+		{"badf", linux.NR_fchmod, []uint64{1000, 0600}, linux.EBADF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, errno := tk.call(tt.nr, tt.args...); errno != tt.errno {
+				t.Fatalf("errno = %v, want %v", errno, tt.errno)
+			}
+		})
+	}
+}
+
+func TestChown(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	at := uint64(testAT_FDCWD)
+	fd := uint64(tk.create("file", nil))
+	tk.call(linux.NR_symlinkat, tk.cstring("file"), at, tk.cstring("link"))
+	if _, errno := tk.call(linux.NR_fchownat, at, tk.cstring("link"), 1234, 5678, 0); errno != 0 {
+		t.Fatalf("fchownat errno = %v", errno)
+	}
+	if stx := tk.statAt(at, "file", 0); stx.stx_uid != 1234 || stx.stx_gid != 5678 {
+		t.Fatalf("owner = %d:%d, want 1234:5678", stx.stx_uid, stx.stx_gid)
+	}
+	if _, errno := tk.call(linux.NR_fchown, fd, 4321, 0xFFFFFFFF); errno != 0 {
+		t.Fatalf("fchown errno = %v", errno)
+	}
+	if stx := tk.statAt(fd, "", AT_EMPTY_PATH); stx.stx_uid != 4321 || stx.stx_gid != 5678 {
+		t.Fatalf("owner = %d:%d, want 4321:5678", stx.stx_uid, stx.stx_gid)
+	}
+	if _, errno := tk.call(linux.NR_fchownat, at, tk.cstring("link"), 42, 42, AT_SYMLINK_NOFOLLOW); errno != 0 {
+		t.Fatalf("fchownat(AT_SYMLINK_NOFOLLOW) errno = %v", errno)
+	}
+	if stx := tk.statAt(at, "file", 0); stx.stx_uid != 4321 {
+		t.Fatalf("lchown followed the link, uid = %d", stx.stx_uid)
+	}
+	if stx := tk.statAt(at, "link", AT_SYMLINK_NOFOLLOW); stx.stx_uid != 42 || stx.stx_gid != 42 {
+		t.Fatalf("link owner = %d:%d, want 42:42", stx.stx_uid, stx.stx_gid)
+	}
+	if _, errno := tk.call(linux.NR_fchownat, at, tk.cstring("file"), 0, 0, 1); errno != linux.EINVAL {
+		t.Fatalf("fchownat bad flags errno = %v, want EINVAL", errno)
+	}
+}
+
+func TestUtimensat(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	at := uint64(testAT_FDCWD)
+	fd := uint64(tk.create("file", nil))
+	times := func(ts ...timespec) emuptr {
+		addr := tk.alloc(32)
+		for i := range ts {
+			memWrite(tk.ctx, addr+uint64(i)*16, &ts[i])
+		}
+		return addr
+	}
+	if _, errno := tk.call(linux.NR_utimensat, at, tk.cstring("file"), times(timespec{100, 5}, timespec{200, 7}), 0); errno != 0 {
+		t.Fatalf("utimensat errno = %v", errno)
+	}
+	if stx := tk.statAt(at, "file", 0); stx.stx_atime != (statx_timestamp{tv_sec: 100, tv_nsec: 5}) || stx.stx_mtime != (statx_timestamp{tv_sec: 200, tv_nsec: 7}) {
+		t.Fatalf("atime = %+v, mtime = %+v", stx.stx_atime, stx.stx_mtime)
+	}
+	if _, errno := tk.call(linux.NR_utimensat, fd, 0, times(timespec{tv_nsec: UTIME_OMIT}, timespec{300, 0}), 0); errno != 0 {
+		t.Fatalf("utimensat(fd) errno = %v", errno)
+	}
+	if stx := tk.statAt(at, "file", 0); stx.stx_atime.tv_sec != 100 || stx.stx_mtime.tv_sec != 300 {
+		t.Fatalf("atime = %+v, mtime = %+v", stx.stx_atime, stx.stx_mtime)
+	}
+	tests := []struct {
+		name  string
+		args  []uint64
+		errno linux.Errno
+	}{
+		{"now", []uint64{at, tk.cstring("file"), 0, 0}, 0},
+		{"omit both", []uint64{at, tk.cstring("missing"), times(timespec{tv_nsec: UTIME_OMIT}, timespec{tv_nsec: UTIME_OMIT}), 0}, 0},
+		{"bad nsec", []uint64{at, tk.cstring("file"), times(timespec{0, 1e9}, timespec{}), 0}, linux.EINVAL},
+		{"bad flags", []uint64{at, tk.cstring("file"), 0, 1}, linux.EINVAL},
+		{"fd with flags", []uint64{fd, 0, 0, AT_SYMLINK_NOFOLLOW}, linux.EINVAL},
+		{"missing", []uint64{at, tk.cstring("missing"), 0, 0}, linux.ENOENT},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, errno := tk.call(linux.NR_utimensat, tt.args...); errno != tt.errno {
+				t.Fatalf("errno = %v, want %v", errno, tt.errno)
+			}
+		})
+	}
+}
+
+func TestUtimensatTime64(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM)
+	at := uint64(testAT_FDCWD)
+	tk.create("file", nil)
+	ts := tk.alloc(32)
+	memWrite(tk.ctx, ts, &timespec64{tv_nsec: UTIME_OMIT})
+	memWrite(tk.ctx, ts+16, &timespec64{tv_sec: 1 << 33, tv_nsec: 9})
+	if _, errno := tk.call(linux.NR_utimensat_time64, at, tk.cstring("file"), ts, 0); errno != 0 {
+		t.Fatalf("utimensat_time64 errno = %v", errno)
+	}
+	if stx := tk.statAt(at, "file", 0); stx.stx_mtime != (statx_timestamp{tv_sec: 1 << 33, tv_nsec: 9}) {
+		t.Fatalf("mtime = %+v", stx.stx_mtime)
+	}
+}
+
+func TestAttrOverlay(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	at := uint64(testAT_FDCWD)
+	tk.create("file", nil)
+	tk.dbg.fs = plainFS{tk.dbg.fs}
+	before := tk.statAt(at, "file", 0)
+	tk.call(linux.NR_fchmodat, at, tk.cstring("file"), 0400)
+	tk.call(linux.NR_fchownat, at, tk.cstring("file"), 7, 8, 0)
+	tk.call(linux.NR_utimensat, at, tk.cstring("file"), 0, 0)
+	stx := tk.statAt(at, "file", 0)
+	if stx.stx_mode != S_IFREG|0400 || stx.stx_uid != 7 || stx.stx_gid != 8 || stx.stx_ino != before.stx_ino {
+		t.Fatalf("statx = %+v", stx)
+	}
+	if info, _ := os.Stat(filepath.Join(tk.dbg.root, "file")); info.Mode().Perm() != 0644 {
+		t.Fatalf("host mode changed to %v", info.Mode())
+	}
+	fd, _ := tk.call(linux.NR_openat, at, tk.cstring("file"), 0, 0)
+	if stx := tk.statAt(fd, "", AT_EMPTY_PATH); stx.stx_mode != S_IFREG|0400 || stx.stx_uid != 7 {
+		t.Fatalf("fstat = %+v", stx)
+	}
+}
@@ -0,0 +1,140 @@
+package kernel
+
+import (
+	"path"
+	"strings"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+func (f *fcntl) getcwd(ctx linux.Context, buf emuptr, size ulong_t) long_t {
+	f.rw.RLock()
+	cwd, ok := within(f.cwd, f.root)
+	if !ok {
+		cwd = "(unreachable)" + f.cwd
+	}
+	f.rw.RUnlock()
+	if uint64(len(cwd)+1) > uint64(size) {
+		ctx.SetErrno(linux.ERANGE)
+		return -1
+	}
+	err := ctx.ToPointer(buf).MemWrite(append([]byte(cwd), 0))
+	if err != nil {
+		ctx.SetErrno(linux.EFAULT)
+		return -1
+	}
+	return long_t(len(cwd) + 1)
+}
+
+func (f *fcntl) chdir(ctx linux.Context, filename emuptr) int32 {
+	dir, errno := f.lookupDir(ctx, filename)
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	f.rw.Lock()
+	f.cwd = dir
+	f.rw.Unlock()
+	return 0
+}
+
+func (f *fcntl) fchdir(ctx linux.Context, fd uint32) int32 {
+	file, err := f.getFile(ctx.Debugger(), int(fd))
+	if err != nil {
+		ctx.SetErrno(linux.EBADF)
+		return -1
+	}
+	if info, err := file.Stat(); err != nil {
+		ctx.SetErrno(linux.ToErrno(err))
+		return -1
+	} else if !info.IsDir() {
+		ctx.SetErrno(linux.ENOTDIR)
+		return -1
+	}
+	f.rw.Lock()
+	defer f.rw.Unlock()
+	dir, ok := f.paths[int(fd)]
+	if !ok {
+		ctx.SetErrno(linux.ENOENT)
+		return -1
+	}
+	f.cwd = dir
+	return 0
+}
+
+func (f *fcntl) chroot(ctx linux.Context, filename emuptr) int32 {
+	dir, errno := f.lookupDir(ctx, filename)
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	f.rw.Lock()
+	f.root = dir
+	f.rw.Unlock()
+	return 0
+}
+
+func (f *fcntl) lookupDir(ctx linux.Context, filename emuptr) (string, linux.Errno) {
+	name, err := ctx.ToPointer(filename).MemReadString()
+	if err != nil {
+		return "", linux.EFAULT
+	}
+	dir, name, errno := f.resolveAt(ctx, AT_FDCWD, name, 0, true)
+	if errno != 0 {
+		return "", errno
+	}
+	info, err := stat(dir, name)
+	if err != nil {
+		return "", linux.ToErrno(err)
+	} else if !info.IsDir() {
+		return "", linux.ENOTDIR
+	}
+	return name, 0
+}
+
+func (f *fcntl) lookup(ctx linux.Context, dfd int32, name string) (filesystem.FS, string, linux.Errno) {
+	return f.resolveAt(ctx, dfd, name, 0, false)
+}
+
+func (f *fcntl) resolveAt(ctx linux.Context, dfd int32, name string, resolve uint64, follow bool) (filesystem.FS, string, linux.Errno) {
+	if name == "" {
+		return nil, "", linux.ENOENT
+	}
+	dbg := ctx.Debugger()
+	if dfd == AT_FDCWD || path.IsAbs(name) {
+		f.rw.RLock()
+		cwd := f.cwd
+		f.rw.RUnlock()
+		return f.walk(dbg.GetFS(), cwd, name, resolve, follow)
+	}
+	file, err := f.getFile(dbg, int(dfd))
+	if err != nil {
+		return nil, "", linux.EBADF
+	}
+	if info, err := file.Stat(); err != nil {
+		return nil, "", linux.ToErrno(err)
+	} else if !info.IsDir() {
+		return nil, "", linux.ENOTDIR
+	}
+	f.rw.RLock()
+	dir, ok := f.paths[int(dfd)]
+	f.rw.RUnlock()
+	if ok {
+		return f.walk(dbg.GetFS(), dir, name, resolve, follow)
+	} else if fsys, ok := file.(filesystem.FS); ok {
+		return f.walk(fsys, ".", name, resolve, follow)
+	}
+	return nil, "", linux.ENOTDIR
+}
+
+func within(name, root string) (string, bool) {
+	if root == "/" {
+		return name, true
+	} else if name == root {
+		return "/", true
+	} else if rel, ok := strings.CutPrefix(name, root); ok && rel[0] == '/' {
+		return rel, true
+	}
+	return "", false
+}
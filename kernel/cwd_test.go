@@ -0,0 +1,118 @@
+package kernel
+
+import (
+	"testing"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/emulator"
+)
+
+func (tk *testKernel) getcwd() string {
+	tk.tb.Helper()
+	buf := tk.alloc(256)
+	n, errno := tk.call(linux.NR_getcwd, buf, 256)
+	if errno != 0 {
+		tk.tb.Fatalf("getcwd errno = %v", errno)
+	}
+	return string(tk.bytes(buf, int(n)-1))
+}
+
+func TestChdir(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	at := uint64(testAT_FDCWD)
+	tk.call(linux.NR_mkdirat, at, tk.cstring("a"), 0755)
+	tk.call(linux.NR_mkdirat, at, tk.cstring("a/b"), 0755)
+	tk.create("a/b/file", []byte("hi"))
+	if cwd := tk.getcwd(); cwd != "/" {
+		t.Fatalf("getcwd = %q, want /", cwd)
+	}
+	if _, errno := tk.call(linux.NR_chdir, tk.cstring("a/b")); errno != 0 {
+		t.Fatalf("chdir errno = %v", errno)
+	}
+	if cwd := tk.getcwd(); cwd != "/a/b" {
+		t.Fatalf("getcwd = %q, want /a/b", cwd)
+	}
+	if _, errno := tk.call(linux.NR_openat, at, tk.cstring("file"), 0, 0); errno != 0 {
+		t.Fatalf("openat relative to cwd errno = %v", errno)
+	}
+	if _, errno := tk.call(linux.NR_faccessat, at, tk.cstring("../b/file"), 0, 0); errno != 0 {
+		t.Fatalf("faccessat relative to cwd errno = %v", errno)
+	}
+	if _, errno := tk.call(linux.NR_chdir, tk.cstring("file")); errno != linux.ENOTDIR {
+		t.Fatalf("chdir(file) errno = %v, want ENOTDIR", errno)
+	}
+	if _, errno := tk.call(linux.NR_chdir, tk.cstring("missing")); errno != linux.ENOENT {
+		t.Fatalf("chdir(missing) errno = %v, want ENOENT", errno)
+	}
+
+	root, _ := tk.call(linux.NR_openat, at, tk.cstring("/"), 0, 0)
+	if _, errno := tk.call(linux.NR_fchdir, root); errno != 0 || tk.getcwd() != "/" {
+		t.Fatalf("fchdir errno = %v, cwd = %q", errno, tk.getcwd())
+	}
+	a, _ := tk.call(linux.NR_openat, at, tk.cstring("a"), 0, 0)
+	if _, errno := tk.call(linux.NR_openat, a, tk.cstring("b/file"), 0, 0); errno != 0 {
+		t.Fatalf("openat(dirfd) errno = %v", errno)
+	}
+	file, _ := tk.call(linux.NR_openat, a, tk.cstring("b/file"), 0, 0)
+	if _, errno := tk.call(linux.NR_fchdir, file); errno != linux.ENOTDIR {
+		t.Fatalf("fchdir(file) errno = %v, want ENOTDIR", errno)
+	}
+
+	buf := tk.alloc(2)
+	if _, errno := tk.call(linux.NR_getcwd, buf, 1); errno != linux.ERANGE {
+		t.Fatalf("getcwd small buffer errno = %v, want ERANGE", errno)
+	}
+}
+
+func TestChroot(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	at := uint64(testAT_FDCWD)
+	tk.call(linux.NR_mkdirat, at, tk.cstring("jail"), 0755)
+	tk.call(linux.NR_mkdirat, at, tk.cstring("jail/etc"), 0755)
+	tk.create("jail/etc/hosts", []byte("jailed"))
+	tk.create("secret", nil)
+	tk.call(linux.NR_mkdirat, at, tk.cstring("etc"), 0755)
+	tk.create("etc/hosts", []byte("escaped"))
+	tk.call(linux.NR_symlinkat, tk.cstring("/etc/hosts"), at, tk.cstring("jail/hosts"))
+	tk.call(linux.NR_symlinkat, tk.cstring("/etc"), at, tk.cstring("jail/link"))
+	tk.call(linux.NR_symlinkat, tk.cstring("/../.."), at, tk.cstring("jail/up"))
+	if _, errno := tk.call(linux.NR_chroot, tk.cstring("/jail")); errno != 0 {
+		t.Fatalf("chroot errno = %v", errno)
+	}
+	if cwd := tk.getcwd(); cwd != "(unreachable)/" {
+		t.Fatalf("getcwd outside root = %q", cwd)
+	}
+	tk.call(linux.NR_chdir, tk.cstring("/"))
+	if cwd := tk.getcwd(); cwd != "/" {
+		t.Fatalf("getcwd = %q, want /", cwd)
+	}
+	fd, errno := tk.call(linux.NR_openat, at, tk.cstring("/etc/hosts"), 0, 0)
+	if errno != 0 {
+		t.Fatalf("openat(/etc/hosts) errno = %v", errno)
+	}
+	buf := tk.alloc(16)
+	if n, _ := tk.call(linux.NR_read, fd, buf, 16); string(tk.bytes(buf, int(n))) != "jailed" {
+		t.Fatalf("read = %q, want jailed", tk.bytes(buf, int(n)))
+	}
+	for _, name := range []string{"/hosts", "/link/hosts", "up/etc/hosts"} {
+		fd, errno := tk.call(linux.NR_openat, at, tk.cstring(name), 0, 0)
+		if errno != 0 {
+			t.Fatalf("openat(%q) errno = %v", name, errno)
+		}
+		if n, _ := tk.call(linux.NR_read, fd, buf, 16); string(tk.bytes(buf, int(n))) != "jailed" {
+			t.Fatalf("read(%q) = %q, want jailed", name, tk.bytes(buf, int(n)))
+		}
+	}
+	for _, name := range []string{"/secret", "../secret", "/../../secret", "/up/secret", "link/../../secret"} {
+		if _, errno := tk.call(linux.NR_openat, at, tk.cstring(name), 0, 0); errno != linux.ENOENT {
+			t.Fatalf("openat(%q) errno = %v, want ENOENT", name, errno)
+		}
+		if _, errno := tk.call(linux.NR_faccessat, at, tk.cstring(name), 0); errno != linux.ENOENT {
+			t.Fatalf("faccessat(%q) errno = %v, want ENOENT", name, errno)
+		}
+	}
+	etc, _ := tk.call(linux.NR_openat, at, tk.cstring("etc"), 0, 0)
+	if _, errno := tk.call(linux.NR_fchdir, etc); errno != 0 || tk.getcwd() != "/etc" {
+		t.Fatalf("fchdir errno = %v, cwd = %q", errno, tk.getcwd())
+	}
+}
@@ -0,0 +1,187 @@
+package kernel
+
+import (
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+const (
+	DT_UNKNOWN = 0
+	DT_FIFO    = 1
+	DT_CHR     = 2
+	DT_DIR     = 4
+	DT_BLK     = 6
+	DT_REG     = 8
+	DT_LNK     = 10
+	DT_SOCK    = 12
+)
+
+const direntNameOffset = 19
+
+type linux_dirent64 struct {
+	d_ino    uint64
+	d_off    int64
+	d_reclen uint16
+	d_type   uint8
+	d_name   string
+}
+
+type dirStream struct {
+	entries []linux_dirent64
+	pos     int
+}
+
+func (d *linux_dirent64) ctype(c *ccodec) {
+	cUint64(c, &d.d_ino)
+	cInt64(c, &d.d_off)
+	cUint16(c, &d.d_reclen)
+	c.bytes([]byte{d.d_type})
+	c.bytes(append([]byte(d.d_name), 0))
+	c.pad(int(d.d_reclen) - direntNameOffset - len(d.d_name) - 1)
+}
+
+func (f *fcntl) getdents64(ctx linux.Context, fd uint32, dirent emuptr, count size_t) ssize_t {
+	file, err := f.getFile(ctx.Debugger(), int(fd))
+	if err != nil {
+		ctx.SetErrno(linux.EBADF)
+		return -1
+	}
+	f.rw.Lock()
+	defer f.rw.Unlock()
+	stream, errno := f.openDir(int(fd), file)
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	model := modelOf(ctx.Debugger().Arch())
+	var buf []byte
+	for ; stream.pos < len(stream.entries); stream.pos++ {
+		b := encodeC(model, &stream.entries[stream.pos])
+		if len(buf)+len(b) > int(count) {
+			break
+		}
+		buf = append(buf, b...)
+	}
+	if len(buf) == 0 && stream.pos < len(stream.entries) {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	err = ctx.ToPointer(dirent).MemWrite(buf)
+	if err != nil {
+		ctx.SetErrno(linux.EFAULT)
+		return -1
+	}
+	return ssize_t(len(buf))
+}
+
+func (f *fcntl) seekdir(ctx linux.Context, fd int, file filesystem.File, offset off_t, whence int32) off_t {
+	f.rw.Lock()
+	defer f.rw.Unlock()
+	stream, errno := f.openDir(fd, file)
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	pos := int64(offset)
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		pos += int64(stream.pos)
+	default:
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	if pos < 0 {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	} else if pos == 0 {
+		delete(f.dirs, fd)
+		return 0
+	}
+	stream.pos = int(min(pos, int64(len(stream.entries))))
+	return off_t(pos)
+}
+
+func (f *fcntl) openDir(fd int, file filesystem.File) (*dirStream, linux.Errno) {
+	if stream, ok := f.dirs[fd]; ok {
+		return stream, 0
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return nil, linux.ToErrno(err)
+	} else if !info.IsDir() {
+		return nil, linux.ENOTDIR
+	}
+	var entries []fs.DirEntry
+	switch dir := file.(type) {
+	case filesystem.DirFS:
+		entries, err = dir.ReadDir("")
+	case filesystem.DirFile:
+		entries, err = dir.ReadDir(-1)
+	default:
+		return nil, linux.ENOTDIR
+	}
+	if err != nil {
+		return nil, linux.ToErrno(err)
+	}
+	name, ok := f.paths[fd]
+	if !ok {
+		name = info.Name()
+	}
+	stream := &dirStream{entries: make([]linux_dirent64, 0, len(entries)+2)}
+	stream.add(".", newKstat(info, name).ino, DT_DIR)
+	parent := inoOf(path.Dir(name))
+	if dir, ok := file.(fs.FS); ok {
+		if info, err := stat(dir, ".."); err == nil {
+			parent = newKstat(info, path.Dir(name)).ino
+		}
+	}
+	stream.add("..", parent, DT_DIR)
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), tmpfilePrefix) {
+			continue
+		}
+		ino := inoOf(path.Join(name, entry.Name()))
+		if info, err := entry.Info(); err == nil {
+			ino = newKstat(info, path.Join(name, entry.Name())).ino
+		}
+		stream.add(entry.Name(), ino, direntType(entry.Type()))
+	}
+	f.dirs[fd] = stream
+	return stream, 0
+}
+
+func (s *dirStream) add(name string, ino uint64, typ uint8) {
+	s.entries = append(s.entries, linux_dirent64{
+		d_ino:    ino,
+		d_off:    int64(len(s.entries) + 1),
+		d_reclen: uint16(alignUp(direntNameOffset+len(name)+1, 8)),
+		d_type:   typ,
+		d_name:   name,
+	})
+}
+
+func direntType(mode fs.FileMode) uint8 {
+	switch mode.Type() {
+	case fs.ModeDir:
+		return DT_DIR
+	case fs.ModeSymlink:
+		return DT_LNK
+	case fs.ModeNamedPipe:
+		return DT_FIFO
+	case fs.ModeSocket:
+		return DT_SOCK
+	case fs.ModeDevice:
+		return DT_BLK
+	case fs.ModeDevice | fs.ModeCharDevice:
+		return DT_CHR
+	case 0:
+		return DT_REG
+	}
+	return DT_UNKNOWN
+}
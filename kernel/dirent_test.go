@@ -0,0 +1,103 @@
+package kernel
+
+import (
+	"encoding/binary"
+	"slices"
+	"testing"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/emulator"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+type testDirent struct {
+	ino  uint64
+	off  int64
+	typ  uint8
+	name string
+}
+
+func (tk *testKernel) getdents(fd uint64, size int) ([]testDirent, linux.Errno) {
+	tk.tb.Helper()
+	buf := tk.alloc(uint64(size))
+	n, errno := tk.call(linux.NR_getdents64, fd, buf, uint64(size))
+	if errno != 0 {
+		return nil, errno
+	}
+	var dirents []testDirent
+	for b := tk.bytes(buf, int(n)); len(b) != 0; {
+		reclen := int(binary.LittleEndian.Uint16(b[16:]))
+		if reclen%8 != 0 || reclen > len(b) || binary.LittleEndian.Uint64(b) == 0 {
+			tk.tb.Fatalf("bad dirent: reclen = %d, ino = %d", reclen, binary.LittleEndian.Uint64(b))
+		}
+		name := b[19:reclen]
+		dirents = append(dirents, testDirent{
+			ino:  binary.LittleEndian.Uint64(b),
+			off:  int64(binary.LittleEndian.Uint64(b[8:])),
+			typ:  b[18],
+			name: string(name[:slices.Index(name, 0)]),
+		})
+		b = b[reclen:]
+	}
+	return dirents, 0
+}
+
+func TestGetdents64(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM)
+	tk.create("a", nil)
+	tk.create("bb", nil)
+	tk.create("ccc", nil)
+	if _, err := tk.dbg.fs.(filesystem.DirFS).Mkdir("sub", 0755); err != nil {
+		t.Fatal(err)
+	}
+	fd, errno := tk.call(linux.NR_openat, uint64(testAT_FDCWD), tk.cstring("."), 0, 0)
+	if errno != 0 {
+		t.Fatalf("openat errno = %v", errno)
+	}
+	want := map[string]uint8{".": DT_DIR, "..": DT_DIR, "a": DT_REG, "bb": DT_REG, "ccc": DT_REG, "sub": DT_DIR}
+	read := func() []testDirent {
+		var all []testDirent
+		for {
+			dirents, errno := tk.getdents(fd, 48)
+			if errno != 0 {
+				t.Fatalf("getdents64 errno = %v", errno)
+			} else if len(dirents) == 0 {
+				return all
+			} else if len(dirents) > 2 {
+				t.Fatalf("getdents64 returned %d entries into 48 bytes", len(dirents))
+			}
+			all = append(all, dirents...)
+		}
+	}
+	all := read()
+	if len(all) != len(want) {
+		t.Fatalf("getdents64 = %v, want %d entries", all, len(want))
+	}
+	for i, d := range all {
+		if typ, ok := want[d.name]; !ok || typ != d.typ || d.ino == 0 || d.off != int64(i+1) {
+			t.Fatalf("dirent %d = %+v", i, d)
+		}
+	}
+	if r, errno := tk.call(linux.NR_lseek, fd, 2, 0); errno != 0 || r != 2 {
+		t.Fatalf("lseek = %d, errno = %v", r, errno)
+	}
+	if rest := read(); !slices.Equal(rest, all[2:]) {
+		t.Fatalf("after lseek(2) = %v, want %v", rest, all[2:])
+	}
+	tk.create("dddd", nil)
+	tk.call(linux.NR_lseek, fd, 0, 0)
+	if again := read(); len(again) != len(all)+1 {
+		t.Fatalf("after rewind = %v, want %d entries", again, len(all)+1)
+	}
+	tk.call(linux.NR_lseek, fd, 0, 0)
+	if _, errno := tk.getdents(fd, 16); errno != linux.EINVAL {
+		t.Fatalf("getdents64 small buffer errno = %v, want EINVAL", errno)
+	}
+	file := uint64(tk.create("file", nil))
+	if _, errno := tk.getdents(file, 64); errno != linux.ENOTDIR {
+		t.Fatalf("getdents64 file errno = %v, want ENOTDIR", errno)
+	}
+	if _, errno := tk.getdents(1000, 64); errno != linux.EBADF {
+		t.Fatalf("getdents64 bad fd errno = %v, want EBADF", errno)
+	}
+}
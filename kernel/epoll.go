@@ -0,0 +1,506 @@
+package kernel
+
+import (
+	"io/fs"
+	"math"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/emulator"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+const (
+	EPOLL_CLOEXEC = O_CLOEXEC
+
+	EPOLL_CTL_ADD = 1
+	EPOLL_CTL_DEL = 2
+	EPOLL_CTL_MOD = 3
+
+	EPOLLIN        = POLLIN
+	EPOLLPRI       = POLLPRI
+	EPOLLOUT       = POLLOUT
+	EPOLLERR       = POLLERR
+	EPOLLHUP       = POLLHUP
+	EPOLLRDNORM    = POLLRDNORM
+	EPOLLRDBAND    = POLLRDBAND
+	EPOLLWRNORM    = POLLWRNORM
+	EPOLLWRBAND    = POLLWRBAND
+	EPOLLMSG       = POLLMSG
+	EPOLLRDHUP     = POLLRDHUP
+	EPOLLEXCLUSIVE = 1 << 28
+	EPOLLWAKEUP    = 1 << 29
+	EPOLLONESHOT   = 1 << 30
+	EPOLLET        = 1 << 31
+
+	EP_PRIVATE_BITS        = EPOLLWAKEUP | EPOLLONESHOT | EPOLLET | EPOLLEXCLUSIVE
+	EPOLLEXCLUSIVE_OK_BITS = EPOLLIN | EPOLLOUT | EPOLLERR | EPOLLHUP | EPOLLWAKEUP | EPOLLET | EPOLLEXCLUSIVE
+)
+
+type epoll_event struct {
+	events uint32
+	data   uint64
+}
+
+type epitem struct {
+	fd     int
+	desc   *fileDesc
+	file   PollFile
+	events uint32
+	data   uint64
+	armed  <-chan struct{}
+}
+
+type epwatch struct {
+	item      *epitem
+	ch        <-chan struct{}
+	exclusive bool
+}
+
+type eventpoll struct {
+	mu     sync.Mutex
+	items  []*epitem
+	ctl    chan struct{}
+	watch  chan struct{}
+	closed bool
+	mtime  time.Time
+}
+
+type epollClaim struct {
+	waiters int
+	taken   bool
+}
+
+type epollClaims struct {
+	mu sync.Mutex
+	m  map[<-chan struct{}]*epollClaim
+}
+
+type anonInfo struct {
+	name  string
+	mtime time.Time
+}
+
+func (ev *epoll_event) ctype(c *ccodec) {
+	cUint32(c, &ev.events)
+	if c.model.arch != emulator.ARCH_X86_64 {
+		cUint64(c, &ev.data)
+		return
+	}
+	lo, hi := uint32(ev.data), uint32(ev.data>>32)
+	cUint32(c, &lo)
+	cUint32(c, &hi)
+	ev.data = uint64(hi)<<32 | uint64(lo)
+}
+
+func (f *fcntl) epollOf(fd int) *eventpoll {
+	f.rw.RLock()
+	defer f.rw.RUnlock()
+	return f.epolls[fd]
+}
+
+func (f *fcntl) pollFile(fd int, file filesystem.File) (PollFile, bool) {
+	f.rw.RLock()
+	defer f.rw.RUnlock()
+	if p, ok := f.pipes[fd]; ok {
+		return p, true
+	} else if ep, ok := f.epolls[fd]; ok {
+		return ep, true
+	}
+	pf, ok := file.(PollFile)
+	return pf, ok
+}
+
+func (f *fcntl) epollRelease(d *fileDesc) {
+	for _, ep := range f.epolls {
+		ep.forget(d)
+	}
+}
+
+func (f *fcntl) epoll_create(ctx linux.Context, size int32) int32 {
+	if size <= 0 {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	return f.epoll_create1(ctx, 0)
+}
+
+func (f *fcntl) epoll_create1(ctx linux.Context, flags int32) int32 {
+	if flags&^EPOLL_CLOEXEC != 0 {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	ep := &eventpoll{ctl: make(chan struct{}), mtime: time.Now()}
+	fd := ctx.Debugger().CreateFileDescriptor(ep)
+	f.rw.Lock()
+	f.setFlags(fd, O_RDWR|flags)
+	f.install(fd, ep, "")
+	f.epolls[fd] = ep
+	f.rw.Unlock()
+	return int32(fd)
+}
+
+func (f *fcntl) epoll_ctl(ctx linux.Context, epfd, op, fd int32, event emuptr) int32 {
+	var ev epoll_event
+	if op != EPOLL_CTL_DEL && memExtract(ctx, event, &ev) != nil {
+		ctx.SetErrno(linux.EFAULT)
+		return -1
+	}
+	dbg := ctx.Debugger()
+	if _, err := dbg.GetFile(int(epfd)); err != nil {
+		ctx.SetErrno(linux.EBADF)
+		return -1
+	}
+	file, err := f.getFile(dbg, int(fd))
+	if err != nil {
+		ctx.SetErrno(linux.EBADF)
+		return -1
+	}
+	target, ok := f.pollFile(int(fd), file)
+	if !ok {
+		ctx.SetErrno(linux.EPERM)
+		return -1
+	}
+	ep := f.epollOf(int(epfd))
+	if ep == nil || epfd == fd || target == PollFile(ep) {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	nested, _ := target.(*eventpoll)
+	if ev.events&EPOLLEXCLUSIVE != 0 && (op == EPOLL_CTL_MOD || op == EPOLL_CTL_ADD && (nested != nil || ev.events&^EPOLLEXCLUSIVE_OK_BITS != 0)) {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	if op == EPOLL_CTL_ADD && nested != nil && nested.reaches(ep) {
+		ctx.SetErrno(linux.ELOOP)
+		return -1
+	}
+	f.rw.Lock()
+	desc := f.desc(int(fd))
+	f.rw.Unlock()
+	if errno := ep.control(op, int(fd), desc, target, ev); errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	return 0
+}
+
+func (f *fcntl) epoll_wait(ctx linux.Context, epfd int32, events emuptr, maxevents, timeout int32) int32 {
+	return f.epoll_pwait(ctx, epfd, events, maxevents, timeout, emunullptr, 0)
+}
+
+func (f *fcntl) epoll_pwait(ctx linux.Context, epfd int32, events emuptr, maxevents, timeout int32, sigmask emuptr, sigsetsize size_t) int32 {
+	d := time.Duration(-1)
+	if timeout >= 0 {
+		d = time.Duration(timeout) * time.Millisecond
+	}
+	return f.epollWait(ctx, epfd, events, maxevents, d, sigmask, sigsetsize)
+}
+
+func (f *fcntl) epoll_pwait2(ctx linux.Context, epfd int32, events emuptr, maxevents int32, timeout, sigmask emuptr, sigsetsize size_t) int32 {
+	d := time.Duration(-1)
+	if timeout != emunullptr {
+		var ts timespec64
+		if memExtract(ctx, timeout, &ts) != nil {
+			ctx.SetErrno(linux.EFAULT)
+			return -1
+		} else if ts.tv_sec < 0 || ts.tv_nsec < 0 || ts.tv_nsec >= int64(time.Second) {
+			ctx.SetErrno(linux.EINVAL)
+			return -1
+		}
+		d = time.Duration(min(ts.tv_sec, math.MaxInt64/int64(time.Second)-1))*time.Second + time.Duration(ts.tv_nsec)
+	}
+	return f.epollWait(ctx, epfd, events, maxevents, d, sigmask, sigsetsize)
+}
+
+func (f *fcntl) epollWait(ctx linux.Context, epfd int32, events emuptr, maxevents int32, timeout time.Duration, sigmask emuptr, sigsetsize size_t) int32 {
+	dbg := ctx.Debugger()
+	size, _ := sizeOf(modelOf(dbg.Arch()), &epoll_event{})
+	if maxevents <= 0 || int(maxevents) > math.MaxInt32/size {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	if _, err := dbg.GetFile(int(epfd)); err != nil {
+		ctx.SetErrno(linux.EBADF)
+		return -1
+	}
+	ep := f.epollOf(int(epfd))
+	if ep == nil {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	if sigmask != emunullptr {
+		var set sigset_t
+		if sigsetsize != 8 {
+			ctx.SetErrno(linux.EINVAL)
+			return -1
+		} else if memExtract(ctx, sigmask, &set) != nil {
+			ctx.SetErrno(linux.EFAULT)
+			return -1
+		}
+		defer f.signal.swapMask(f.signal.swapMask(set))
+	}
+	var expire <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expire = timer.C
+	}
+	done := taskDone(ctx)
+	var skip map[*epitem]bool
+	for {
+		ready, watches, ctl, ok := ep.scan(int(maxevents), skip)
+		if !ok {
+			ctx.SetErrno(linux.EINTR)
+			return -1
+		} else if len(ready) > 0 {
+			for i := range ready {
+				if memWrite(ctx, events+emuptr(i*size), &ready[i]) != nil {
+					ctx.SetErrno(linux.EFAULT)
+					return -1
+				}
+			}
+			return int32(len(ready))
+		} else if timeout == 0 {
+			return 0
+		}
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctl)},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(done)},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(expire)},
+		}
+		for _, w := range watches {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(w.ch)})
+		}
+		excl := f.claims.enter(watches)
+		chosen, _, _ := reflect.Select(cases)
+		skip = f.claims.leave(excl, chosen != 1 && chosen != 2)
+		switch chosen {
+		case 1:
+			ctx.SetErrno(linux.EINTR)
+			return -1
+		case 2:
+			return 0
+		}
+	}
+}
+
+func (it *epitem) ready(mask uint32) bool {
+	if it.events&^EP_PRIVATE_BITS == 0 || mask&it.events == 0 {
+		return false
+	} else if it.events&EPOLLET == 0 || it.armed == nil {
+		return true
+	}
+	select {
+	case <-it.armed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (ep *eventpoll) changed() {
+	close(ep.ctl)
+	ep.ctl = make(chan struct{})
+}
+
+func (ep *eventpoll) control(op int32, fd int, desc *fileDesc, file PollFile, ev epoll_event) linux.Errno {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	if ep.closed {
+		return linux.EBADF
+	}
+	i := slices.IndexFunc(ep.items, func(it *epitem) bool { return it.fd == fd && it.desc == desc })
+	switch op {
+	case EPOLL_CTL_ADD:
+		if i >= 0 {
+			return linux.EEXIST
+		}
+		ep.items = append(ep.items, &epitem{fd: fd, desc: desc, file: file, events: ev.events | EPOLLERR | EPOLLHUP, data: ev.data})
+	case EPOLL_CTL_DEL:
+		if i < 0 {
+			return linux.ENOENT
+		}
+		ep.items = slices.Delete(ep.items, i, i+1)
+	case EPOLL_CTL_MOD:
+		if i < 0 {
+			return linux.ENOENT
+		}
+		it := ep.items[i]
+		if it.events&EPOLLEXCLUSIVE != 0 {
+			return linux.EINVAL
+		}
+		it.events, it.data, it.armed = ev.events|EPOLLERR|EPOLLHUP, ev.data, nil
+	default:
+		return linux.EINVAL
+	}
+	ep.changed()
+	return 0
+}
+
+func (ep *eventpoll) forget(d *fileDesc) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	n := len(ep.items)
+	ep.items = slices.DeleteFunc(ep.items, func(it *epitem) bool { return it.desc == d })
+	if len(ep.items) != n {
+		ep.changed()
+	}
+}
+
+func (ep *eventpoll) reaches(target *eventpoll) bool {
+	if ep == target {
+		return true
+	}
+	var nested []*eventpoll
+	ep.mu.Lock()
+	for _, it := range ep.items {
+		if n, ok := it.file.(*eventpoll); ok {
+			nested = append(nested, n)
+		}
+	}
+	ep.mu.Unlock()
+	return slices.ContainsFunc(nested, func(n *eventpoll) bool { return n.reaches(target) })
+}
+
+func (ep *eventpoll) scan(max int, skip map[*epitem]bool) (ready []epoll_event, watches []epwatch, ctl <-chan struct{}, ok bool) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	if ep.closed {
+		return nil, nil, nil, false
+	}
+	var reported []*epitem
+	for _, it := range ep.items {
+		mask, changed := it.file.Poll()
+		if len(ready) < max && !skip[it] && it.ready(mask) {
+			ready = append(ready, epoll_event{events: mask & it.events, data: it.data})
+			reported = append(reported, it)
+			it.armed = changed
+			if it.events&EPOLLONESHOT != 0 {
+				it.events &= EP_PRIVATE_BITS
+			}
+		}
+		watches = append(watches, epwatch{item: it, ch: changed, exclusive: it.events&EPOLLEXCLUSIVE != 0})
+	}
+	if len(reported) > 0 {
+		ep.items = append(slices.DeleteFunc(ep.items, func(it *epitem) bool { return slices.Contains(reported, it) }), reported...)
+	}
+	return ready, watches, ep.ctl, true
+}
+
+func (ep *eventpoll) notify(chans []<-chan struct{}, watch chan struct{}) {
+	cases := make([]reflect.SelectCase, len(chans))
+	for i, ch := range chans {
+		cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)}
+	}
+	reflect.Select(cases)
+	ep.mu.Lock()
+	if ep.watch == watch {
+		ep.watch = nil
+	}
+	ep.mu.Unlock()
+	close(watch)
+}
+
+func (ep *eventpoll) Poll() (uint32, <-chan struct{}) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	var events uint32
+	chans := []<-chan struct{}{ep.ctl}
+	for _, it := range ep.items {
+		mask, changed := it.file.Poll()
+		if it.ready(mask) {
+			events = POLLIN | POLLRDNORM
+		}
+		chans = append(chans, changed)
+	}
+	if ep.watch == nil {
+		ep.watch = make(chan struct{})
+		go ep.notify(chans, ep.watch)
+	}
+	return events, ep.watch
+}
+
+func (ep *eventpoll) Stat() (fs.FileInfo, error) {
+	return anonInfo{"anon_inode:[eventpoll]", ep.mtime}, nil
+}
+
+func (ep *eventpoll) Close() error {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	if ep.closed {
+		return fs.ErrClosed
+	}
+	ep.closed = true
+	ep.items = nil
+	ep.changed()
+	return nil
+}
+
+func (c *epollClaims) enter(watches []epwatch) []epwatch {
+	var excl []epwatch
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, w := range watches {
+		if !w.exclusive {
+			continue
+		}
+		cl, ok := c.m[w.ch]
+		if !ok {
+			cl = new(epollClaim)
+			c.m[w.ch] = cl
+		}
+		cl.waiters++
+		excl = append(excl, w)
+	}
+	return excl
+}
+
+func (c *epollClaims) leave(excl []epwatch, claim bool) map[*epitem]bool {
+	skip := make(map[*epitem]bool)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, w := range excl {
+		cl := c.m[w.ch]
+		select {
+		case <-w.ch:
+			if cl.taken {
+				skip[w.item] = true
+			} else if claim {
+				cl.taken = true
+			}
+		default:
+		}
+		if cl.waiters--; cl.waiters == 0 {
+			delete(c.m, w.ch)
+		}
+	}
+	return skip
+}
+
+func (info anonInfo) Name() string {
+	return info.name
+}
+
+func (info anonInfo) Size() int64 {
+	return 0
+}
+
+func (info anonInfo) Mode() fs.FileMode {
+	return fs.ModeIrregular | 0600
+}
+
+func (info anonInfo) ModTime() time.Time {
+	return info.mtime
+}
+
+func (info anonInfo) IsDir() bool {
+	return false
+}
+
+func (info anonInfo) Sys() any {
+	return nil
+}
@@ -0,0 +1,217 @@
+package kernel
+
+import (
+	"testing"
+	"time"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/emulator"
+)
+
+func (tk *testKernel) epollCtl(ep uint64, op int, fd uint64, events uint32, data uint64) linux.Errno {
+	tk.tb.Helper()
+	_, errno := tk.call(linux.NR_epoll_ctl, ep, uint64(op), fd, tk.encode(&epoll_event{events: events, data: data}))
+	return errno
+}
+
+func (tk *testKernel) epollWait(ep uint64, timeout int) ([]epoll_event, linux.Errno) {
+	tk.tb.Helper()
+	size, _ := sizeOf(modelOf(tk.dbg.Arch()), &epoll_event{})
+	buf := tk.alloc(uint64(size * 8))
+	n, errno := tk.call(linux.NR_epoll_pwait, ep, buf, 8, uint64(timeout), 0, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	events := make([]epoll_event, n)
+	for i := range events {
+		tk.decode(buf+emuptr(i*size), &events[i])
+	}
+	return events, 0
+}
+
+func TestEpollTriggers(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	lt, _ := tk.call(linux.NR_epoll_create1, EPOLL_CLOEXEC)
+	if flags, _ := tk.call(linux.NR_fcntl, lt, 1); flags != FD_CLOEXEC {
+		t.Fatalf("epoll_create1(EPOLL_CLOEXEC) F_GETFD = %d", flags)
+	}
+	et, _ := tk.call(linux.NR_epoll_create, 1)
+	r, w := tk.pipe()
+	if errno := tk.epollCtl(lt, EPOLL_CTL_ADD, r, EPOLLIN, 1); errno != 0 {
+		t.Fatalf("epoll_ctl(ADD) errno = %v", errno)
+	}
+	tk.epollCtl(et, EPOLL_CTL_ADD, r, EPOLLIN|EPOLLET, 0x1122334455667788)
+	if events, _ := tk.epollWait(lt, 0); len(events) != 0 {
+		t.Fatalf("epoll_wait on an empty pipe = %v", events)
+	}
+	tk.call(linux.NR_write, w, tk.cstring("x"), 1)
+	for i := 0; i < 2; i++ {
+		if events, _ := tk.epollWait(lt, 0); len(events) != 1 || events[0] != (epoll_event{EPOLLIN, 1}) {
+			t.Fatalf("level-triggered wait #%d = %v", i, events)
+		}
+	}
+	if events, _ := tk.epollWait(et, 0); len(events) != 1 || events[0] != (epoll_event{EPOLLIN, 0x1122334455667788}) {
+		t.Fatalf("edge-triggered wait = %v", events)
+	}
+	if events, _ := tk.epollWait(et, 0); len(events) != 0 {
+		t.Fatalf("edge-triggered wait without a new edge = %v", events)
+	}
+	tk.call(linux.NR_write, w, tk.cstring("y"), 1)
+	if events, _ := tk.epollWait(et, 0); len(events) != 1 {
+		t.Fatalf("edge-triggered wait after a write = %v", events)
+	}
+
+	tk.epollCtl(lt, EPOLL_CTL_MOD, r, EPOLLIN|EPOLLONESHOT, 2)
+	for i, want := range []int{1, 0} {
+		if events, _ := tk.epollWait(lt, 0); len(events) != want {
+			t.Fatalf("oneshot wait #%d = %v, want %d events", i, events, want)
+		}
+	}
+	tk.epollCtl(lt, EPOLL_CTL_MOD, r, EPOLLIN, 3)
+	if events, _ := tk.epollWait(lt, 0); len(events) != 1 || events[0].data != 3 {
+		t.Fatalf("wait after re-arming = %v", events)
+	}
+
+	tk.call(linux.NR_close, w)
+	if events, _ := tk.epollWait(lt, 0); len(events) != 1 || events[0].events != EPOLLIN|EPOLLHUP {
+		t.Fatalf("wait after hangup = %v", events)
+	}
+	tk.call(linux.NR_close, r)
+	if events, _ := tk.epollWait(lt, 0); len(events) != 0 {
+		t.Fatalf("wait after closing the target = %v", events)
+	}
+	if errno := tk.epollCtl(lt, EPOLL_CTL_DEL, r, 0, 0); errno != linux.EBADF {
+		t.Fatalf("epoll_ctl(DEL, closed fd) errno = %v, want EBADF", errno)
+	}
+}
+
+func TestEpollCtlErrors(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_X86_64)
+	if _, errno := tk.call(linux.NR_epoll_create, 0); errno != linux.EINVAL {
+		t.Fatalf("epoll_create(0) errno = %v, want EINVAL", errno)
+	}
+	if _, errno := tk.call(linux.NR_epoll_create1, 1); errno != linux.EINVAL {
+		t.Fatalf("epoll_create1(1) errno = %v, want EINVAL", errno)
+	}
+	ep, _ := tk.call(linux.NR_epoll_create1, 0)
+	ep2, _ := tk.call(linux.NR_epoll_create1, 0)
+	r, w := tk.pipe()
+	file := uint64(tk.create("file", nil))
+	tk.epollCtl(ep, EPOLL_CTL_ADD, w, EPOLLOUT|EPOLLEXCLUSIVE, 0)
+	tk.epollCtl(ep, EPOLL_CTL_ADD, ep2, EPOLLIN, 0)
+	tests := []struct {
+		name   string
+		ep     uint64
+		op     int
+		fd     uint64
+		events uint32
+		errno  linux.Errno
+	}{
+		{"bad epfd", 1000, EPOLL_CTL_ADD, r, EPOLLIN, linux.EBADF},
+		{"bad fd", ep, EPOLL_CTL_ADD, 1000, EPOLLIN, linux.EBADF},
+		{"not epoll", r, EPOLL_CTL_ADD, w, EPOLLIN, linux.EINVAL},
+		{"self", ep, EPOLL_CTL_ADD, ep, EPOLLIN, linux.EINVAL},
+		{"regular file", ep, EPOLL_CTL_ADD, file, EPOLLIN, linux.EPERM},
+		{"exists", ep, EPOLL_CTL_ADD, w, EPOLLOUT, linux.EEXIST},
+		{"mod missing", ep, EPOLL_CTL_MOD, r, EPOLLIN, linux.ENOENT},
+		{"del missing", ep, EPOLL_CTL_DEL, r, 0, linux.ENOENT},
+		{"bad op", ep, 4, r, EPOLLIN, linux.EINVAL},
+		{"exclusive mod", ep, EPOLL_CTL_MOD, r, EPOLLIN | EPOLLEXCLUSIVE, linux.EINVAL},
+		{"exclusive bits", ep, EPOLL_CTL_ADD, r, EPOLLPRI | EPOLLEXCLUSIVE, linux.EINVAL},
+		{"exclusive epoll", ep2, EPOLL_CTL_ADD, ep, EPOLLIN | EPOLLEXCLUSIVE, linux.EINVAL},
+		{"mod exclusive", ep, EPOLL_CTL_MOD, w, EPOLLOUT, linux.EINVAL},
+		{"loop", ep2, EPOLL_CTL_ADD, ep, EPOLLIN, linux.ELOOP},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errno := tk.epollCtl(tt.ep, tt.op, tt.fd, tt.events, 0); errno != tt.errno {
+				t.Fatalf("errno = %v, want %v", errno, tt.errno)
+			}
+		})
+	}
+	if _, errno := tk.call(linux.NR_epoll_ctl, ep, EPOLL_CTL_ADD, r, 0x1000); errno != linux.EFAULT {
+		t.Fatalf("epoll_ctl(bad event) errno = %v, want EFAULT", errno)
+	}
+	buf := tk.alloc(64)
+	if _, errno := tk.call(linux.NR_epoll_wait, ep, buf, 0, 0); errno != linux.EINVAL {
+		t.Fatalf("epoll_wait(maxevents 0) errno = %v, want EINVAL", errno)
+	}
+	if _, errno := tk.call(linux.NR_epoll_wait, r, buf, 1, 0); errno != linux.EINVAL {
+		t.Fatalf("epoll_wait(pipe) errno = %v, want EINVAL", errno)
+	}
+	if _, errno := tk.call(linux.NR_epoll_pwait, ep, buf, 1, 0, tk.alloc(8), 4); errno != linux.EINVAL {
+		t.Fatalf("epoll_pwait(sigsetsize 4) errno = %v, want EINVAL", errno)
+	}
+	if _, errno := tk.call(linux.NR_epoll_pwait2, ep, buf, 1, tk.encode(&timespec64{tv_sec: -1}), 0, 0); errno != linux.EINVAL {
+		t.Fatalf("epoll_pwait2(negative timeout) errno = %v, want EINVAL", errno)
+	}
+	if _, errno := tk.call(linux.NR_epoll_wait, ep, 0x1000, 1, 0); errno != linux.EFAULT {
+		t.Fatalf("epoll_wait(bad events) errno = %v, want EFAULT", errno)
+	}
+	for arch, want := range map[emulator.Arch]int{emulator.ARCH_X86: 12, emulator.ARCH_X86_64: 12, emulator.ARCH_ARM: 16, emulator.ARCH_ARM64: 16} {
+		if size, _ := sizeOf(modelOf(arch), &epoll_event{}); size != want {
+			t.Errorf("sizeof(struct epoll_event) on %v = %d, want %d", arch, size, want)
+		}
+	}
+}
+
+func TestEpollBlocking(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM)
+	ep, _ := tk.call(linux.NR_epoll_create1, 0)
+	r, w := tk.pipe()
+	tk.epollCtl(ep, EPOLL_CTL_ADD, r, EPOLLIN, 7)
+	start := time.Now()
+	if n, errno := tk.call(linux.NR_epoll_pwait2, ep, tk.alloc(16), 1, tk.encode(&timespec64{tv_nsec: int64(20 * time.Millisecond)}), 0, 0); errno != 0 || n != 0 {
+		t.Fatalf("epoll_pwait2 timeout = %d, errno = %v", n, errno)
+	} else if d := time.Since(start); d < 20*time.Millisecond {
+		t.Fatalf("epoll_pwait2 returned after %v", d)
+	}
+
+	outer, _ := tk.call(linux.NR_epoll_create1, 0)
+	tk.epollCtl(outer, EPOLL_CTL_ADD, ep, EPOLLIN, 8)
+	waiter := tk.task(2)
+	mask := tk.encode(new(sigset_t))
+	done := make(chan []epoll_event, 1)
+	go func() {
+		events, errno := waiter.epollWait(outer, -1)
+		if errno != 0 {
+			t.Errorf("epoll_pwait errno = %v", errno)
+		}
+		done <- events
+	}()
+	time.Sleep(20 * time.Millisecond)
+	tk.call(linux.NR_write, w, tk.cstring("x"), 1)
+	if events := <-done; len(events) != 1 || events[0] != (epoll_event{EPOLLIN, 8}) {
+		t.Fatalf("nested epoll_pwait = %v", events)
+	}
+	blocked := sigset_t(1 << (SIGPIPE - 1))
+	tk.call(linux.NR_rt_sigprocmask, 1, tk.encode(&blocked), 0, 8)
+	if n, errno := tk.call(linux.NR_epoll_pwait, ep, tk.alloc(16), 1, 0, mask, 8); errno != 0 || n != 1 {
+		t.Fatalf("epoll_pwait(sigmask) = %d, errno = %v", n, errno)
+	}
+	if tk.sys.signal.set != blocked {
+		t.Fatalf("epoll_pwait left the signal mask at %#x", tk.sys.signal.set)
+	}
+}
+
+func TestEpollExclusive(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_X86_64)
+	r, w := tk.pipe()
+	results := make(chan int, 2)
+	for tid := 2; tid <= 3; tid++ {
+		waiter := tk.task(tid)
+		ep, _ := waiter.call(linux.NR_epoll_create1, 0)
+		if errno := waiter.epollCtl(ep, EPOLL_CTL_ADD, r, EPOLLIN|EPOLLEXCLUSIVE, 0); errno != 0 {
+			t.Fatalf("epoll_ctl(EPOLLEXCLUSIVE) errno = %v", errno)
+		}
+		go func() {
+			events, _ := waiter.epollWait(ep, 200)
+			results <- len(events)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	tk.call(linux.NR_write, w, tk.cstring("x"), 1)
+	if a, b := <-results, <-results; a+b != 1 {
+		t.Fatalf("exclusive waiters woke with %d and %d events, want exactly one wakeup", a, b)
+	}
+}
@@ -2,20 +2,55 @@ package kernel
 
 import (
 	"errors"
-	"fmt"
 	"io"
-	"io/fs"
-	"os"
+	"math"
 	"sync"
 	"unsafe"
 
 	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
 	"github.com/wnxd/microdbg/filesystem"
 )
 
 const (
 	AT_FDCWD = -100
 
+	O_ACCMODE   = 0x3
+	O_RDONLY    = 0x0
+	O_WRONLY    = 0x1
+	O_RDWR      = 0x2
+	O_CREAT     = 0x40
+	O_EXCL      = 0x80
+	O_NOCTTY    = 0x100
+	O_TRUNC     = 0x200
+	O_APPEND    = 0x400
+	O_NONBLOCK  = 0x800
+	O_DSYNC     = 0x1000
+	O_ASYNC     = 0x2000
+	O_DIRECT    = 0x4000
+	O_LARGEFILE = 0x8000
+	O_DIRECTORY = 0x10000
+	O_NOFOLLOW  = 0x20000
+	O_NOATIME   = 0x40000
+	O_CLOEXEC   = 0x80000
+	O_SYNC      = 0x101000
+	O_PATH      = 0x200000
+	O_TMPFILE   = 0x410000
+
+	FD_CLOEXEC = 1
+
+	SETFL_MASK = O_APPEND | O_NONBLOCK | O_ASYNC | O_DIRECT | O_NOATIME
+
+	UIO_MAXIOV   = 1024
+	MAX_RW_COUNT = 0x7FFFF000
+
+	RWF_HIPRI  = 0x1
+	RWF_DSYNC  = 0x2
+	RWF_SYNC   = 0x4
+	RWF_NOWAIT = 0x8
+	RWF_APPEND = 0x10
+
 	S_IFIFO  = 0x1000
 	S_IFCHR  = 0x2000
 	S_IFDIR  = 0x4000
@@ -26,7 +61,7 @@ const (
 )
 
 type iovec struct {
-	iov_base uintptr
+	iov_base emuptr
 	iov_len  size_t
 }
 
@@ -69,47 +104,171 @@ type stat64 struct {
 	_          uint32
 }
 
-var (
-	_ = stat3264{}.st_dev
-	_ = stat3264{}.__st_ino
-	_ = stat3264{}.st_nlink
-	_ = stat3264{}.st_uid
-	_ = stat3264{}.st_gid
-	_ = stat3264{}.st_rdev
-	_ = stat3264{}.st_blksize
-	_ = stat3264{}.st_blocks
-	_ = stat3264{}.st_ino
-	_ = stat64{}.st_dev
-	_ = stat64{}.st_ino
-	_ = stat64{}.st_nlink
-	_ = stat64{}.st_uid
-	_ = stat64{}.st_gid
-	_ = stat64{}.st_rdev
-	_ = stat64{}.st_blksize
-	_ = stat64{}.st_blocks
-)
+func (iov *iovec) ctype(c *ccodec) {
+	cULong(c, &iov.iov_base)
+	cULong(c, &iov.iov_len)
+}
+
+func (st *stat3264) ctype(c *ccodec) {
+	cUint64(c, &st.st_dev)
+	c.pad(4)
+	cULong(c, &st.__st_ino)
+	cUint32(c, &st.st_mode)
+	cUint32(c, &st.st_nlink)
+	cUint32(c, &st.st_uid)
+	cUint32(c, &st.st_gid)
+	cUint64(c, &st.st_rdev)
+	c.pad(4)
+	cInt64(c, &st.st_size)
+	cULong(c, &st.st_blksize)
+	cUint64(c, &st.st_blocks)
+	c.nested(&st.st_atim)
+	c.nested(&st.st_mtim)
+	c.nested(&st.st_ctim)
+	cUint64(c, &st.st_ino)
+}
+
+func (st *stat64) ctype(c *ccodec) {
+	if c.model.arch == emulator.ARCH_X86_64 {
+		cULong(c, &st.st_dev)
+		cULong(c, &st.st_ino)
+		nlink := ulong_t(st.st_nlink)
+		cULong(c, &nlink)
+		st.st_nlink = nlink_t(nlink)
+		cUint32(c, &st.st_mode)
+		cUint32(c, &st.st_uid)
+		cUint32(c, &st.st_gid)
+		c.pad(4)
+		cULong(c, &st.st_rdev)
+		cLong(c, &st.st_size)
+		blksize := long_t(st.st_blksize)
+		cLong(c, &blksize)
+		st.st_blksize = int32(blksize)
+		cLong(c, &st.st_blocks)
+		c.nested(&st.st_atim)
+		c.nested(&st.st_mtim)
+		c.nested(&st.st_ctim)
+		c.pad(3 * c.model.long)
+		return
+	}
+	cULong(c, &st.st_dev)
+	cULong(c, &st.st_ino)
+	cUint32(c, &st.st_mode)
+	cUint32(c, &st.st_nlink)
+	cUint32(c, &st.st_uid)
+	cUint32(c, &st.st_gid)
+	cULong(c, &st.st_rdev)
+	c.pad(c.model.long)
+	cLong(c, &st.st_size)
+	cInt32(c, &st.st_blksize)
+	c.pad(4)
+	cLong(c, &st.st_blocks)
+	c.nested(&st.st_atim)
+	c.nested(&st.st_mtim)
+	c.nested(&st.st_ctim)
+	c.pad(8)
+}
 
 type fcntl struct {
-	rw    sync.RWMutex
-	flags map[int]int32
+	rw      sync.RWMutex
+	flags   map[int]int32
+	fdflags map[int]int32
+	dirs    map[int]*dirStream
+	paths   map[int]string
+	tmps    map[int]*tmpFile
+	descs   map[int]*fileDesc
+	pipes   map[int]*pipeEnd
+	epolls  map[int]*eventpoll
+	claims  epollClaims
+	attrs   map[inodeKey]*inodeAttr
+	locks   lockManager
+	sched   *sched
+	signal  *signal
+	root    string
+	cwd     string
+	cmask   mode_t
+	cred    cred
 }
 
 func (f *fcntl) ctor() {
 	f.flags = make(map[int]int32)
+	f.fdflags = make(map[int]int32)
+	f.dirs = make(map[int]*dirStream)
+	f.paths = make(map[int]string)
+	f.tmps = make(map[int]*tmpFile)
+	f.descs = make(map[int]*fileDesc)
+	f.pipes = make(map[int]*pipeEnd)
+	f.epolls = make(map[int]*eventpoll)
+	f.claims.m = make(map[<-chan struct{}]*epollClaim)
+	f.locks.ctor()
+	f.attrs = make(map[inodeKey]*inodeAttr)
+	f.root = "/"
+	f.cwd = "/"
+	f.cmask = 022
 }
 
 func (f *fcntl) dtor() {
+	for fd := range f.tmps {
+		f.releaseTmp(fd)
+	}
+	for _, p := range f.pipes {
+		p.hangup()
+	}
+	for _, ep := range f.epolls {
+		ep.Close()
+	}
 	f.flags = nil
+	f.fdflags = nil
+	f.dirs = nil
+	f.paths = nil
+	f.tmps = nil
+	f.descs = nil
+	f.pipes = nil
+	f.epolls = nil
+	f.attrs = nil
+	f.locks.dtor()
+}
+
+func (f *fcntl) dup(ctx linux.Context, fildes uint32) int32 {
+	newfd, err := f.dupfd(ctx.Debugger(), int(fildes), 0)
+	if err != nil {
+		ctx.SetErrno(linux.EBADF)
+		return -1
+	}
+	f.rw.Lock()
+	f.flags[newfd] = f.flags[int(fildes)]
+	f.dupState(int(fildes), newfd)
+	f.rw.Unlock()
+	return int32(newfd)
+}
+
+func (f *fcntl) dup2(ctx linux.Context, oldfd, newfd uint32) int32 {
+	if oldfd == newfd {
+		if _, err := ctx.Debugger().GetFile(int(oldfd)); err != nil {
+			ctx.SetErrno(linux.EBADF)
+			return -1
+		}
+		return int32(newfd)
+	}
+	return f.dup3(ctx, oldfd, newfd, 0)
 }
 
 func (f *fcntl) dup3(ctx linux.Context, oldfd, newfd uint32, flags int32) int32 {
+	if flags&^O_CLOEXEC != 0 || oldfd == newfd {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
 	err := ctx.Debugger().Dup2File(int(oldfd), int(newfd))
 	if err != nil {
 		ctx.SetErrno(linux.EBADF)
 		return -1
 	}
 	f.rw.Lock()
-	f.flags[int(newfd)] = flags
+	f.flags[int(newfd)] = f.flags[int(oldfd)]
+	f.dupState(int(oldfd), int(newfd))
+	if flags&O_CLOEXEC != 0 {
+		f.fdflags[int(newfd)] = FD_CLOEXEC
+	}
 	f.rw.Unlock()
 	return int32(newfd)
 }
@@ -121,301 +280,466 @@ func (f *fcntl) fcntl(ctx linux.Context, fd, cmd uint32, arg ulong_t) int32 {
 		F_SETFD
 		F_GETFL
 		F_SETFL
-		F_GETLK
-		F_SETLK
-		F_SETLKW
-		F_SETOWN
-		F_GETOWN
-		F_SETSIG
-		F_GETSIG
-		F_GETLK64
-		F_SETLK64
-		F_SETLKW64
-
-		O_CLOEXEC = 0x80000
+
+		F_DUPFD_CLOEXEC = 1030
 	)
 
 	dbg := ctx.Debugger()
-	_, err := dbg.GetFile(int(fd))
+	file, err := f.getFile(dbg, int(fd))
 	if err != nil {
 		ctx.SetErrno(linux.EBADF)
 		return -1
 	}
+	f.rw.RLock()
+	status := f.flags[int(fd)]
+	f.rw.RUnlock()
 	switch cmd {
-	case F_DUPFD:
-		newfd, err := dbg.DupFile(int(fd))
+	case F_DUPFD, F_DUPFD_CLOEXEC:
+		if int32(arg) < 0 {
+			ctx.SetErrno(linux.EINVAL)
+			return -1
+		}
+		newfd, err := f.dupfd(dbg, int(fd), int(int32(arg)))
 		if err != nil {
 			ctx.SetErrno(linux.EBADF)
 			return -1
 		}
 		f.rw.Lock()
-		f.flags[newfd] = f.flags[int(fd)]
+		f.flags[newfd] = status
+		f.dupState(int(fd), newfd)
+		if cmd == F_DUPFD_CLOEXEC {
+			f.fdflags[newfd] = FD_CLOEXEC
+		}
 		f.rw.Unlock()
 		return int32(newfd)
 	case F_GETFD:
 		f.rw.RLock()
-		hasExec := f.flags[int(fd)]&O_CLOEXEC != 0
-		f.rw.RUnlock()
-		if hasExec {
-			return 1
-		}
-		return 0
+		defer f.rw.RUnlock()
+		return f.fdflags[int(fd)]
 	case F_SETFD:
 		f.rw.Lock()
-		f.flags[int(fd)] |= O_CLOEXEC
+		if arg&FD_CLOEXEC != 0 {
+			f.fdflags[int(fd)] = FD_CLOEXEC
+		} else {
+			delete(f.fdflags, int(fd))
+		}
 		f.rw.Unlock()
 		return 0
 	case F_GETFL:
-		f.rw.RLock()
-		flag := f.flags[int(fd)]
-		f.rw.RUnlock()
-		return flag
+		return openFlags(dbg.Arch(), status)
+	}
+	if status&O_PATH != 0 {
+		ctx.SetErrno(linux.EBADF)
+		return -1
+	}
+	switch cmd {
 	case F_SETFL:
+		flag := openFlags(dbg.Arch(), int32(arg))&SETFL_MASK | status&^SETFL_MASK
+		if nb, ok := f.stream(ctx, int(fd), file).(NonblockFile); ok && (flag^status)&O_NONBLOCK != 0 {
+			nb.SetNonblock(flag&O_NONBLOCK != 0)
+		}
 		f.rw.Lock()
-		f.flags[int(fd)] = int32(arg)
+		f.flags[int(fd)] = flag
 		f.rw.Unlock()
 		return 0
-	case F_GETLK, F_GETLK64:
-		return 0
-	case F_SETLK, F_SETLKW, F_SETLK64, F_SETLKW64:
-		return 0
+	case F_GETLK, F_SETLK, F_SETLKW:
+		return f.fcntlLock(ctx, int(fd), file, cmd, emuptr(arg), false)
+	case F_GETLK64, F_SETLK64, F_SETLKW64, F_OFD_GETLK, F_OFD_SETLK, F_OFD_SETLKW:
+		return f.fcntlLock(ctx, int(fd), file, cmd, emuptr(arg), true)
+	case F_SETPIPE_SZ, F_GETPIPE_SZ:
+		return f.pipeSize(ctx, int(fd), cmd, arg)
 	}
-	panic(fmt.Errorf("fcntl: %d %w", cmd, errors.ErrUnsupported))
+	ctx.SetErrno(linux.EINVAL)
+	return -1
 }
 
-func (f *fcntl) faccessat(ctx linux.Context, dfd int32, filename emuptr, mode int32) int32 {
-	path, err := ctx.ToPointer(filename).MemReadString()
-	if err != nil {
-		ctx.SetErrno(linux.ENOENT)
-		return -1
-	}
-	dbg := ctx.Debugger()
-	var dir filesystem.FS
-	if dfd != AT_FDCWD {
-		file, err := dbg.GetFile(int(dfd))
+func (f *fcntl) fcntl64(ctx linux.Context, fd, cmd uint32, arg ulong_t) int32 {
+	switch cmd {
+	case F_OFD_GETLK, F_OFD_SETLK, F_OFD_SETLKW:
+		file, err := f.getFile(ctx.Debugger(), int(fd))
 		if err != nil {
 			ctx.SetErrno(linux.EBADF)
 			return -1
 		}
-		var ok bool
-		dir, ok = file.(filesystem.FS)
-		if !ok {
-			ctx.SetErrno(linux.ENOTDIR)
-			return -1
-		}
-	} else {
-		dir = dbg.GetFS()
-	}
-	file, err := dir.OpenFile(path, filesystem.O_RDONLY, fs.FileMode(mode))
-	if err != nil {
-		ctx.SetErrno(linux.ENOENT)
-		return -1
+		return f.fcntlLock(ctx, int(fd), file, cmd, emuptr(arg), true)
 	}
-	file.Close()
-	return 0
+	return f.fcntl(ctx, fd, cmd, arg)
+}
+
+func (f *fcntl) faccessat(ctx linux.Context, dfd int32, filename emuptr, mode int32) int32 {
+	return f.faccessat2(ctx, dfd, filename, mode, 0)
 }
 
 func (f *fcntl) open(ctx linux.Context, filename emuptr, flags, mode int32) int32 {
-	path, err := ctx.ToPointer(filename).MemReadString()
-	if err != nil {
-		ctx.SetErrno(linux.ENOENT)
-		return -1
-	}
-	dbg := ctx.Debugger()
-	file, err := dbg.OpenFile(path, toFileFlag(flags), fs.FileMode(mode))
-	if err != nil {
-		if errors.Is(err, fs.ErrExist) {
-			ctx.SetErrno(linux.EEXIST)
-			return -1
-		}
-		ctx.SetErrno(linux.ENOENT)
-		return -1
-	}
-	fd := dbg.CreateFileDescriptor(file)
-	f.rw.Lock()
-	f.flags[fd] = flags
-	f.rw.Unlock()
-	return int32(fd)
+	return f.openat(ctx, AT_FDCWD, filename, flags, mode)
 }
 
 func (f *fcntl) openat(ctx linux.Context, dfd int32, filename emuptr, flags, mode int32) int32 {
-	path, err := ctx.ToPointer(filename).MemReadString()
-	if err != nil {
-		ctx.SetErrno(linux.ENOENT)
-		return -1
+	flags = openFlags(ctx.Debugger().Arch(), flags) & VALID_OPEN_FLAGS
+	if flags&O_PATH != 0 {
+		flags &= O_PATH_FLAGS
 	}
-	dbg := ctx.Debugger()
-	var dir filesystem.FS
-	if dfd != AT_FDCWD {
-		file, err := dbg.GetFile(int(dfd))
-		if err != nil {
-			ctx.SetErrno(linux.EBADF)
-			return -1
-		}
-		var ok bool
-		dir, ok = file.(filesystem.FS)
-		if !ok {
-			ctx.SetErrno(linux.ENOTDIR)
-			return -1
-		}
-	} else {
-		dir = dbg.GetFS()
+	if flags&(O_CREAT|__O_TMPFILE) == 0 {
+		mode = 0
 	}
-	file, err := dir.OpenFile(path, toFileFlag(flags), fs.FileMode(mode))
-	if err != nil {
-		if errors.Is(err, fs.ErrExist) {
-			ctx.SetErrno(linux.EEXIST)
-			return -1
-		}
-		ctx.SetErrno(linux.ENOENT)
-		return -1
-	}
-	fd := dbg.CreateFileDescriptor(file)
-	f.rw.Lock()
-	f.flags[fd] = flags
-	f.rw.Unlock()
-	return int32(fd)
+	return f.doOpen(ctx, dfd, filename, flags, mode_t(mode)&S_IALLUGO, 0)
 }
 
 func (f *fcntl) close(ctx linux.Context, fd uint32) int32 {
-	file, err := ctx.Debugger().CloseFileDescriptor(int(fd))
-	if err != nil {
+	if f.closeFd(ctx, int(fd)) != nil {
 		ctx.SetErrno(linux.EBADF)
 		return -1
 	}
+	return 0
+}
+
+func (f *fcntl) closeFd(ctx linux.Context, fd int) error {
+	file, err := ctx.Debugger().CloseFileDescriptor(fd)
+	if err != nil {
+		return err
+	}
+	f.releasePosix(ctx, fd, file)
 	file.Close()
 	f.rw.Lock()
-	delete(f.flags, int(fd))
+	f.dropDesc(fd)
+	delete(f.flags, fd)
+	delete(f.fdflags, fd)
+	delete(f.dirs, fd)
+	delete(f.paths, fd)
+	delete(f.pipes, fd)
+	delete(f.epolls, fd)
+	f.releaseTmp(fd)
 	f.rw.Unlock()
-	return 0
+	return nil
+}
+
+func (f *fcntl) closeOnExec(ctx linux.Context) {
+	var fds []int
+	f.rw.RLock()
+	for fd, flags := range f.fdflags {
+		if flags&FD_CLOEXEC != 0 {
+			fds = append(fds, fd)
+		}
+	}
+	f.rw.RUnlock()
+	for _, fd := range fds {
+		f.closeFd(ctx, fd)
+	}
 }
 
 func (f *fcntl) pipe2(ctx linux.Context, fildes emuptr, flags int32) int32 {
-	r, w, err := os.Pipe()
-	if err != nil {
-		ctx.SetErrno(linux.EMFILE)
+	flags = openFlags(ctx.Debugger().Arch(), flags)
+	if flags&^(O_CLOEXEC|O_NONBLOCK|O_DIRECT) != 0 {
+		ctx.SetErrno(linux.EINVAL)
 		return -1
 	}
+	r, w := newPipe(flags)
 	dbg := ctx.Debugger()
 	rfd := dbg.CreateFileDescriptor(r)
 	wfd := dbg.CreateFileDescriptor(w)
 	f.rw.Lock()
-	f.flags[rfd] = flags
-	f.flags[wfd] = flags
+	f.setFlags(rfd, flags)
+	f.setFlags(wfd, flags|O_WRONLY)
+	f.install(rfd, r, r.name)
+	f.install(wfd, w, w.name)
+	f.pipes[rfd], f.pipes[wfd] = r, w
 	f.rw.Unlock()
-	dbg.MemWrite(fildes, [2]int32{int32(rfd), int32(wfd)})
+	fds := [2]int32{int32(rfd), int32(wfd)}
+	err := ctx.ToPointer(fildes).MemWritePtr(uint64(unsafe.Sizeof(fds)), unsafe.Pointer(&fds))
+	if err != nil {
+		f.close(ctx, uint32(rfd))
+		f.close(ctx, uint32(wfd))
+		ctx.SetErrno(linux.EFAULT)
+		return -1
+	}
 	return 0
 }
 
 func (f *fcntl) lseek(ctx linux.Context, fd uint32, offset off_t, whence int32) off_t {
-	file, err := ctx.Debugger().GetFile(int(fd))
+	file, err := f.getFile(ctx.Debugger(), int(fd))
 	if err != nil {
 		ctx.SetErrno(linux.EBADF)
 		return -1
 	}
+	if info, err := file.Stat(); err == nil && info.IsDir() {
+		return f.seekdir(ctx, int(fd), file, offset, whence)
+	}
 	if seek, ok := file.(io.Seeker); ok {
 		off, err := seek.Seek(int64(offset), int(whence))
 		if err != nil {
-			ctx.SetErrno(linux.EINVAL)
+			ctx.SetErrno(linux.ToErrno(err))
 			return -1
 		}
 		return off_t(off)
 	}
-	ctx.SetErrno(linux.EINVAL)
+	ctx.SetErrno(linux.ESPIPE)
 	return -1
 }
 
 func (f *fcntl) read(ctx linux.Context, fd uint32, buf emuptr, count size_t) ssize_t {
-	file, err := ctx.Debugger().GetFile(int(fd))
-	if err != nil {
-		ctx.SetErrno(linux.EBADF)
+	return f.readIov(ctx, fd, []iovec{{iov_base: buf, iov_len: min(count, MAX_RW_COUNT)}}, -1)
+}
+
+func (f *fcntl) write(ctx linux.Context, fd uint32, buf emuptr, count size_t) ssize_t {
+	return f.writeIov(ctx, fd, []iovec{{iov_base: buf, iov_len: min(count, MAX_RW_COUNT)}}, -1)
+}
+
+func (f *fcntl) readv(ctx linux.Context, fd uint32, iov emuptr, iovcnt int32) ssize_t {
+	return f.preadv2(ctx, fd, iov, iovcnt, -1, 0)
+}
+
+func (f *fcntl) writev(ctx linux.Context, fd uint32, iov emuptr, iovcnt int32) ssize_t {
+	return f.pwritev2(ctx, fd, iov, iovcnt, -1, 0)
+}
+
+func (f *fcntl) pread64(ctx linux.Context, fd uint32, buf emuptr, count size_t, pos loff_t) ssize_t {
+	if pos < 0 {
+		ctx.SetErrno(linux.EINVAL)
 		return -1
 	}
-	r, ok := file.(io.Reader)
-	if !ok {
-		ctx.SetErrno(linux.EINTR)
+	return f.readIov(ctx, fd, []iovec{{iov_base: buf, iov_len: min(count, MAX_RW_COUNT)}}, pos)
+}
+
+func (f *fcntl) pwrite64(ctx linux.Context, fd uint32, buf emuptr, count size_t, pos loff_t) ssize_t {
+	if pos < 0 {
+		ctx.SetErrno(linux.EINVAL)
 		return -1
 	}
-	n, err := io.CopyN(io.NewOffsetWriter(ctx.ToPointer(buf), 0), r, int64(count))
-	if err != nil {
-		ctx.SetErrno(linux.EIO)
+	return f.writeIov(ctx, fd, []iovec{{iov_base: buf, iov_len: min(count, MAX_RW_COUNT)}}, pos)
+}
+
+func (f *fcntl) preadv(ctx linux.Context, fd uint32, iov emuptr, iovcnt int32, pos loff_t) ssize_t {
+	if pos < 0 {
+		ctx.SetErrno(linux.EINVAL)
 		return -1
 	}
-	return ssize_t(n)
+	return f.preadv2(ctx, fd, iov, iovcnt, pos, 0)
 }
 
-func (f *fcntl) write(ctx linux.Context, fd uint32, buf emuptr, count size_t) ssize_t {
-	file, err := ctx.Debugger().GetFile(int(fd))
+func (f *fcntl) pwritev(ctx linux.Context, fd uint32, iov emuptr, iovcnt int32, pos loff_t) ssize_t {
+	if pos < 0 {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	return f.pwritev2(ctx, fd, iov, iovcnt, pos, 0)
+}
+
+func (f *fcntl) preadv2(ctx linux.Context, fd uint32, iov emuptr, iovcnt int32, pos loff_t, flags int32) ssize_t {
+	if flags&^(RWF_HIPRI|RWF_DSYNC|RWF_SYNC|RWF_NOWAIT|RWF_APPEND) != 0 {
+		ctx.SetErrno(linux.EOPNOTSUPP)
+		return -1
+	} else if pos < -1 {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	arr, errno := f.extractIovec(ctx, iov, iovcnt)
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	return f.readIov(ctx, fd, arr, pos)
+}
+
+func (f *fcntl) pwritev2(ctx linux.Context, fd uint32, iov emuptr, iovcnt int32, pos loff_t, flags int32) ssize_t {
+	if flags&^(RWF_HIPRI|RWF_DSYNC|RWF_SYNC|RWF_NOWAIT|RWF_APPEND) != 0 {
+		ctx.SetErrno(linux.EOPNOTSUPP)
+		return -1
+	} else if pos < -1 {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	arr, errno := f.extractIovec(ctx, iov, iovcnt)
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	return f.writeIov(ctx, fd, arr, pos)
+}
+
+func (f *fcntl) readIov(ctx linux.Context, fd uint32, iov []iovec, pos loff_t) ssize_t {
+	file, err := f.getFile(ctx.Debugger(), int(fd))
 	if err != nil {
 		ctx.SetErrno(linux.EBADF)
 		return -1
 	}
-	w, ok := file.(io.Writer)
-	if !ok {
-		ctx.SetErrno(linux.EINTR)
+	var r io.Reader
+	if pos == -1 {
+		r, _ = f.stream(ctx, int(fd), file).(io.Reader)
+	} else if ra, ok := file.(io.ReaderAt); ok {
+		r = io.NewSectionReader(ra, int64(pos), math.MaxInt64-int64(pos))
+	} else if _, ok := file.(io.Reader); ok {
+		ctx.SetErrno(linux.ESPIPE)
 		return -1
 	}
-	n, err := io.Copy(w, io.NewSectionReader(ctx.ToPointer(buf), 0, int64(count)))
-	if err != nil {
-		ctx.SetErrno(linux.EIO)
+	if r == nil {
+		ctx.SetErrno(linux.EBADF)
 		return -1
 	}
+	var total size_t
+	for i := range iov {
+		total += min(iov[i].iov_len, MAX_RW_COUNT-total)
+	}
+	if total == 0 {
+		return 0
+	}
+	_, regular := file.(io.ReaderAt)
+	buf := make([]byte, min(total, spliceChunk))
+	var n size_t
+	for n < total {
+		b := buf[:min(size_t(len(buf)), total-n)]
+		m, err := r.Read(b)
+		if m > 0 {
+			w := scatterIov(ctx, iov, n, b[:m])
+			n += size_t(w)
+			if w < m {
+				if n == 0 {
+					ctx.SetErrno(linux.EFAULT)
+					return -1
+				}
+				break
+			}
+		}
+		if err != nil {
+			if err != io.EOF && n == 0 {
+				ctx.SetErrno(linux.ToErrno(err))
+				return -1
+			}
+			break
+		} else if m < len(b) || !regular {
+			break
+		}
+	}
 	return ssize_t(n)
 }
 
-func (f *fcntl) writev(ctx linux.Context, fd uint32, iov emuptr, iovcnt int32) ssize_t {
-	dbg := ctx.Debugger()
-	file, err := dbg.GetFile(int(fd))
+func scatterIov(ctx linux.Context, iov []iovec, off size_t, b []byte) int {
+	var n int
+	for i := 0; i < len(iov) && n < len(b); i++ {
+		if off >= iov[i].iov_len {
+			off -= iov[i].iov_len
+			continue
+		}
+		chunk := b[n : n+int(min(iov[i].iov_len-off, size_t(len(b)-n)))]
+		if ctx.ToPointer(iov[i].iov_base+emuptr(off)).MemWrite(chunk) != nil {
+			break
+		}
+		n += len(chunk)
+		off = 0
+	}
+	return n
+}
+
+func (f *fcntl) writeIov(ctx linux.Context, fd uint32, iov []iovec, pos loff_t) ssize_t {
+	file, err := f.getFile(ctx.Debugger(), int(fd))
 	if err != nil {
 		ctx.SetErrno(linux.EBADF)
 		return -1
 	}
-	w, ok := file.(io.Writer)
-	if !ok {
-		ctx.SetErrno(linux.EINTR)
+	var w io.Writer
+	if pos == -1 {
+		w, _ = f.stream(ctx, int(fd), file).(io.Writer)
+	} else if wa, ok := file.(io.WriterAt); ok {
+		w = io.NewOffsetWriter(wa, int64(pos))
+	} else if _, ok := file.(io.Writer); ok {
+		ctx.SetErrno(linux.ESPIPE)
 		return -1
 	}
-	arr := make([]iovec, iovcnt)
-	dbg.MemExtract(iov, arr)
-	var n ssize_t
-	for i := range arr {
-		ptr := ctx.ToPointer(emuptr(arr[i].iov_base))
-		m, err := io.Copy(w, io.NewSectionReader(ptr, 0, int64(arr[i].iov_len)))
+	if w == nil {
+		ctx.SetErrno(linux.EBADF)
+		return -1
+	}
+	var total size_t
+	for i := range iov {
+		total += min(iov[i].iov_len, MAX_RW_COUNT-total)
+	}
+	if total == 0 {
+		return 0
+	}
+	buf := make([]byte, min(total, spliceChunk))
+	var n size_t
+	for n < total {
+		b := buf[:min(size_t(len(buf)), total-n)]
+		m := gatherIov(ctx, iov, n, b)
+		if m == 0 {
+			if n == 0 {
+				ctx.SetErrno(linux.EFAULT)
+				return -1
+			}
+			break
+		}
+		wn, err := w.Write(b[:m])
+		n += size_t(wn)
+		if errors.Is(err, linux.EPIPE) {
+			f.signal.raise(ctx, SIGPIPE)
+		}
 		if err != nil {
-			ctx.SetErrno(linux.EIO)
-			return -1
+			if n == 0 {
+				ctx.SetErrno(linux.ToErrno(err))
+				return -1
+			}
+			break
+		} else if wn < m || m < len(b) {
+			break
+		}
+	}
+	return ssize_t(n)
+}
+
+func gatherIov(ctx linux.Context, iov []iovec, off size_t, b []byte) int {
+	var n int
+	for i := 0; i < len(iov) && n < len(b); i++ {
+		if off >= iov[i].iov_len {
+			off -= iov[i].iov_len
+			continue
+		}
+		chunk := b[n : n+int(min(iov[i].iov_len-off, size_t(len(b)-n)))]
+		if ctx.ToPointer(iov[i].iov_base+emuptr(off)).MemReadPtr(uint64(len(chunk)), unsafe.Pointer(&chunk[0])) != nil {
+			break
 		}
-		n += ssize_t(m)
+		n += len(chunk)
+		off = 0
 	}
 	return n
 }
 
+func (f *fcntl) extractIovec(ctx linux.Context, iov emuptr, iovcnt int32) ([]iovec, linux.Errno) {
+	if iovcnt < 0 || iovcnt > UIO_MAXIOV {
+		return nil, linux.EINVAL
+	}
+	arr := make([]iovec, iovcnt)
+	err := memExtractArray(ctx, iov, arr)
+	if err != nil {
+		return nil, linux.EFAULT
+	}
+	var total size_t
+	for i := range arr {
+		if arr[i].iov_len > MAX_RW_COUNT-total {
+			arr[i].iov_len = MAX_RW_COUNT - total
+		}
+		total += arr[i].iov_len
+	}
+	return arr, 0
+}
+
 func (f *fcntl) readlinkat(ctx linux.Context, dfd int32, filename, buf emuptr, bufsiz size_t) ssize_t {
 	path, err := ctx.ToPointer(filename).MemReadString()
 	if err != nil {
-		ctx.SetErrno(linux.ENOENT)
+		ctx.SetErrno(linux.EFAULT)
 		return -1
 	}
-	dbg := ctx.Debugger()
-	var dir filesystem.ReadlinkFS
-	if dfd != AT_FDCWD {
-		file, err := dbg.GetFile(int(dfd))
-		if err != nil {
-			ctx.SetErrno(linux.EBADF)
-			return -1
-		}
-		var ok bool
-		dir, ok = file.(filesystem.ReadlinkFS)
-		if !ok {
-			ctx.SetErrno(linux.ENOTDIR)
-			return -1
-		}
-	} else {
-		dir = dbg.GetFS().(filesystem.ReadlinkFS)
+	fsys, path, errno := f.lookup(ctx, dfd, path)
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	dir, ok := fsys.(filesystem.ReadlinkFS)
+	if !ok {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
 	}
 	link, err := dir.Readlink(path)
 	if err != nil {
-		ctx.SetErrno(linux.EINVAL)
+		ctx.SetErrno(linux.ToErrno(err))
 		return -1
 	}
 	size := min(uint64(len(link)), uint64(bufsiz))
@@ -424,225 +748,148 @@ func (f *fcntl) readlinkat(ctx linux.Context, dfd int32, filename, buf emuptr, b
 }
 
 func (f *fcntl) fstatat3264(ctx linux.Context, dfd int32, filename, statbuf emuptr, flag int32) int32 {
-	path, err := ctx.ToPointer(filename).MemReadString()
-	if err != nil {
-		ctx.SetErrno(linux.ENOENT)
+	st, errno := f.statat(ctx, dfd, filename, flag)
+	if errno != 0 {
+		ctx.SetErrno(errno)
 		return -1
 	}
-	dbg := ctx.Debugger()
-	var dir fs.FS
-	if dfd != AT_FDCWD {
-		file, err := dbg.GetFile(int(dfd))
-		if err != nil {
-			ctx.SetErrno(linux.EBADF)
-			return -1
-		}
-		var ok bool
-		dir, ok = file.(fs.FS)
-		if !ok {
-			ctx.SetErrno(linux.ENOTDIR)
-			return -1
-		}
-	} else {
-		dir = dbg.GetFS()
+	stat := st.stat3264()
+	if memWrite(ctx, statbuf, &stat) != nil {
+		ctx.SetErrno(linux.EFAULT)
+		return -1
 	}
-	info, err := fs.Stat(dir, path)
-	if err != nil {
-		ctx.SetErrno(linux.ENOENT)
-		return -1
-	}
-	var stat stat3264
-	mode := info.Mode()
-	stat.st_mode = mode_t(mode.Perm())
-	switch mode.Type() {
-	case fs.ModeCharDevice:
-		stat.st_mode |= S_IFCHR
-	case fs.ModeDevice:
-		stat.st_mode |= S_IFBLK
-	case fs.ModeDir:
-		stat.st_mode |= S_IFDIR
-	case fs.ModeNamedPipe:
-		stat.st_mode |= S_IFIFO
-	case fs.ModeSymlink:
-		stat.st_mode |= S_IFLNK
-	case fs.ModeSocket:
-		stat.st_mode |= S_IFSOCK
-	default:
-		stat.st_mode |= S_IFREG
-	}
-	stat.st_size = info.Size()
-	nano := info.ModTime().UnixNano()
-	ts := timespec{
-		tv_sec:  time_t(nano / 1e9),
-		tv_nsec: long_t(nano % 1e9),
-	}
-	stat.st_atim = ts
-	stat.st_mtim = ts
-	stat.st_ctim = ts
-	dbg.MemWrite(statbuf, stat)
 	return 0
 }
 
 func (f *fcntl) fstatat64(ctx linux.Context, dfd int32, filename, statbuf emuptr, flag int32) int32 {
-	path, err := ctx.ToPointer(filename).MemReadString()
-	if err != nil {
-		ctx.SetErrno(linux.ENOENT)
+	st, errno := f.statat(ctx, dfd, filename, flag)
+	if errno != 0 {
+		ctx.SetErrno(errno)
 		return -1
 	}
-	dbg := ctx.Debugger()
-	var dir fs.FS
-	if dfd != AT_FDCWD {
-		file, err := dbg.GetFile(int(dfd))
-		if err != nil {
-			ctx.SetErrno(linux.EBADF)
-			return -1
-		}
-		var ok bool
-		dir, ok = file.(fs.FS)
-		if !ok {
-			ctx.SetErrno(linux.ENOTDIR)
-			return -1
-		}
-	} else {
-		dir = dbg.GetFS()
+	stat := st.stat64()
+	if memWrite(ctx, statbuf, &stat) != nil {
+		ctx.SetErrno(linux.EFAULT)
+		return -1
 	}
-	info, err := fs.Stat(dir, path)
-	if err != nil {
-		ctx.SetErrno(linux.ENOENT)
-		return -1
-	}
-	var stat stat64
-	mode := info.Mode()
-	stat.st_mode = mode_t(mode.Perm())
-	switch mode.Type() {
-	case fs.ModeCharDevice:
-		stat.st_mode |= S_IFCHR
-	case fs.ModeDevice:
-		stat.st_mode |= S_IFBLK
-	case fs.ModeDir:
-		stat.st_mode |= S_IFDIR
-	case fs.ModeNamedPipe:
-		stat.st_mode |= S_IFIFO
-	case fs.ModeSymlink:
-		stat.st_mode |= S_IFLNK
-	case fs.ModeSocket:
-		stat.st_mode |= S_IFSOCK
-	default:
-		stat.st_mode |= S_IFREG
-	}
-	stat.st_size = off_t(info.Size())
-	nano := info.ModTime().UnixNano()
-	ts := timespec{
-		tv_sec:  time_t(nano / 1e9),
-		tv_nsec: long_t(nano % 1e9),
-	}
-	stat.st_atim = ts
-	stat.st_mtim = ts
-	stat.st_ctim = ts
-	dbg.MemWrite(statbuf, stat)
 	return 0
 }
 
 func (f *fcntl) fstat3264(ctx linux.Context, fd uint32, statbuf emuptr) int32 {
-	dbg := ctx.Debugger()
-	file, err := dbg.GetFile(int(fd))
-	if err != nil {
-		ctx.SetErrno(linux.EBADF)
+	st, errno := f.statfd(ctx, fd)
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	stat := st.stat3264()
+	if memWrite(ctx, statbuf, &stat) != nil {
+		ctx.SetErrno(linux.EFAULT)
 		return -1
 	}
-	info, err := file.Stat()
-	if err != nil {
-		ctx.SetErrno(linux.ENOENT)
-		return -1
-	}
-	var stat stat3264
-	mode := info.Mode()
-	stat.st_mode = mode_t(mode.Perm())
-	switch mode.Type() {
-	case fs.ModeCharDevice:
-		stat.st_mode |= S_IFCHR
-	case fs.ModeDevice:
-		stat.st_mode |= S_IFBLK
-	case fs.ModeDir:
-		stat.st_mode |= S_IFDIR
-	case fs.ModeNamedPipe:
-		stat.st_mode |= S_IFIFO
-	case fs.ModeSymlink:
-		stat.st_mode |= S_IFLNK
-	case fs.ModeSocket:
-		stat.st_mode |= S_IFSOCK
-	default:
-		stat.st_mode |= S_IFREG
-	}
-	stat.st_size = info.Size()
-	nano := info.ModTime().UnixNano()
-	ts := timespec{
-		tv_sec:  time_t(nano / 1e9),
-		tv_nsec: long_t(nano % 1e9),
-	}
-	stat.st_atim = ts
-	stat.st_mtim = ts
-	stat.st_ctim = ts
-	dbg.MemWrite(statbuf, stat)
 	return 0
 }
 
 func (f *fcntl) fstat64(ctx linux.Context, fd uint32, statbuf emuptr) int32 {
-	dbg := ctx.Debugger()
-	file, err := dbg.GetFile(int(fd))
-	if err != nil {
-		ctx.SetErrno(linux.EBADF)
+	st, errno := f.statfd(ctx, fd)
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	stat := st.stat64()
+	if memWrite(ctx, statbuf, &stat) != nil {
+		ctx.SetErrno(linux.EFAULT)
 		return -1
 	}
-	info, err := file.Stat()
-	if err != nil {
-		ctx.SetErrno(linux.ENOENT)
-		return -1
-	}
-	var stat stat64
-	mode := info.Mode()
-	stat.st_mode = mode_t(mode.Perm())
-	switch mode.Type() {
-	case fs.ModeCharDevice:
-		stat.st_mode |= S_IFCHR
-	case fs.ModeDevice:
-		stat.st_mode |= S_IFBLK
-	case fs.ModeDir:
-		stat.st_mode |= S_IFDIR
-	case fs.ModeNamedPipe:
-		stat.st_mode |= S_IFIFO
-	case fs.ModeSymlink:
-		stat.st_mode |= S_IFLNK
-	case fs.ModeSocket:
-		stat.st_mode |= S_IFSOCK
-	default:
-		stat.st_mode |= S_IFREG
-	}
-	stat.st_size = off_t(info.Size())
-	nano := info.ModTime().UnixNano()
-	ts := timespec{
-		tv_sec:  time_t(nano / 1e9),
-		tv_nsec: long_t(nano % 1e9),
-	}
-	stat.st_atim = ts
-	stat.st_mtim = ts
-	stat.st_ctim = ts
-	dbg.MemWrite(statbuf, stat)
 	return 0
 }
 
-func toFileFlag(flags int32) filesystem.FileFlag {
-	const (
-		O_RDONLY = 0
-		O_WRONLY = 1
-		O_RDWR   = 2
-		O_APPEND = 0x400
-		O_CREAT  = 0x40
-		O_EXCL   = 0x80
-		O_SYNC   = 0x101000
-		O_TRUNC  = 0x200
-	)
+func (f *fcntl) setFlags(fd int, flags int32) {
+	f.flags[fd] = flags &^ (O_CREAT | O_EXCL | O_NOCTTY | O_TRUNC | O_CLOEXEC)
+	if flags&O_CLOEXEC != 0 {
+		f.fdflags[fd] = FD_CLOEXEC
+	} else {
+		delete(f.fdflags, fd)
+	}
+}
+
+func (f *fcntl) dupfd(dbg debugger.Debugger, fd, minfd int) (int, error) {
+	tmp, err := dbg.DupFile(fd)
+	if err != nil || tmp >= minfd {
+		return tmp, err
+	}
+	newfd := minfd
+	for ; ; newfd++ {
+		if _, err := dbg.GetFile(newfd); err != nil {
+			break
+		}
+	}
+	if err := dbg.Dup2File(fd, newfd); err != nil {
+		return -1, err
+	}
+	if file, err := dbg.CloseFileDescriptor(tmp); err == nil {
+		file.Close()
+	}
+	return newfd, nil
+}
+
+func (f *fcntl) install(fd int, file filesystem.File, name string) {
+	d := f.desc(fd)
+	d.file, d.name = file, name
+}
+
+func (f *fcntl) getFile(dbg debugger.Debugger, fd int) (filesystem.File, error) {
+	file, err := dbg.GetFile(fd)
+	if err != nil {
+		return nil, err
+	}
+	f.rw.RLock()
+	defer f.rw.RUnlock()
+	if d, ok := f.descs[fd]; ok && d.file != nil {
+		return d.file, nil
+	}
+	return file, nil
+}
+
+func (f *fcntl) dupState(oldfd, newfd int) {
+	if oldfd == newfd {
+		return
+	}
+	delete(f.fdflags, newfd)
+	delete(f.dirs, newfd)
+	f.releaseTmp(newfd)
+	f.dropDesc(newfd)
+	d := f.desc(oldfd)
+	d.refs++
+	f.descs[newfd] = d
+	if dir, ok := f.paths[oldfd]; ok {
+		f.paths[newfd] = dir
+	} else {
+		delete(f.paths, newfd)
+	}
+	if tmp, ok := f.tmps[oldfd]; ok {
+		tmp.refs++
+		f.tmps[newfd] = tmp
+	}
+	if p, ok := f.pipes[oldfd]; ok {
+		f.pipes[newfd] = p
+	} else {
+		delete(f.pipes, newfd)
+	}
+	if ep, ok := f.epolls[oldfd]; ok {
+		f.epolls[newfd] = ep
+	} else {
+		delete(f.epolls, newfd)
+	}
+}
+
+func (f *fcntl) writable(fd int) bool {
+	f.rw.RLock()
+	flags, ok := f.flags[fd]
+	f.rw.RUnlock()
+	return !ok || flags&O_ACCMODE != 0
+}
 
+func toFileFlag(flags int32) filesystem.FileFlag {
 	ff := filesystem.O_RDONLY
 	if flags&O_WRONLY != 0 {
 		ff = filesystem.O_WRONLY
@@ -659,7 +906,7 @@ func toFileFlag(flags int32) filesystem.FileFlag {
 	if flags&O_EXCL != 0 {
 		ff |= filesystem.O_EXCL
 	}
-	if flags&O_SYNC != 0 {
+	if flags&O_SYNC == O_SYNC {
 		ff |= filesystem.O_SYNC
 	}
 	if flags&O_TRUNC != 0 {
@@ -0,0 +1,484 @@
+package kernel
+
+import (
+	"bytes"
+	"runtime"
+	"testing"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/emulator"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+const (
+	testO_RDWR  = 0x2
+	testO_CREAT = 0x40
+)
+
+var testAT_FDCWD = int64(AT_FDCWD)
+
+func (tk *testKernel) create(name string, data []byte) int {
+	tk.tb.Helper()
+	fd, errno := tk.call(linux.NR_openat, uint64(testAT_FDCWD), tk.cstring(name), testO_RDWR|testO_CREAT, 0644)
+	if errno != 0 {
+		tk.tb.Fatalf("openat(%q) errno = %d", name, errno)
+	}
+	if len(data) != 0 {
+		buf := tk.alloc(uint64(len(data)))
+		tk.ctx.ToPointer(buf).MemWrite(data)
+		if n, errno := tk.call(linux.NR_write, fd, buf, uint64(len(data))); errno != 0 || n != uint64(len(data)) {
+			tk.tb.Fatalf("write = %d, errno = %d", n, errno)
+		}
+	}
+	return int(fd)
+}
+
+func TestOpenReadWrite(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	fd := uint64(tk.create("file", []byte("hello, world")))
+	if r, errno := tk.call(linux.NR_lseek, fd, 7, 0); errno != 0 || r != 7 {
+		t.Fatalf("lseek = %d, errno = %d", r, errno)
+	}
+	buf := tk.alloc(16)
+	n, errno := tk.call(linux.NR_read, fd, buf, 16)
+	if errno != 0 {
+		t.Fatalf("read errno = %d", errno)
+	}
+	if got := tk.bytes(buf, int(n)); string(got) != "world" {
+		t.Fatalf("read = %q, want %q", got, "world")
+	}
+	if _, errno := tk.call(linux.NR_close, fd); errno != 0 {
+		t.Fatalf("close errno = %d", errno)
+	}
+	if _, errno := tk.call(linux.NR_close, fd); errno != linux.EBADF {
+		t.Fatalf("second close errno = %d, want EBADF", errno)
+	}
+	if _, errno := tk.call(linux.NR_openat, uint64(testAT_FDCWD), tk.cstring("missing"), 0, 0); errno != linux.ENOENT {
+		t.Fatalf("openat(missing) errno = %d, want ENOENT", errno)
+	}
+}
+
+func TestWritev(t *testing.T) {
+	for _, arch := range []emulator.Arch{emulator.ARCH_ARM, emulator.ARCH_ARM64, emulator.ARCH_X86, emulator.ARCH_X86_64} {
+		tk := newTestKernel(t, arch)
+		fd := uint64(tk.create("file", nil))
+		parts := []string{"foo", "", "bar"}
+		arr := tk.alloc(uint64(len(parts)) * 16)
+		model := modelOf(arch)
+		for i, part := range parts {
+			iov := iovec{iov_base: tk.cstring(part), iov_len: size_t(len(part))}
+			size, _ := sizeOf(model, &iov)
+			memWrite(tk.ctx, arr+uint64(i*size), &iov)
+		}
+		n, errno := tk.call(linux.NR_writev, fd, arr, uint64(len(parts)))
+		if errno != 0 || n != 6 {
+			t.Fatalf("%v: writev = %d, errno = %d", arch, n, errno)
+		}
+		tk.call(linux.NR_lseek, fd, 0, 0)
+		buf := tk.alloc(16)
+		n, _ = tk.call(linux.NR_read, fd, buf, 16)
+		if got := tk.bytes(buf, int(n)); string(got) != "foobar" {
+			t.Fatalf("%v: read = %q, want %q", arch, got, "foobar")
+		}
+	}
+}
+
+func TestFcntl(t *testing.T) {
+	const (
+		F_DUPFD = 0
+		F_GETFD = 1
+		F_SETFD = 2
+		F_GETFL = 3
+		F_SETFL = 4
+
+		F_DUPFD_CLOEXEC = 1030
+	)
+
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	fd := uint64(tk.create("file", nil))
+	tests := []struct {
+		name  string
+		fd    uint64
+		cmd   uint64
+		arg   uint64
+		want  uint64
+		errno linux.Errno
+	}{
+		{"getfl", fd, F_GETFL, 0, testO_RDWR, 0},
+		{"setfl", fd, F_SETFL, 0x800 | O_WRONLY | testO_CREAT, 0, 0},
+		{"getfl after setfl", fd, F_GETFL, 0, testO_RDWR | 0x800, 0},
+		{"getfd", fd, F_GETFD, 0, 0, 0},
+		{"setfd", fd, F_SETFD, FD_CLOEXEC, 0, 0},
+		{"getfd after setfd", fd, F_GETFD, 0, FD_CLOEXEC, 0},
+		{"getfl ignores cloexec", fd, F_GETFL, 0, testO_RDWR | 0x800, 0},
+		{"clear fd flags", fd, F_SETFD, 0, 0, 0},
+		{"getfd after clear", fd, F_GETFD, 0, 0, 0},
+		{"bad fd", 1000, F_GETFL, 0, ^uint64(0), linux.EBADF},
+		{"getown", fd, 9, 0, ^uint64(0), linux.EINVAL},
+		{"unknown", fd, 0xFFFF, 0, ^uint64(0), linux.EINVAL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, errno := tk.call(linux.NR_fcntl, tt.fd, tt.cmd, tt.arg)
+			if errno != tt.errno || r != tt.want {
+				t.Fatalf("fcntl = %d, errno = %d, want %d, errno = %d", r, errno, tt.want, tt.errno)
+			}
+		})
+	}
+	newfd, errno := tk.call(linux.NR_fcntl, fd, F_DUPFD, 0)
+	if errno != 0 || newfd == fd {
+		t.Fatalf("F_DUPFD = %d, errno = %d", newfd, errno)
+	}
+	if r, _ := tk.call(linux.NR_fcntl, newfd, F_GETFL, 0); r&0x800 == 0 {
+		t.Fatalf("F_GETFL on dup = %#x, want %#x set", r, 0x800)
+	}
+	if r, _ := tk.call(linux.NR_fcntl, newfd, F_GETFD, 0); r != 0 {
+		t.Fatalf("F_GETFD on dup = %d, want 0", r)
+	}
+	cloexec, errno := tk.call(linux.NR_fcntl, fd, F_DUPFD_CLOEXEC, 0)
+	if r, _ := tk.call(linux.NR_fcntl, cloexec, F_GETFD, 0); errno != 0 || r != FD_CLOEXEC {
+		t.Fatalf("F_DUPFD_CLOEXEC = %d, errno = %d, F_GETFD = %d", cloexec, errno, r)
+	}
+	if r, errno := tk.call(linux.NR_fcntl, fd, F_DUPFD, 100); errno != 0 || r != 100 {
+		t.Fatalf("F_DUPFD(100) = %d, errno = %d", r, errno)
+	}
+	if r, errno := tk.call(linux.NR_fcntl, fd, F_DUPFD, 100); errno != 0 || r != 101 {
+		t.Fatalf("second F_DUPFD(100) = %d, errno = %d", r, errno)
+	}
+	if _, errno := tk.call(linux.NR_fcntl, fd, F_DUPFD, ^uint64(0)); errno != linux.EINVAL {
+		t.Fatalf("F_DUPFD(-1) errno = %d, want EINVAL", errno)
+	}
+}
+
+func TestDup(t *testing.T) {
+	const F_GETFD = 1
+
+	tk := newTestKernel(t, emulator.ARCH_X86_64)
+	fd, errno := tk.call(linux.NR_openat, uint64(testAT_FDCWD), tk.cstring("file"), O_RDWR|O_CREAT|O_CLOEXEC, 0644)
+	if errno != 0 {
+		t.Fatalf("openat errno = %d", errno)
+	}
+	if r, _ := tk.call(linux.NR_fcntl, fd, F_GETFD, 0); r != FD_CLOEXEC {
+		t.Fatalf("F_GETFD = %d, want FD_CLOEXEC", r)
+	}
+	dup, errno := tk.call(linux.NR_dup, fd)
+	if r, _ := tk.call(linux.NR_fcntl, dup, F_GETFD, 0); errno != 0 || dup == fd || r != 0 {
+		t.Fatalf("dup = %d, errno = %d, F_GETFD = %d", dup, errno, r)
+	}
+	if r, errno := tk.call(linux.NR_dup2, fd, fd); errno != 0 || r != fd {
+		t.Fatalf("dup2 same fd = %d, errno = %d", r, errno)
+	}
+	if _, errno := tk.call(linux.NR_dup2, 1000, 1000); errno != linux.EBADF {
+		t.Fatalf("dup2 bad fd errno = %d, want EBADF", errno)
+	}
+	if r, errno := tk.call(linux.NR_dup2, fd, 50); errno != 0 || r != 50 {
+		t.Fatalf("dup2 = %d, errno = %d", r, errno)
+	}
+	if r, _ := tk.call(linux.NR_fcntl, 50, F_GETFD, 0); r != 0 {
+		t.Fatalf("F_GETFD after dup2 = %d, want 0", r)
+	}
+	if _, errno := tk.call(linux.NR_dup3, fd, 51, O_CLOEXEC); errno != 0 {
+		t.Fatalf("dup3 errno = %d", errno)
+	}
+	if r, _ := tk.call(linux.NR_fcntl, 51, F_GETFD, 0); r != FD_CLOEXEC {
+		t.Fatalf("F_GETFD after dup3 = %d, want FD_CLOEXEC", r)
+	}
+	if _, errno := tk.call(linux.NR_dup3, fd, fd, 0); errno != linux.EINVAL {
+		t.Fatalf("dup3 same fd errno = %d, want EINVAL", errno)
+	}
+	if _, errno := tk.call(linux.NR_dup3, fd, 52, O_NONBLOCK); errno != linux.EINVAL {
+		t.Fatalf("dup3 bad flags errno = %d, want EINVAL", errno)
+	}
+
+	tk.sys.Register(linux.NR_execve, func(ctx linux.Context, args *linux.SyscallArgs) uint64 { return 0 })
+	if _, errno := tk.call(linux.NR_execve, 0, 0, 0); errno != 0 {
+		t.Fatalf("execve errno = %d", errno)
+	}
+	for _, tt := range []struct {
+		fd   uint64
+		open bool
+	}{{fd, false}, {dup, true}, {50, true}, {51, false}} {
+		if _, err := tk.dbg.GetFile(int(tt.fd)); (err == nil) != tt.open {
+			t.Fatalf("fd %d open = %v after exec, want %v", tt.fd, err == nil, tt.open)
+		}
+	}
+	tk.sys.Unregister(linux.NR_execve)
+	if _, errno := tk.call(linux.NR_execve, 0, 0, 0); errno != linux.ENOSYS {
+		t.Fatalf("builtin execve errno = %d, want ENOSYS", errno)
+	}
+	if _, err := tk.dbg.GetFile(int(dup)); err != nil {
+		t.Fatal("failed execve closed descriptors")
+	}
+}
+
+func TestFstatat64(t *testing.T) {
+	data := bytes.Repeat([]byte{'x'}, 1234)
+	for _, arch := range []emulator.Arch{emulator.ARCH_ARM, emulator.ARCH_ARM64, emulator.ARCH_X86, emulator.ARCH_X86_64} {
+		tk := newTestKernel(t, arch)
+		fd := uint64(tk.create("file", data))
+		statbuf := tk.alloc(256)
+		if _, errno := tk.call(linux.NR_fstatat64, uint64(testAT_FDCWD), tk.cstring("file"), statbuf, 0); errno != 0 {
+			t.Fatalf("%v: fstatat64 errno = %d", arch, errno)
+		}
+		var mode mode_t
+		var size int64
+		switch arch {
+		case emulator.ARCH_ARM, emulator.ARCH_X86:
+			var st stat3264
+			tk.decode(statbuf, &st)
+			mode, size = st.st_mode, st.st_size
+		default:
+			var st stat64
+			tk.decode(statbuf, &st)
+			mode, size = st.st_mode, int64(st.st_size)
+		}
+		if mode&0xF000 != S_IFREG || mode&0777 != 0644 || size != int64(len(data)) {
+			t.Fatalf("%v: fstatat64 mode = %o, size = %d", arch, mode, size)
+		}
+		if _, errno := tk.call(linux.NR_fstat64, fd, statbuf); errno != 0 {
+			t.Fatalf("%v: fstat64 errno = %d", arch, errno)
+		}
+		if _, errno := tk.call(linux.NR_fstat64, 1000, statbuf); errno != linux.EBADF {
+			t.Fatalf("%v: fstat64(1000) errno = %d, want EBADF", arch, errno)
+		}
+	}
+}
+
+func TestStatLayout(t *testing.T) {
+	tests := []struct {
+		arch emulator.Arch
+		st   ctype
+		size int
+	}{
+		{emulator.ARCH_ARM, new(stat3264), 104},
+		{emulator.ARCH_X86, new(stat3264), 96},
+		{emulator.ARCH_ARM64, new(stat64), 128},
+		{emulator.ARCH_X86_64, new(stat64), 144},
+	}
+	for _, tt := range tests {
+		if size, _ := sizeOf(modelOf(tt.arch), tt.st); size != tt.size {
+			t.Errorf("%v: sizeof(struct stat) = %d, want %d", tt.arch, size, tt.size)
+		}
+	}
+}
+
+func TestOpenatErrno(t *testing.T) {
+	const (
+		O_WRONLY = 0x1
+		O_EXCL   = 0x80
+	)
+
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	tk.create("file", nil)
+	if _, err := tk.dbg.fs.(filesystem.DirFS).Mkdir("dir", 0755); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		path  emuptr
+		flags uint64
+		errno linux.Errno
+	}{
+		{"missing", tk.cstring("missing"), 0, linux.ENOENT},
+		{"exclusive", tk.cstring("file"), testO_CREAT | O_EXCL | O_WRONLY, linux.EEXIST},
+		{"directory", tk.cstring("dir"), O_WRONLY, linux.EISDIR},
+		{"not a directory", tk.cstring("file/x"), 0, linux.ENOTDIR},
+		{"fault", 0x1000, 0, linux.EFAULT},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, errno := tk.call(linux.NR_openat, uint64(testAT_FDCWD), tt.path, tt.flags, 0644); errno != tt.errno {
+				t.Fatalf("openat errno = %v, want %v", errno, tt.errno)
+			}
+		})
+	}
+}
+
+func (tk *testKernel) iovecs(parts ...iovec) emuptr {
+	tk.tb.Helper()
+	model := modelOf(tk.dbg.Arch())
+	size, _ := sizeOf(model, &iovec{})
+	arr := tk.alloc(uint64(len(parts) * size))
+	for i := range parts {
+		memWrite(tk.ctx, arr+uint64(i*size), &parts[i])
+	}
+	return arr
+}
+
+func TestReadv(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM)
+	fd := uint64(tk.create("file", []byte("abcdefgh")))
+	tk.call(linux.NR_lseek, fd, 1, 0)
+	a, b := tk.alloc(3), tk.alloc(16)
+	n, errno := tk.call(linux.NR_readv, fd, tk.iovecs(iovec{a, 3}, iovec{0, 0}, iovec{b, 16}), 3)
+	if errno != 0 || n != 7 || string(tk.bytes(a, 3)) != "bcd" || string(tk.bytes(b, 4)) != "efgh" {
+		t.Fatalf("readv = %d, errno = %v, a = %q, b = %q", n, errno, tk.bytes(a, 3), tk.bytes(b, 4))
+	}
+	if n, errno := tk.call(linux.NR_readv, fd, tk.iovecs(iovec{a, 3}), 1); errno != 0 || n != 0 {
+		t.Fatalf("readv at EOF = %d, errno = %v", n, errno)
+	}
+	if _, errno := tk.call(linux.NR_readv, fd, tk.iovecs(iovec{a, 3}), UIO_MAXIOV+1); errno != linux.EINVAL {
+		t.Fatalf("readv(UIO_MAXIOV+1) errno = %v, want EINVAL", errno)
+	}
+	if _, errno := tk.call(linux.NR_readv, fd, 0x1000, 1); errno != linux.EFAULT {
+		t.Fatalf("readv(bad iov) errno = %v, want EFAULT", errno)
+	}
+}
+
+func TestReadvChunked(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	data := make([]byte, 2*spliceChunk+5)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	fd := uint64(tk.create("big", data))
+	tk.call(linux.NR_lseek, fd, 0, 0)
+	a, b := tk.alloc(7), tk.alloc(uint64(len(data)-7))
+	n, errno := tk.call(linux.NR_readv, fd, tk.iovecs(iovec{a, 7}, iovec{b, MAX_RW_COUNT}), 2)
+	if errno != 0 || n != uint64(len(data)) {
+		t.Fatalf("readv = %d, errno = %d", n, errno)
+	}
+	if got := append(tk.bytes(a, 7), tk.bytes(b, len(data)-7)...); !bytes.Equal(got, data) {
+		t.Fatal("readv data mismatch")
+	}
+	tk.call(linux.NR_lseek, fd, 0, 0)
+	c := tk.alloc(uint64(len(data)))
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	n, errno = tk.call(linux.NR_read, fd, c, MAX_RW_COUNT)
+	runtime.ReadMemStats(&after)
+	if errno != 0 || n != uint64(len(data)) {
+		t.Fatalf("read = %d, errno = %d", n, errno)
+	} else if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 16<<20 {
+		t.Fatalf("read allocated %d bytes for a %d byte file", alloc, len(data))
+	}
+}
+
+func TestWritevChunked(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	data := make([]byte, 2*spliceChunk+5)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	buf := tk.alloc(uint64(len(data)))
+	tk.ctx.ToPointer(buf).MemWrite(data)
+	fd := uint64(tk.create("big", nil))
+	n, errno := tk.call(linux.NR_writev, fd, tk.iovecs(iovec{buf, 7}, iovec{buf + 7, size_t(len(data) - 7)}, iovec{0x1000, 4}), 3)
+	if errno != 0 || n != uint64(len(data)) {
+		t.Fatalf("writev = %d, errno = %d", n, errno)
+	} else if tk.readFile("big") != string(data) {
+		t.Fatal("writev data mismatch")
+	}
+	_, w := tk.pipeFlags(O_NONBLOCK)
+	n, errno = tk.call(linux.NR_writev, w, tk.iovecs(iovec{buf, spliceChunk}, iovec{buf, spliceChunk}), 2)
+	if errno != 0 || n != pipeDefSize {
+		t.Fatalf("writev into pipe = %d, errno = %d, want %d", n, errno, pipeDefSize)
+	}
+}
+
+func TestPreadPwrite(t *testing.T) {
+	for _, arch := range []emulator.Arch{emulator.ARCH_ARM, emulator.ARCH_ARM64, emulator.ARCH_X86, emulator.ARCH_X86_64} {
+		tk := newTestKernel(t, arch)
+		fd := uint64(tk.create("file", []byte("0123456789")))
+		pos := func(off uint64) []uint64 {
+			switch arch {
+			case emulator.ARCH_ARM:
+				return []uint64{0, off, 0}
+			case emulator.ARCH_X86:
+				return []uint64{off, 0}
+			}
+			return []uint64{off}
+		}
+		buf := tk.cstring("xy")
+		if n, errno := tk.call(linux.NR_pwrite64, append([]uint64{fd, buf, 2}, pos(4)...)...); errno != 0 || n != 2 {
+			t.Fatalf("%v: pwrite64 = %d, errno = %v", arch, n, errno)
+		}
+		out := tk.alloc(8)
+		if n, errno := tk.call(linux.NR_pread64, append([]uint64{fd, out, 8}, pos(3)...)...); errno != 0 || string(tk.bytes(out, int(n))) != "3xy6789" {
+			t.Fatalf("%v: pread64 = %q, errno = %v", arch, tk.bytes(out, int(n)), errno)
+		}
+		if r, _ := tk.call(linux.NR_lseek, fd, 0, 1); r != 10 {
+			t.Fatalf("%v: pread64/pwrite64 moved the file offset to %d", arch, r)
+		}
+		iov := tk.iovecs(iovec{out, 2}, iovec{out + 2, 2})
+		if n, errno := tk.call(linux.NR_preadv, fd, iov, 2, 6, 0); errno != 0 || string(tk.bytes(out, int(n))) != "6789" {
+			t.Fatalf("%v: preadv = %q, errno = %v", arch, tk.bytes(out, int(n)), errno)
+		}
+		if n, errno := tk.call(linux.NR_pwritev2, fd, iov, 2, ^uint64(0), ^uint64(0), 0); errno != 0 || n != 4 {
+			t.Fatalf("%v: pwritev2(-1) = %d, errno = %v", arch, n, errno)
+		}
+		if n, errno := tk.call(linux.NR_preadv2, fd, iov, 2, 0, 0, 0x100); errno != linux.EOPNOTSUPP {
+			t.Fatalf("%v: preadv2(bad flags) = %d, errno = %v", arch, n, errno)
+		}
+	}
+}
+
+func TestPreadAfterDup(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	fd := uint64(tk.create("file", []byte("0123456789")))
+	dup, errno := tk.call(linux.NR_dup, fd)
+	if errno != 0 {
+		t.Fatalf("dup errno = %v", errno)
+	}
+	out := tk.alloc(8)
+	for _, fd := range []uint64{fd, dup} {
+		if n, errno := tk.call(linux.NR_pread64, fd, out, 4, 2); errno != 0 || string(tk.bytes(out, int(n))) != "2345" {
+			t.Fatalf("pread64(%d) after dup = %q, errno = %v", fd, tk.bytes(out, int(n)), errno)
+		}
+	}
+	if n, errno := tk.call(linux.NR_pwrite64, dup, tk.cstring("ab"), 2, 8); errno != 0 || n != 2 {
+		t.Fatalf("pwrite64 after dup = %d, errno = %v", n, errno)
+	}
+	if _, errno := tk.call(linux.NR_ftruncate, fd, 9); errno != 0 {
+		t.Fatalf("ftruncate after dup errno = %v", errno)
+	}
+	if _, errno := tk.call(linux.NR_fsync, dup); errno != 0 {
+		t.Fatalf("fsync after dup errno = %v", errno)
+	}
+	other := uint64(tk.create("other", nil))
+	if n, errno := tk.call(linux.NR_copy_file_range, dup, tk.loff(6), other, tk.loff(0), 8, 0); errno != 0 || n != 3 {
+		t.Fatalf("copy_file_range after dup = %d, errno = %v", n, errno)
+	}
+	if s := tk.readFile("file") + tk.readFile("other"); s != "01234567a67a" {
+		t.Fatalf("contents = %q", s)
+	}
+}
+
+func TestPreadPipe(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	fds := tk.alloc(8)
+	if _, errno := tk.call(linux.NR_pipe2, fds, 0); errno != 0 {
+		t.Fatalf("pipe2 errno = %v", errno)
+	}
+	b := tk.bytes(fds, 8)
+	rfd, wfd := uint64(b[0]), uint64(b[4])
+	buf := tk.cstring("ping")
+	if _, errno := tk.call(linux.NR_pwrite64, wfd, buf, 4, 0); errno != linux.ESPIPE {
+		t.Fatalf("pwrite64(pipe) errno = %v, want ESPIPE", errno)
+	}
+	tk.call(linux.NR_write, wfd, buf, 4)
+	out := tk.alloc(64)
+	if n, errno := tk.call(linux.NR_read, rfd, out, 64); errno != 0 || string(tk.bytes(out, int(n))) != "ping" {
+		t.Fatalf("short read = %q, errno = %v", tk.bytes(out, int(n)), errno)
+	}
+}
+
+func TestPipe2Flags(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_X86_64)
+	fds := tk.alloc(8)
+	if _, errno := tk.call(linux.NR_pipe2, fds, O_CLOEXEC|O_NONBLOCK); errno != 0 {
+		t.Fatalf("pipe2 errno = %v", errno)
+	}
+	b := tk.bytes(fds, 8)
+	for fd, want := range map[uint64]uint64{uint64(b[0]): O_NONBLOCK, uint64(b[4]): O_WRONLY | O_NONBLOCK} {
+		if r, _ := tk.call(linux.NR_fcntl, fd, 1, 0); r != FD_CLOEXEC {
+			t.Fatalf("fd %d F_GETFD = %d, want FD_CLOEXEC", fd, r)
+		}
+		if r, _ := tk.call(linux.NR_fcntl, fd, 3, 0); r != want {
+			t.Fatalf("fd %d F_GETFL = %#x, want %#x", fd, r, want)
+		}
+	}
+	if _, errno := tk.call(linux.NR_pipe2, fds, O_TRUNC); errno != linux.EINVAL {
+		t.Fatalf("pipe2(O_TRUNC) errno = %v, want EINVAL", errno)
+	}
+}
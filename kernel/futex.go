@@ -78,7 +78,7 @@ func (f *futex) futex(ctx linux.Context, uaddr emuptr, op int32, val uint32, uti
 		var timeout <-chan time.Time
 		if utime != emunullptr {
 			var ts timespec
-			err = ctx.Debugger().MemExtract(utime, &ts)
+			err = memExtract(ctx, utime, &ts)
 			if err != nil {
 				ctx.SetErrno(linux.EFAULT)
 				return -1
@@ -104,8 +104,7 @@ func (f *futex) futex(ctx linux.Context, uaddr emuptr, op int32, val uint32, uti
 			}
 		}
 	case FUTEX_WAKE:
-		ch := f.getAwait(uaddr)
-		if ch == nil {
+		if f.getAwait(uaddr) == nil {
 			return 0
 		}
 		var value uint32
@@ -114,15 +113,7 @@ func (f *futex) futex(ctx linux.Context, uaddr emuptr, op int32, val uint32, uti
 			ctx.SetErrno(linux.EFAULT)
 			return -1
 		}
-		count := int32(val)
-		for i := int32(0); i != count; i++ {
-			select {
-			case ch <- value:
-			default:
-				return i
-			}
-		}
-		return count
+		return f.wake(uaddr, value, int32(val))
 	case FUTEX_CMP_REQUEUE:
 		panic(fmt.Sprint("futex: FUTEX_CMP_REQUEUE", uaddr, val, uaddr2, val3))
 	case FUTEX_WAIT_BITSET:
@@ -140,7 +131,7 @@ func (f *futex) futex(ctx linux.Context, uaddr emuptr, op int32, val uint32, uti
 		var timeout <-chan time.Time
 		if utime != emunullptr {
 			var ts timespec
-			err = ctx.Debugger().MemExtract(utime, &ts)
+			err = memExtract(ctx, utime, &ts)
 			if err != nil {
 				ctx.SetErrno(linux.EFAULT)
 				return -1
@@ -200,6 +191,21 @@ func (f *futex) futex(ctx linux.Context, uaddr emuptr, op int32, val uint32, uti
 	return -1
 }
 
+func (f *futex) wake(addr emuptr, value uint32, count int32) int32 {
+	ch := f.getAwait(addr)
+	if ch == nil {
+		return 0
+	}
+	for i := int32(0); i != count; i++ {
+		select {
+		case ch <- value:
+		default:
+			return i
+		}
+	}
+	return count
+}
+
 func (f *futex) getAwait(addr emuptr) chan<- uint32 {
 	f.rw.RLock()
 	defer f.rw.RUnlock()
@@ -0,0 +1,91 @@
+package kernel
+
+import (
+	"testing"
+	"time"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/emulator"
+)
+
+const (
+	testFUTEX_WAIT = 0
+	testFUTEX_WAKE = 1
+)
+
+func (tk *testKernel) task(tid int) *testKernel {
+	return &testKernel{Kernel: tk.Kernel, tb: tk.tb, dbg: tk.dbg, ctx: &fakeContext{dbg: tk.dbg, tid: tid}}
+}
+
+func (tk *testKernel) store32(addr emuptr, v uint32) {
+	tk.tb.Helper()
+	if err := tk.ctx.ToPointer(addr).MemWrite([]byte{byte(v), byte(v >> 8), byte(v >> 16), byte(v >> 24)}); err != nil {
+		tk.tb.Fatal(err)
+	}
+}
+
+func TestFutexWait(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	uaddr := tk.alloc(4)
+	tk.store32(uaddr, 1)
+	timeout := tk.encode(&timespec{tv_nsec: long_t(time.Millisecond)})
+	tests := []struct {
+		name  string
+		uaddr emuptr
+		val   uint64
+		utime emuptr
+		errno linux.Errno
+	}{
+		{"value mismatch", uaddr, 2, 0, linux.EAGAIN},
+		{"timeout", uaddr, 1, timeout, linux.ETIMEDOUT},
+		{"fault", 0x1000, 1, 0, linux.EFAULT},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, errno := tk.call(linux.NR_futex, tt.uaddr, testFUTEX_WAIT, tt.val, tt.utime)
+			if errno != tt.errno || int32(r) != -1 {
+				t.Fatalf("futex = %d, errno = %d, want errno = %d", int32(r), errno, tt.errno)
+			}
+		})
+	}
+}
+
+func TestFutexWake(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	uaddr := tk.alloc(4)
+	if r, errno := tk.call(linux.NR_futex, uaddr, testFUTEX_WAKE, 1); errno != 0 || r != 0 {
+		t.Fatalf("wake without waiters = %d, errno = %d", r, errno)
+	}
+	waiter := tk.task(2)
+	done := make(chan linux.Errno, 1)
+	go func() {
+		_, errno := waiter.call(linux.NR_futex, uaddr, testFUTEX_WAIT, 0)
+		done <- errno
+	}()
+	deadline := time.After(5 * time.Second)
+	for tk.sys.futex.getAwait(uaddr) == nil {
+		select {
+		case <-deadline:
+			t.Fatal("waiter never blocked")
+		case errno := <-done:
+			t.Fatalf("waiter returned early, errno = %d", errno)
+		case <-time.After(time.Millisecond):
+		}
+	}
+	tk.store32(uaddr, 1)
+	for {
+		if r, _ := tk.call(linux.NR_futex, uaddr, testFUTEX_WAKE, 1); r == 1 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("waiter was never woken")
+		case errno := <-done:
+			t.Fatalf("waiter returned early, errno = %d", errno)
+		case <-time.After(time.Millisecond):
+		}
+	}
+	if errno := <-done; errno != 0 {
+		t.Fatalf("waiter errno = %d", errno)
+	}
+}
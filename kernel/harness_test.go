@@ -0,0 +1,455 @@
+package kernel
+
+import (
+	"errors"
+	"io/fs"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"unsafe"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+	emu_arm "github.com/wnxd/microdbg/emulator/arm"
+	emu_arm64 "github.com/wnxd/microdbg/emulator/arm64"
+	emu_x86 "github.com/wnxd/microdbg/emulator/x86"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+const fakeMapBase = 0x10000000
+
+var errFakeUnmapped = errors.New("fake: memory unmapped")
+
+type fakeEmulator struct {
+	emulator.Emulator
+	arch  emulator.Arch
+	rw    sync.RWMutex
+	pages map[uint64][]byte
+	prots map[uint64]emulator.MemProt
+	stops atomic.Int32
+}
+
+type fakeDebugger struct {
+	debugger.Debugger
+	*Kernel
+	emu   *fakeEmulator
+	fs    filesystem.FS
+	root  string
+	next  uint64
+	rw    sync.RWMutex
+	fd    int
+	files map[int]filesystem.File
+}
+
+type fakeFileRef struct {
+	file  filesystem.File
+	count int64
+}
+
+type fakeContext struct {
+	debugger.Context
+	dbg  *fakeDebugger
+	tid  int
+	regs [emu_arm64.ARM64_REG_ENDING]uint64
+}
+
+type testKernel struct {
+	*Kernel
+	tb  testing.TB
+	dbg *fakeDebugger
+	ctx *fakeContext
+}
+
+func newTestKernel(tb testing.TB, arch emulator.Arch) *testKernel {
+	tb.Helper()
+	k, err := newKernel(arch)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	k.sys.ctor()
+	tb.Cleanup(func() { k.sys.Close() })
+	root := tb.TempDir()
+	dbg := &fakeDebugger{
+		Kernel: k,
+		emu:    &fakeEmulator{arch: arch, pages: make(map[uint64][]byte), prots: make(map[uint64]emulator.MemProt)},
+		fs:     newHostDirFS(root),
+		root:   root,
+		next:   fakeMapBase,
+		fd:     3,
+		files:  make(map[int]filesystem.File),
+	}
+	return &testKernel{Kernel: k, tb: tb, dbg: dbg, ctx: &fakeContext{dbg: dbg, tid: 1}}
+}
+
+func (tk *testKernel) call(nr linux.NR, args ...uint64) (uint64, linux.Errno) {
+	tk.tb.Helper()
+	call := tk.sys.Get(nr)
+	if call == nil {
+		tk.tb.Fatalf("%d: no handler", nr)
+	}
+	var sysArgs linux.SyscallArgs
+	copy(sysArgs[:], args)
+	ctx := &syscallContext{Context: tk.ctx, k: tk.Kernel}
+	tk.SetTaskErrno(tk.ctx.tid, 0)
+	r := call(ctx, &sysArgs)
+	return r, tk.TaskErrno(tk.ctx.tid)
+}
+
+func (tk *testKernel) alloc(size uint64) emuptr {
+	tk.tb.Helper()
+	region, err := tk.dbg.MapAlloc(size, emulator.MEM_PROT_READ|emulator.MEM_PROT_WRITE)
+	if err != nil {
+		tk.tb.Fatal(err)
+	}
+	return region.Addr
+}
+
+func (tk *testKernel) cstring(s string) emuptr {
+	tk.tb.Helper()
+	addr := tk.alloc(uint64(len(s) + 1))
+	tk.ctx.ToPointer(addr).MemWrite(append([]byte(s), 0))
+	return addr
+}
+
+func (tk *testKernel) bytes(addr emuptr, size int) []byte {
+	tk.tb.Helper()
+	b, err := tk.ctx.ToPointer(addr).MemRead(uint64(size))
+	if err != nil {
+		tk.tb.Fatal(err)
+	}
+	return b
+}
+
+func (tk *testKernel) encode(v ctype) emuptr {
+	tk.tb.Helper()
+	size, _ := sizeOf(modelOf(tk.dbg.Arch()), v)
+	addr := tk.alloc(uint64(size))
+	if err := memWrite(tk.ctx, addr, v); err != nil {
+		tk.tb.Fatal(err)
+	}
+	return addr
+}
+
+func (tk *testKernel) decode(addr emuptr, v ctype) {
+	tk.tb.Helper()
+	if err := memExtract(tk.ctx, addr, v); err != nil {
+		tk.tb.Fatal(err)
+	}
+}
+
+func (emu *fakeEmulator) Arch() emulator.Arch {
+	return emu.arch
+}
+
+func (emu *fakeEmulator) ByteOrder() emulator.ByteOrder {
+	return emulator.BO_LITTLE_ENDIAN
+}
+
+func (emu *fakeEmulator) PageSize() uint64 {
+	return PAGE_SIZE
+}
+
+func (emu *fakeEmulator) MemMap(addr, size uint64, prot emulator.MemProt) error {
+	emu.rw.Lock()
+	defer emu.rw.Unlock()
+	for page := addr &^ (PAGE_SIZE - 1); page < addr+size; page += PAGE_SIZE {
+		if _, ok := emu.pages[page]; !ok {
+			emu.pages[page] = make([]byte, PAGE_SIZE)
+		}
+		emu.prots[page] = prot
+	}
+	return nil
+}
+
+func (emu *fakeEmulator) MemUnmap(addr, size uint64) error {
+	emu.rw.Lock()
+	defer emu.rw.Unlock()
+	for page := addr &^ (PAGE_SIZE - 1); page < addr+size; page += PAGE_SIZE {
+		delete(emu.pages, page)
+		delete(emu.prots, page)
+	}
+	return nil
+}
+
+func (emu *fakeEmulator) MemProtect(addr, size uint64, prot emulator.MemProt) error {
+	emu.rw.Lock()
+	defer emu.rw.Unlock()
+	for page := addr &^ (PAGE_SIZE - 1); page < addr+size; page += PAGE_SIZE {
+		if _, ok := emu.pages[page]; !ok {
+			return errFakeUnmapped
+		}
+		emu.prots[page] = prot
+	}
+	return nil
+}
+
+func (emu *fakeEmulator) MemRead(addr, size uint64) ([]byte, error) {
+	data := make([]byte, size)
+	return data, emu.access(addr, data, false)
+}
+
+func (emu *fakeEmulator) MemWrite(addr uint64, data []byte) error {
+	return emu.access(addr, data, true)
+}
+
+func (emu *fakeEmulator) MemReadPtr(addr, size uint64, ptr unsafe.Pointer) error {
+	return emu.access(addr, unsafe.Slice((*byte)(ptr), size), false)
+}
+
+func (emu *fakeEmulator) MemWritePtr(addr, size uint64, ptr unsafe.Pointer) error {
+	return emu.access(addr, unsafe.Slice((*byte)(ptr), size), true)
+}
+
+func (emu *fakeEmulator) Stop() error {
+	emu.stops.Add(1)
+	return nil
+}
+
+func (emu *fakeEmulator) access(addr uint64, data []byte, write bool) error {
+	emu.rw.Lock()
+	defer emu.rw.Unlock()
+	for len(data) != 0 {
+		page, ok := emu.pages[addr&^(PAGE_SIZE-1)]
+		if !ok {
+			return errFakeUnmapped
+		}
+		off := addr & (PAGE_SIZE - 1)
+		var n int
+		if write {
+			n = copy(page[off:], data)
+		} else {
+			n = copy(data, page[off:])
+		}
+		data = data[n:]
+		addr += uint64(n)
+	}
+	return nil
+}
+
+func (dbg *fakeDebugger) Close() error {
+	return nil
+}
+
+func (dbg *fakeDebugger) Emulator() emulator.Emulator {
+	return dbg.emu
+}
+
+func (dbg *fakeDebugger) Arch() emulator.Arch {
+	return dbg.emu.arch
+}
+
+func (dbg *fakeDebugger) PointerSize() uint64 {
+	return uint64(modelOf(dbg.emu.arch).long)
+}
+
+func (dbg *fakeDebugger) MemMap(addr, size uint64, prot emulator.MemProt) (emulator.MemRegion, error) {
+	err := dbg.emu.MemMap(addr, size, prot)
+	return emulator.MemRegion{Addr: addr, Size: size, Prot: prot}, err
+}
+
+func (dbg *fakeDebugger) MemUnmap(addr, size uint64) error {
+	return dbg.emu.MemUnmap(addr, size)
+}
+
+func (dbg *fakeDebugger) MemProtect(addr, size uint64, prot emulator.MemProt) error {
+	return dbg.emu.MemProtect(addr, size, prot)
+}
+
+func (dbg *fakeDebugger) MapAlloc(size uint64, prot emulator.MemProt) (emulator.MemRegion, error) {
+	dbg.rw.Lock()
+	addr := dbg.next
+	dbg.next += uint64(alignUp(int(size), PAGE_SIZE)) + PAGE_SIZE
+	dbg.rw.Unlock()
+	return dbg.MemMap(addr, size, prot)
+}
+
+func (dbg *fakeDebugger) MapFree(addr, size uint64) error {
+	if addr < fakeMapBase || addr&(PAGE_SIZE-1) != 0 {
+		return errFakeUnmapped
+	}
+	return dbg.emu.MemUnmap(addr, size)
+}
+
+func (dbg *fakeDebugger) ToPointer(addr uint64) emulator.Pointer {
+	return emulator.ToPointer(dbg.emu, addr)
+}
+
+func (dbg *fakeDebugger) CreateFileDescriptor(file filesystem.File) int {
+	dbg.rw.Lock()
+	defer dbg.rw.Unlock()
+	dbg.fd++
+	dbg.files[dbg.fd] = file
+	return dbg.fd
+}
+
+func (dbg *fakeDebugger) CloseFileDescriptor(fd int) (filesystem.File, error) {
+	dbg.rw.Lock()
+	defer dbg.rw.Unlock()
+	if file, ok := dbg.files[fd]; ok {
+		delete(dbg.files, fd)
+		return file, nil
+	}
+	return nil, fs.ErrNotExist
+}
+
+func (dbg *fakeDebugger) GetFile(fd int) (filesystem.File, error) {
+	dbg.rw.RLock()
+	defer dbg.rw.RUnlock()
+	if file, ok := dbg.files[fd]; ok {
+		return file, nil
+	}
+	return nil, fs.ErrNotExist
+}
+
+func (dbg *fakeDebugger) DupFile(fd int) (int, error) {
+	dbg.rw.Lock()
+	defer dbg.rw.Unlock()
+	file, ok := dbg.files[fd]
+	if !ok {
+		return -1, fs.ErrNotExist
+	}
+	ref, ok := file.(*fakeFileRef)
+	if ok {
+		atomic.AddInt64(&ref.count, 1)
+	} else {
+		ref = &fakeFileRef{file: file, count: 2}
+		dbg.files[fd] = ref
+	}
+	dbg.fd++
+	dbg.files[dbg.fd] = ref
+	return dbg.fd, nil
+}
+
+func (dbg *fakeDebugger) Dup2File(oldfd, newfd int) error {
+	if oldfd == newfd || newfd < 3 {
+		return nil
+	}
+	dbg.rw.Lock()
+	defer dbg.rw.Unlock()
+	file, ok := dbg.files[oldfd]
+	if !ok {
+		return fs.ErrNotExist
+	}
+	if old, ok := dbg.files[newfd]; ok {
+		old.Close()
+	}
+	ref, ok := file.(*fakeFileRef)
+	if ok {
+		atomic.AddInt64(&ref.count, 1)
+	} else {
+		ref = &fakeFileRef{file: file, count: 2}
+		dbg.files[oldfd] = ref
+	}
+	dbg.files[newfd] = ref
+	return nil
+}
+
+func (dbg *fakeDebugger) GetFS() filesystem.FS {
+	return dbg.fs
+}
+
+func (dbg *fakeDebugger) OpenFile(name string, flag filesystem.FileFlag, perm fs.FileMode) (filesystem.File, error) {
+	return dbg.fs.OpenFile(name, flag, perm)
+}
+
+func (dbg *fakeDebugger) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(dbg.fs, name)
+}
+
+func (ctx *fakeContext) Debugger() debugger.Debugger {
+	return ctx.dbg
+}
+
+func (ctx *fakeContext) TaskID() int {
+	return ctx.tid
+}
+
+func (ctx *fakeContext) ToPointer(addr uint64) emulator.Pointer {
+	return ctx.dbg.ToPointer(addr)
+}
+
+func (ctx *fakeContext) PC() emulator.Reg {
+	switch ctx.dbg.Arch() {
+	case emulator.ARCH_ARM:
+		return emu_arm.ARM_REG_PC
+	case emulator.ARCH_X86:
+		return emu_x86.X86_REG_EIP
+	case emulator.ARCH_X86_64:
+		return emu_x86.X86_REG_RIP
+	}
+	return emu_arm64.ARM64_REG_PC
+}
+
+func (ctx *fakeContext) RegRead(reg emulator.Reg) (uint64, error) {
+	return ctx.regs[reg], nil
+}
+
+func (ctx *fakeContext) RegReadBatch(regs ...emulator.Reg) ([]uint64, error) {
+	vals := make([]uint64, len(regs))
+	for i, reg := range regs {
+		vals[i] = ctx.regs[reg]
+	}
+	return vals, nil
+}
+
+func (ctx *fakeContext) RegWrite(reg emulator.Reg, value uint64) error {
+	ctx.regs[reg] = value
+	return nil
+}
+
+func (f *fakeFileRef) Close() error {
+	i := atomic.AddInt64(&f.count, -1)
+	if i > 0 {
+		return nil
+	} else if i < 0 {
+		return fs.ErrClosed
+	}
+	return f.file.Close()
+}
+
+func (f *fakeFileRef) Stat() (fs.FileInfo, error) {
+	return f.file.Stat()
+}
+
+func (f *fakeFileRef) Read(b []byte) (int, error) {
+	if r, ok := f.file.(filesystem.ReadFile); ok {
+		return r.Read(b)
+	}
+	return 0, errors.ErrUnsupported
+}
+
+func (f *fakeFileRef) Write(b []byte) (int, error) {
+	if w, ok := f.file.(filesystem.WriteFile); ok {
+		return w.Write(b)
+	}
+	return 0, errors.ErrUnsupported
+}
+
+func (f *fakeFileRef) ReadDir(n int) ([]fs.DirEntry, error) {
+	if dir, ok := f.file.(filesystem.DirFile); ok {
+		return dir.ReadDir(n)
+	}
+	return nil, errors.ErrUnsupported
+}
+
+func (f *fakeFileRef) OpenFile(name string, flag filesystem.FileFlag, perm fs.FileMode) (filesystem.File, error) {
+	if dir, ok := f.file.(filesystem.Dir); ok {
+		return dir.OpenFile(name, flag, perm)
+	}
+	return nil, errors.ErrUnsupported
+}
+
+func (f *fakeFileRef) Mkdir(name string, perm fs.FileMode) error {
+	if dir, ok := f.file.(filesystem.Dir); ok {
+		return dir.Mkdir(name, perm)
+	}
+	return errors.ErrUnsupported
+}
+
+func (f *fakeFileRef) Control(op int, arg any) error {
+	if ctl, ok := f.file.(filesystem.ControlFile); ok {
+		return ctl.Control(op, arg)
+	}
+	return errors.ErrUnsupported
+}
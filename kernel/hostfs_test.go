@@ -0,0 +1,357 @@
+package kernel
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+type hostDirFS struct {
+	root string
+	dir  string
+}
+
+type hostDirFile struct {
+	filesystem.File
+	hostDirFS
+}
+
+func newHostDirFS(dir string) hostDirFS {
+	return hostDirFS{root: filepath.Clean(dir)}
+}
+
+func (d hostDirFS) Open(name string) (fs.File, error) {
+	p, err := d.resolve("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+func (d hostDirFS) Stat(name string) (fs.FileInfo, error) {
+	p, err := d.resolve("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(p)
+}
+
+func (d hostDirFS) Lstat(name string) (fs.FileInfo, error) {
+	p, err := d.resolve("lstat", name, false)
+	if err != nil {
+		return nil, err
+	}
+	return os.Lstat(p)
+}
+
+func (d hostDirFS) OpenFile(name string, flag filesystem.FileFlag, perm fs.FileMode) (filesystem.File, error) {
+	rel, err := d.walk("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(d.host(rel), int(flag), perm)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil || !info.IsDir() {
+		return file, nil
+	}
+	return &hostDirFile{file, hostDirFS{d.root, rel}}, nil
+}
+
+func (d hostDirFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	p, err := d.resolve("readdir", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadDir(p)
+}
+
+func (d hostDirFS) Mkdir(name string, perm fs.FileMode) (filesystem.DirFS, error) {
+	rel, err := d.walk("mkdir", name, false)
+	if err != nil {
+		return nil, err
+	}
+	err = os.Mkdir(d.host(rel), perm)
+	if err != nil {
+		return nil, err
+	}
+	return hostDirFS{d.root, rel}, nil
+}
+
+func (d hostDirFS) Readlink(name string) (string, error) {
+	p, err := d.resolve("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	return os.Readlink(p)
+}
+
+func (d hostDirFS) Remove(name string) error {
+	p, err := d.resolve("remove", name, false)
+	if err != nil {
+		return err
+	}
+	return os.Remove(p)
+}
+
+func (d hostDirFS) Symlink(oldname, newname string) error {
+	p, err := d.resolve("symlink", newname, false)
+	if err != nil {
+		return err
+	}
+	return os.Symlink(oldname, p)
+}
+
+func (d hostDirFS) Link(oldname string, newdir filesystem.FS, newname string) error {
+	oldpath, newpath, err := d.pair("link", oldname, newdir, newname)
+	if err != nil {
+		return err
+	}
+	return os.Link(oldpath, newpath)
+}
+
+func (d hostDirFS) Rename(oldname string, newdir filesystem.FS, newname string) error {
+	oldpath, newpath, err := d.pair("rename", oldname, newdir, newname)
+	if err != nil {
+		return err
+	}
+	return os.Rename(oldpath, newpath)
+}
+
+func (d hostDirFS) Exchange(oldname string, newdir filesystem.FS, newname string) error {
+	oldpath, newpath, err := d.pair("rename", oldname, newdir, newname)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(oldpath), ".exchange")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	moved := filepath.Join(tmp, "old")
+	if err := os.Rename(oldpath, moved); err != nil {
+		return err
+	} else if err := os.Rename(newpath, oldpath); err != nil {
+		os.Rename(moved, oldpath)
+		return err
+	}
+	return os.Rename(moved, newpath)
+}
+
+func (d hostDirFS) RenameNoReplace(oldname string, newdir filesystem.FS, newname string) error {
+	oldpath, newpath, err := d.pair("rename", oldname, newdir, newname)
+	if err != nil {
+		return err
+	}
+	info, err := os.Lstat(oldpath)
+	if err != nil {
+		return err
+	} else if !info.IsDir() {
+		if err := os.Link(oldpath, newpath); err != nil {
+			return err
+		}
+		return os.Remove(oldpath)
+	} else if err := os.Mkdir(newpath, 0); err != nil {
+		return err
+	}
+	return os.Rename(oldpath, newpath)
+}
+
+func (d hostDirFS) Chmod(name string, mode fs.FileMode) error {
+	p, err := d.resolve("chmod", name, true)
+	if err != nil {
+		return err
+	}
+	return os.Chmod(p, mode)
+}
+
+func (d hostDirFS) Lchown(name string, uid, gid int) error {
+	p, err := d.resolve("lchown", name, false)
+	if err != nil {
+		return err
+	}
+	return os.Lchown(p, uid, gid)
+}
+
+func (d hostDirFS) Chtimes(name string, atime, mtime time.Time) error {
+	p, err := d.resolve("chtimes", name, true)
+	if err != nil {
+		return err
+	}
+	return os.Chtimes(p, atime, mtime)
+}
+
+func (d hostDirFS) pair(op, oldname string, newdir filesystem.FS, newname string) (string, string, error) {
+	dir, ok := hostDirOf(newdir)
+	if !ok || dir.root != d.root {
+		return "", "", &os.LinkError{Op: op, Old: oldname, New: newname, Err: syscall.EXDEV}
+	}
+	oldpath, err := d.resolve(op, oldname, false)
+	if err != nil {
+		return "", "", err
+	}
+	newpath, err := dir.resolve(op, newname, false)
+	if err != nil {
+		return "", "", err
+	}
+	return oldpath, newpath, nil
+}
+
+func (d hostDirFS) resolve(op, name string, follow bool) (string, error) {
+	rel, err := d.walk(op, name, follow)
+	if err != nil {
+		return "", err
+	}
+	return d.host(rel), nil
+}
+
+func (d hostDirFS) walk(op, name string, follow bool) (string, error) {
+	var parts []string
+	if !path.IsAbs(name) {
+		parts = splitPath(d.dir)
+	}
+	pending := splitPath(name)
+	var links int
+	for len(pending) > 0 {
+		elem := pending[0]
+		pending = pending[1:]
+		if elem == "." {
+			continue
+		} else if elem == ".." {
+			parts = parts[:max(len(parts)-1, 0)]
+			continue
+		}
+		parts = append(parts, elem)
+		if len(pending) == 0 && !follow {
+			break
+		}
+		p := d.host(strings.Join(parts, "/"))
+		if info, err := os.Lstat(p); err != nil || info.Mode()&fs.ModeSymlink == 0 {
+			continue
+		} else if links++; links > MAXSYMLINKS {
+			return "", &fs.PathError{Op: op, Path: name, Err: syscall.ELOOP}
+		}
+		target, err := os.Readlink(p)
+		if err != nil {
+			return "", err
+		}
+		parts = parts[:len(parts)-1]
+		if path.IsAbs(target) {
+			parts = nil
+		}
+		pending = append(splitPath(target), pending...)
+	}
+	return strings.Join(parts, "/"), nil
+}
+
+func (d hostDirFS) host(rel string) string {
+	return filepath.Join(d.root, filepath.FromSlash(rel))
+}
+
+func (f *hostDirFile) Stat() (fs.FileInfo, error) {
+	return f.File.Stat()
+}
+
+func (f *hostDirFile) Sync() error {
+	return f.File.(*os.File).Sync()
+}
+
+func hostDirOf(fsys filesystem.FS) (hostDirFS, bool) {
+	switch dir := fsys.(type) {
+	case hostDirFS:
+		return dir, true
+	case *hostDirFile:
+		return dir.hostDirFS, true
+	}
+	return hostDirFS{}, false
+}
+
+func splitPath(name string) []string {
+	return strings.FieldsFunc(name, func(r rune) bool { return r == '/' })
+}
+
+func TestHostDirFSContainment(t *testing.T) {
+	outside := t.TempDir()
+	secret := filepath.Join(outside, "secret")
+	os.WriteFile(secret, []byte("secret"), 0644)
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "data"), []byte("data"), 0644)
+	os.Mkdir(filepath.Join(root, "dir"), 0755)
+	fsys := newHostDirFS(root)
+	rel, _ := filepath.Rel(filepath.Join(root, "dir"), secret)
+	rel = filepath.ToSlash(rel)
+
+	for _, name := range []string{"dir/" + rel, "../../../../../../" + filepath.ToSlash(secret), filepath.ToSlash(secret)} {
+		if _, err := fsys.Open(name); !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("Open(%q) err = %v, want ErrNotExist", name, err)
+		}
+	}
+	fsys.Symlink(secret, "abs")
+	fsys.Symlink(rel, "dir/rel")
+	fsys.Symlink("/data", "dir/inroot")
+	fsys.Symlink("loop", "loop")
+	for _, name := range []string{"abs", "dir/rel"} {
+		if _, err := fsys.Open(name); !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("Open(%q) through an escaping symlink err = %v", name, err)
+		}
+		if err := fsys.Chmod(name, 0777); err == nil {
+			t.Fatalf("Chmod(%q) followed an escaping symlink", name)
+		}
+	}
+	if info, err := os.Stat(secret); err != nil || info.Mode().Perm() != 0644 {
+		t.Fatalf("host file outside the root changed: %v, %v", info, err)
+	}
+	if info, err := fsys.Lstat("abs"); err != nil || info.Mode()&fs.ModeSymlink == 0 {
+		t.Fatalf("Lstat(abs) = %v, %v", info, err)
+	}
+	if b, err := fs.ReadFile(fsys, "dir/inroot"); err != nil || string(b) != "data" {
+		t.Fatalf("ReadFile(dir/inroot) = %q, %v", b, err)
+	}
+	if _, err := fsys.Open("loop"); !errors.Is(err, syscall.ELOOP) {
+		t.Fatalf("Open(loop) err = %v, want ELOOP", err)
+	}
+
+	dir, err := fsys.OpenFile("dir", filesystem.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := dir.(filesystem.FS)
+	if _, err := sub.OpenFile("../"+rel, filesystem.O_RDONLY, 0); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("sub.OpenFile(escape) err = %v, want ErrNotExist", err)
+	}
+	if f, err := sub.OpenFile("../data", filesystem.O_RDONLY, 0); err != nil {
+		t.Fatalf("sub.OpenFile(../data) err = %v", err)
+	} else {
+		f.Close()
+	}
+	dir.Close()
+}
+
+func TestHostDirFSExchange(t *testing.T) {
+	root := t.TempDir()
+	for name, data := range map[string]string{"a": "A", "b": "B", "b.exchange~": "keep"} {
+		os.WriteFile(filepath.Join(root, name), []byte(data), 0644)
+	}
+	fsys := newHostDirFS(root)
+	if err := fsys.Exchange("a", fsys, "b"); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"a": "B", "b": "A", "b.exchange~": "keep"} {
+		if b, _ := os.ReadFile(filepath.Join(root, name)); string(b) != want {
+			t.Fatalf("%s = %q, want %q", name, b, want)
+		}
+	}
+	if err := fsys.Exchange("a", newHostDirFS(t.TempDir()), "b"); !errors.Is(err, syscall.EXDEV) {
+		t.Fatalf("Exchange across roots err = %v, want EXDEV", err)
+	}
+}
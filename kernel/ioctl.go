@@ -7,15 +7,18 @@ import (
 
 func (sys *Syscall) ioctl(ctx linux.Context, fd, cmd uint32, arg emuptr) int32 {
 	dbg := ctx.Debugger()
-	file, err := dbg.GetFile(int(fd))
+	file, err := sys.getFile(dbg, int(fd))
 	if err != nil {
 		ctx.SetErrno(linux.EBADF)
 		return -1
 	}
+	if p := sys.fcntl.pipeOf(int(fd)); p != nil {
+		return sys.fcntl.pipeIoctl(ctx, p, cmd, arg)
+	}
 	if ctl, ok := file.(filesystem.ControlFile); ok {
 		err := ctl.Control(int(cmd), arg)
 		if err != nil {
-			ctx.SetErrno(linux.EINVAL)
+			ctx.SetErrno(linux.ToErrno(err))
 			return -1
 		}
 		return 0
@@ -2,47 +2,150 @@ package kernel
 
 import (
 	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	linux "github.com/wnxd/microdbg-linux"
 	"github.com/wnxd/microdbg/debugger"
 	"github.com/wnxd/microdbg/emulator"
 	emu_arm "github.com/wnxd/microdbg/emulator/arm"
 	emu_arm64 "github.com/wnxd/microdbg/emulator/arm64"
+	emu_x86 "github.com/wnxd/microdbg/emulator/x86"
+)
+
+const (
+	X86_INTR_SYSCALL = 0x80
+	X86_INSN_SYSCALL = "\x0f\x05"
+	X86_INSN_INT80   = "\xcd\x80"
 )
 
 type Kernel struct {
+	nr       *nrTable
+	abi      *syscallABI
 	sys      Syscall
-	err      linux.Errno
-	intrHook debugger.HookHandler
+	errno    sync.Map
+	ctxPool  sync.Pool
+	tracer   atomic.Pointer[Tracer]
+	trapHook debugger.HookHandler
+	scanHook debugger.HookHandler
+}
+
+type trapSites struct {
+	rw     sync.RWMutex
+	blocks map[uint64]uint64
+	sites  map[uint64]struct{}
+}
+
+type syscallABI struct {
+	intno uint64
+	insn  string
+	nr    emulator.Reg
+	args  [len(linux.SyscallArgs{})]emulator.Reg
+	ret   emulator.Reg
+}
+
+type syscallContext struct {
+	debugger.Context
+	k    *Kernel
+	args linux.SyscallArgs
+}
+
+var syscallABIs = map[emulator.Arch]*syscallABI{
+	emulator.ARCH_ARM: {
+		intno: emu_arm.ARM_INTR_EXCP_SWI,
+		nr:    emu_arm.ARM_REG_R7,
+		args:  [...]emulator.Reg{emu_arm.ARM_REG_R0, emu_arm.ARM_REG_R1, emu_arm.ARM_REG_R2, emu_arm.ARM_REG_R3, emu_arm.ARM_REG_R4, emu_arm.ARM_REG_R5},
+		ret:   emu_arm.ARM_REG_R0,
+	},
+	emulator.ARCH_ARM64: {
+		intno: emu_arm.ARM_INTR_EXCP_SWI,
+		nr:    emu_arm64.ARM64_REG_X8,
+		args:  [...]emulator.Reg{emu_arm64.ARM64_REG_X0, emu_arm64.ARM64_REG_X1, emu_arm64.ARM64_REG_X2, emu_arm64.ARM64_REG_X3, emu_arm64.ARM64_REG_X4, emu_arm64.ARM64_REG_X5},
+		ret:   emu_arm64.ARM64_REG_X0,
+	},
+	emulator.ARCH_X86: {
+		intno: X86_INTR_SYSCALL,
+		nr:    emu_x86.X86_REG_EAX,
+		args:  [...]emulator.Reg{emu_x86.X86_REG_EBX, emu_x86.X86_REG_ECX, emu_x86.X86_REG_EDX, emu_x86.X86_REG_ESI, emu_x86.X86_REG_EDI, emu_x86.X86_REG_EBP},
+		ret:   emu_x86.X86_REG_EAX,
+	},
+	emulator.ARCH_X86_64: {
+		intno: X86_INTR_SYSCALL,
+		insn:  X86_INSN_SYSCALL,
+		nr:    emu_x86.X86_REG_RAX,
+		args:  [...]emulator.Reg{emu_x86.X86_REG_RDI, emu_x86.X86_REG_RSI, emu_x86.X86_REG_RDX, emu_x86.X86_REG_R10, emu_x86.X86_REG_R8, emu_x86.X86_REG_R9},
+		ret:   emu_x86.X86_REG_RAX,
+	},
 }
 
 func NewKernel(dbg debugger.Debugger) (*Kernel, error) {
-	k := new(Kernel)
-	var handleIntr debugger.InterruptCallback
-	switch dbg.Arch() {
-	case emulator.ARCH_ARM:
-		handleIntr = k.armIntr
-	case emulator.ARCH_ARM64:
-		handleIntr = k.arm64Intr
-	default:
-		return nil, errors.ErrUnsupported
+	k, err := newKernel(dbg.Arch())
+	if err != nil {
+		return nil, err
 	}
-	hook, err := dbg.AddHook(emulator.HOOK_TYPE_INTR, handleIntr, nil, 1, 0)
+	hook, err := dbg.AddHook(emulator.HOOK_TYPE_INTR, k.handleIntr, nil, 1, 0)
 	if err != nil {
 		return nil, err
 	}
+	if k.abi.insn != "" {
+		scan, err := dbg.AddHook(emulator.HOOK_TYPE_BLOCK, k.handleBlock, nil, 1, 0)
+		if err != nil {
+			hook.Close()
+			return nil, err
+		}
+		k.scanHook = scan
+	}
 	k.sys.ctor()
-	k.intrHook = hook
+	k.trapHook = hook
+	return k, nil
+}
+
+func newKernel(arch emulator.Arch) (*Kernel, error) {
+	abi, ok := syscallABIs[arch]
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	k := new(Kernel)
+	k.nr = nrTables[arch]
+	k.abi = abi
+	k.ctxPool.New = func() any {
+		return &syscallContext{k: k}
+	}
 	return k, nil
 }
 
 func (k *Kernel) Close() error {
-	k.intrHook.Close()
+	k.trapHook.Close()
+	if k.scanHook != nil {
+		k.scanHook.Close()
+	}
 	return k.sys.Close()
 }
 
+func (k *Kernel) SetTracer(t *Tracer) {
+	k.tracer.Store(t)
+}
+
+func (k *Kernel) SetLockTimeout(d time.Duration) {
+	k.sys.fcntl.locks.timeout.Store(int64(d))
+}
+
+func (k *Kernel) SetCred(uid, gid, euid, egid uint32) {
+	k.sys.fcntl.setCred(cred{uid: uid_t(uid), euid: uid_t(euid), gid: gid_t(gid), egid: gid_t(egid)})
+}
+
 func (k *Kernel) NR(no uint64) linux.NR {
-	return linux.NR(no)
+	return k.nr.NR(no)
+}
+
+func (k *Kernel) No(nr linux.NR) (uint64, bool) {
+	return k.nr.No(nr)
+}
+
+func (k *Kernel) ExitStatus() *linux.ExitStatus {
+	return k.sys.sched.status.Load()
 }
 
 func (k *Kernel) Syscall() linux.Syscall {
@@ -50,55 +153,189 @@ func (k *Kernel) Syscall() linux.Syscall {
 }
 
 func (k *Kernel) Errno() linux.Errno {
-	return k.err
+	return k.TaskErrno(os.Getpid())
 }
 
 func (k *Kernel) SetErrno(err linux.Errno) {
-	k.err = err
+	k.SetTaskErrno(os.Getpid(), err)
 }
 
-func (k *Kernel) armIntr(ctx debugger.Context, intno uint64, data any) debugger.HookResult {
-	if intno != emu_arm.ARM_INTR_EXCP_SWI {
-		return debugger.HookResult_Next
+func (k *Kernel) TaskErrno(tid int) linux.Errno {
+	if err, ok := k.errno.Load(tid); ok {
+		return err.(linux.Errno)
 	}
-	nr, err := ctx.RegRead(emu_arm.ARM_REG_R7)
-	if err != nil {
-		return debugger.HookResult_Next
+	return 0
+}
+
+func (k *Kernel) SetTaskErrno(tid int, err linux.Errno) {
+	if err == 0 {
+		k.errno.Delete(tid)
+	} else {
+		k.errno.Store(tid, err)
 	}
-	args, err := ctx.RegReadBatch(emu_arm.ARM_REG_R0, emu_arm.ARM_REG_R1, emu_arm.ARM_REG_R2, emu_arm.ARM_REG_R3, emu_arm.ARM_REG_R4, emu_arm.ARM_REG_R5)
-	if err != nil {
+}
+
+func (k *Kernel) handleIntr(ctx debugger.Context, intno uint64, data any) debugger.HookResult {
+	if intno != k.abi.intno {
 		return debugger.HookResult_Next
-	}
-	dbg := ctx.Debugger().(linux.Debugger)
-	call := dbg.Syscall().Get(dbg.NR(nr))
-	if call == nil {
+	} else if k.abi.insn != "" && !k.isSite(ctx) {
 		return debugger.HookResult_Next
 	}
-	dbg.SetErrno(0)
-	r := call(linux.NewContext(ctx, dbg), args...)
-	ctx.RegWrite(emu_arm.ARM_REG_R0, r)
-	return debugger.HookResult_Done
+	return k.dispatch(ctx)
 }
 
-func (k *Kernel) arm64Intr(ctx debugger.Context, intno uint64, data any) debugger.HookResult {
-	if intno != emu_arm.ARM_INTR_EXCP_SWI {
-		return debugger.HookResult_Next
+// handleBlock rewrites the syscall instruction that ends a translation block
+// into int 0x80, so the call is delivered through handleIntr like every other
+// trap instead of running on the emulator thread. Each block is decoded once,
+// and only a syscall that starts on an instruction boundary is patched before
+// the block is restarted to pick up the trap.
+func (k *Kernel) handleBlock(ctx debugger.Context, addr, size uint64, data any) {
+	traps := &k.sys.traps
+	n := uint64(len(X86_INSN_SYSCALL))
+	if !traps.scan(addr, size) || size < n {
+		return
+	}
+	b, err := ctx.ToPointer(addr).MemRead(size)
+	if err != nil || string(b[size-n:]) != X86_INSN_SYSCALL {
+		return
+	}
+	var off uint64
+	for off < size-n {
+		l := x86InsnLen(b[off:])
+		if l == 0 {
+			return
+		}
+		off += uint64(l)
 	}
-	nr, err := ctx.RegRead(emu_arm64.ARM64_REG_X8)
+	if off != size-n {
+		return
+	} else if err = ctx.ToPointer(addr + off).MemWrite([]byte(X86_INSN_INT80)); err != nil {
+		return
+	}
+	traps.add(addr + off)
+	ctx.RegWrite(ctx.PC(), addr)
+}
+
+func (k *Kernel) isSite(ctx debugger.Context) bool {
+	pc, err := ctx.RegRead(ctx.PC())
 	if err != nil {
-		return debugger.HookResult_Next
+		return false
+	}
+	return k.sys.traps.has(pc - uint64(len(X86_INSN_INT80)))
+}
+
+func (t *trapSites) scan(addr, size uint64) bool {
+	t.rw.RLock()
+	_, ok := t.blocks[addr]
+	t.rw.RUnlock()
+	if ok {
+		return false
 	}
-	args, err := ctx.RegReadBatch(emu_arm64.ARM64_REG_X0, emu_arm64.ARM64_REG_X1, emu_arm64.ARM64_REG_X2, emu_arm64.ARM64_REG_X3, emu_arm64.ARM64_REG_X4, emu_arm64.ARM64_REG_X5)
+	t.rw.Lock()
+	defer t.rw.Unlock()
+	if _, ok = t.blocks[addr]; ok {
+		return false
+	} else if t.blocks == nil {
+		t.blocks = make(map[uint64]uint64)
+	}
+	t.blocks[addr] = addr + size
+	return true
+}
+
+func (t *trapSites) add(site uint64) {
+	t.rw.Lock()
+	defer t.rw.Unlock()
+	if t.sites == nil {
+		t.sites = make(map[uint64]struct{})
+	}
+	t.sites[site] = struct{}{}
+}
+
+func (t *trapSites) has(site uint64) bool {
+	t.rw.RLock()
+	defer t.rw.RUnlock()
+	_, ok := t.sites[site]
+	return ok
+}
+
+// forget drops the blocks and sites in [addr, addr+size) so they are decoded
+// again once new code runs there, returning the sites it removed.
+func (t *trapSites) forget(addr, size uint64) []uint64 {
+	end := addr + size
+	t.rw.Lock()
+	defer t.rw.Unlock()
+	for start, stop := range t.blocks {
+		if start < end && stop > addr {
+			delete(t.blocks, start)
+		}
+	}
+	var sites []uint64
+	for site := range t.sites {
+		if site >= addr && site < end {
+			delete(t.sites, site)
+			sites = append(sites, site)
+		}
+	}
+	return sites
+}
+
+func (k *Kernel) dispatch(ctx debugger.Context) debugger.HookResult {
+	abi := k.abi
+	no, err := ctx.RegRead(abi.nr)
 	if err != nil {
 		return debugger.HookResult_Next
 	}
-	dbg := ctx.Debugger().(linux.Debugger)
-	call := dbg.Syscall().Get(dbg.NR(nr))
+	nr := k.nr.NR(no)
+	call := k.sys.dispatch(nr)
+	tracer := k.tracer.Load()
 	if call == nil {
+		if tracer != nil {
+			tracer.unhandled(ctx, nr, no, abi)
+		}
 		return debugger.HookResult_Next
 	}
-	dbg.SetErrno(0)
-	r := call(linux.NewContext(ctx, dbg), args...)
-	ctx.RegWrite(emu_arm64.ARM64_REG_X0, r)
+	sysCtx := k.ctxPool.Get().(*syscallContext)
+	sysCtx.Context = ctx
+	for i, reg := range abi.args {
+		sysCtx.args[i], err = ctx.RegRead(reg)
+		if err != nil {
+			k.putContext(sysCtx)
+			return debugger.HookResult_Next
+		}
+	}
+	var ev *traceEvent
+	if tracer != nil {
+		ev = tracer.enter(ctx, nr, no, &sysCtx.args)
+	}
+	tid := ctx.TaskID()
+	k.SetTaskErrno(tid, 0)
+	r := call(sysCtx, &sysCtx.args)
+	errno := k.TaskErrno(tid)
+	if errno != 0 {
+		r = uint64(-errno)
+	}
+	if ev != nil {
+		tracer.exit(ctx, ev, &sysCtx.args, r, errno)
+	}
+	k.putContext(sysCtx)
+	ctx.RegWrite(abi.ret, r)
 	return debugger.HookResult_Done
 }
+
+func (k *Kernel) putContext(ctx *syscallContext) {
+	ctx.Context = nil
+	ctx.args = linux.SyscallArgs{}
+	k.ctxPool.Put(ctx)
+}
+
+func (ctx *syscallContext) Unwrap() debugger.Context {
+	return ctx.Context
+}
+
+func (ctx *syscallContext) Errno() linux.Errno {
+	return ctx.k.TaskErrno(ctx.TaskID())
+}
+
+func (ctx *syscallContext) SetErrno(err linux.Errno) {
+	ctx.k.SetTaskErrno(ctx.TaskID(), err)
+}
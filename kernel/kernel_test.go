@@ -0,0 +1,313 @@
+package kernel
+
+import (
+	"testing"
+	"time"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+	emu_arm "github.com/wnxd/microdbg/emulator/arm"
+	emu_arm64 "github.com/wnxd/microdbg/emulator/arm64"
+	emu_x86 "github.com/wnxd/microdbg/emulator/x86"
+)
+
+func BenchmarkHandleIntr(b *testing.B) {
+	tk := newTestKernel(b, emulator.ARCH_ARM64)
+	ctx := tk.ctx
+	gettid, _ := tk.No(linux.NR_gettid)
+	b.ReportAllocs()
+	for range b.N {
+		ctx.regs[emu_arm64.ARM64_REG_X8] = gettid
+		tk.handleIntr(ctx, emu_arm.ARM_INTR_EXCP_SWI, nil)
+	}
+	if ctx.regs[emu_arm64.ARM64_REG_X0] != 1 {
+		b.Fatalf("gettid = %d, want 1", ctx.regs[emu_arm64.ARM64_REG_X0])
+	}
+}
+
+// BenchmarkHandleIntrLegacy runs the same workload as BenchmarkHandleIntr
+// through the dispatch path handleIntr replaced: a batched register read,
+// the old switch lookup and a freshly allocated context per call.
+func BenchmarkHandleIntrLegacy(b *testing.B) {
+	tk := newTestKernel(b, emulator.ARCH_ARM64)
+	ctx := tk.ctx
+	gettid, _ := tk.No(linux.NR_gettid)
+	b.ReportAllocs()
+	for range b.N {
+		ctx.regs[emu_arm64.ARM64_REG_X8] = gettid
+		tk.legacyIntr(ctx, emu_arm.ARM_INTR_EXCP_SWI)
+	}
+	if ctx.regs[emu_arm64.ARM64_REG_X0] != 1 {
+		b.Fatalf("gettid = %d, want 1", ctx.regs[emu_arm64.ARM64_REG_X0])
+	}
+}
+
+func (tk *testKernel) legacyIntr(ctx debugger.Context, intno uint64) debugger.HookResult {
+	if intno != emu_arm.ARM_INTR_EXCP_SWI {
+		return debugger.HookResult_Next
+	}
+	nr, err := ctx.RegRead(emu_arm64.ARM64_REG_X8)
+	if err != nil {
+		return debugger.HookResult_Next
+	}
+	regs, err := ctx.RegReadBatch(emu_arm64.ARM64_REG_X0, emu_arm64.ARM64_REG_X1, emu_arm64.ARM64_REG_X2, emu_arm64.ARM64_REG_X3, emu_arm64.ARM64_REG_X4, emu_arm64.ARM64_REG_X5)
+	if err != nil {
+		return debugger.HookResult_Next
+	}
+	call := legacyGet(&tk.sys, tk.nr.NR(nr))
+	if call == nil {
+		return debugger.HookResult_Next
+	}
+	sysCtx := &syscallContext{Context: ctx, k: tk.Kernel}
+	sysCtx.SetErrno(0)
+	args := new(linux.SyscallArgs)
+	copy(args[:], regs)
+	r := call(sysCtx, args)
+	if errno := sysCtx.Errno(); errno != 0 {
+		r = uint64(-errno)
+	}
+	ctx.RegWrite(emu_arm64.ARM64_REG_X0, r)
+	return debugger.HookResult_Done
+}
+
+func legacyGet(sys *Syscall, nr linux.NR) linux.SyscallHandler {
+	switch nr {
+	case linux.NR_reject:
+		return sys.Reject
+	case linux.NR_ignore:
+		return sys.Ignore
+	case linux.NR_dup3:
+		return sys.Emulate_dup3
+	case linux.NR_fcntl:
+		return sys.Emulate_fcntl
+	case linux.NR_ioctl:
+		return sys.Emulate_ioctl
+	case linux.NR_faccessat:
+		return sys.Emulate_faccessat
+	case linux.NR_open:
+		return sys.Emulate_open
+	case linux.NR_openat:
+		return sys.Emulate_openat
+	case linux.NR_close:
+		return sys.Emulate_close
+	case linux.NR_pipe2:
+		return sys.Emulate_pipe2
+	case linux.NR_lseek:
+		return sys.Emulate_lseek
+	case linux.NR_read:
+		return sys.Emulate_read
+	case linux.NR_write:
+		return sys.Emulate_write
+	case linux.NR_writev:
+		return sys.Emulate_writev
+	case linux.NR_readlinkat:
+		return sys.Emulate_readlinkat
+	case linux.NR_fstatat64:
+		return sys.Emulate_fstatat64
+	case linux.NR_fstat64:
+		return sys.Emulate_fstat64
+	case linux.NR_exit, linux.NR_exit_group:
+		return sys.Emulate_exit
+	case linux.NR_futex:
+		return sys.Emulate_futex
+	case linux.NR_clock_gettime:
+		return sys.Emulate_clock_gettime
+	case linux.NR_sigaltstack:
+		return sys.Ignore
+	case linux.NR_rt_sigaction:
+		return sys.Emulate_rt_sigaction
+	case linux.NR_rt_sigprocmask:
+		return sys.Emulate_rt_sigprocmask
+	case linux.NR_getrlimit:
+		return sys.Emulate_getrlimit
+	case linux.NR_setrlimit:
+		return sys.Emulate_setrlimit
+	case linux.NR_prctl:
+		return sys.Emulate_prctl
+	case linux.NR_gettimeofday:
+		return sys.Emulate_gettimeofday
+	case linux.NR_getpid:
+		return sys.Emulate_getpid
+	case linux.NR_getuid, linux.NR_geteuid:
+		return sys.Ignore
+	case linux.NR_gettid:
+		return sys.Emulate_gettid
+	case linux.NR_sysinfo:
+		return sys.Emulate_sysinfo
+	case linux.NR_socket:
+		return sys.Emulate_socket
+	case linux.NR_munmap:
+		return sys.Emulate_munmap
+	case linux.NR_clone:
+		return sys.Emulate_clone
+	case linux.NR_execve:
+		return sys.Emulate_execve
+	case linux.NR_mmap:
+		return sys.Emulate_mmap
+	case linux.NR_mmap2:
+		return sys.Emulate_mmap2
+	case linux.NR_mprotect:
+		return sys.Emulate_mprotect
+	case linux.NR_madvise:
+		return sys.Reject
+	case linux.NR_rt_tgsigqueueinfo:
+		return sys.Emulate_rt_tgsigqueueinfo
+	case linux.NR_getrandom:
+		return sys.Emulate_getrandom
+	}
+	return nil
+}
+
+func (tk *testKernel) syscall64(site emuptr, nr linux.NR, args ...uint64) (uint64, debugger.HookResult) {
+	tk.tb.Helper()
+	no, ok := tk.No(nr)
+	if !ok {
+		tk.tb.Fatalf("%v: no syscall number", nr)
+	}
+	ctx := tk.ctx
+	ctx.regs[emu_x86.X86_REG_RAX] = no
+	for i, reg := range tk.abi.args {
+		ctx.regs[reg] = 0
+		if i < len(args) {
+			ctx.regs[reg] = args[i]
+		}
+	}
+	ctx.regs[emu_x86.X86_REG_RIP] = site + uint64(len(X86_INSN_INT80))
+	result := tk.handleIntr(ctx, X86_INTR_SYSCALL, nil)
+	return ctx.regs[emu_x86.X86_REG_RAX], result
+}
+
+func TestHandleBlockPatchesSyscall(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_X86_64)
+	ctx := tk.ctx
+	code := tk.alloc(8)
+	ctx.ToPointer(code).MemWrite([]byte{0x90, 0x0f, 0x05, 0x90, 0x0f, 0x05})
+	if _, result := tk.syscall64(code+1, linux.NR_gettid); result != debugger.HookResult_Next {
+		t.Fatal("int 0x80 outside a patched site was handled")
+	}
+	tk.handleBlock(ctx, code, 3, nil)
+	tk.handleBlock(ctx, code+3, 2, nil)
+	if got := tk.bytes(code, 6); string(got) != "\x90"+X86_INSN_INT80+"\x90\x0f\x05" {
+		t.Fatalf("code = % x", got)
+	} else if ctx.regs[emu_x86.X86_REG_RIP] != code {
+		t.Fatalf("rip = %#x, want block restart at %#x", ctx.regs[emu_x86.X86_REG_RIP], code)
+	}
+	if r, result := tk.syscall64(code+1, linux.NR_gettid); result != debugger.HookResult_Done || r != 1 {
+		t.Fatalf("gettid = %d, result = %d", r, result)
+	}
+}
+
+func TestHandleBlockDecodesBoundary(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_X86_64)
+	code := tk.alloc(PAGE_SIZE)
+	jmp := "\xe9\x11\x22" + X86_INSN_SYSCALL
+	tk.ctx.ToPointer(code).MemWrite([]byte(jmp))
+	tk.handleBlock(tk.ctx, code, uint64(len(jmp)), nil)
+	if got := tk.bytes(code, len(jmp)); string(got) != jmp {
+		t.Fatalf("operand bytes patched: % x", got)
+	}
+	site := code + 0x100
+	tk.ctx.ToPointer(site).MemWrite([]byte(X86_INSN_SYSCALL))
+	tk.handleBlock(tk.ctx, site, 2, nil)
+	if _, errno := tk.call(linux.NR_mprotect, code, PAGE_SIZE, uint64(emulator.MEM_PROT_READ|emulator.MEM_PROT_WRITE)); errno != 0 {
+		t.Fatalf("mprotect errno = %v", errno)
+	}
+	if got := tk.bytes(site, 2); string(got) != X86_INSN_SYSCALL {
+		t.Fatalf("writable code = % x, want the original syscall", got)
+	} else if _, result := tk.syscall64(site, linux.NR_gettid); result != debugger.HookResult_Next {
+		t.Fatal("site survived mprotect(PROT_WRITE)")
+	}
+	tk.handleBlock(tk.ctx, site, 2, nil)
+	if _, errno := tk.call(linux.NR_munmap, code, PAGE_SIZE); errno != 0 {
+		t.Fatalf("munmap errno = %v", errno)
+	} else if _, result := tk.syscall64(site, linux.NR_gettid); result != debugger.HookResult_Next {
+		t.Fatal("site survived munmap")
+	}
+}
+
+func BenchmarkHandleBlock(b *testing.B) {
+	tk := newTestKernel(b, emulator.ARCH_X86_64)
+	code := tk.alloc(16)
+	tk.ctx.ToPointer(code).MemWrite([]byte("\x90\x90\x90\x90"))
+	b.ReportAllocs()
+	for range b.N {
+		tk.handleBlock(tk.ctx, code, 4, nil)
+	}
+}
+
+func TestSyscall64FutexWake(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_X86_64)
+	code := tk.alloc(2)
+	tk.ctx.ToPointer(code).MemWrite([]byte(X86_INSN_SYSCALL))
+	tk.handleBlock(tk.ctx, code, 2, nil)
+	uaddr := tk.alloc(4)
+	waiter := tk.task(2)
+	done := make(chan uint64, 1)
+	go func() {
+		r, _ := waiter.syscall64(code, linux.NR_futex, uaddr, testFUTEX_WAIT, 0)
+		done <- r
+	}()
+	deadline := time.After(5 * time.Second)
+	for tk.sys.futex.getAwait(uaddr) == nil {
+		select {
+		case <-deadline:
+			t.Fatal("waiter never blocked")
+		case r := <-done:
+			t.Fatalf("waiter returned early: %d", int64(r))
+		case <-time.After(time.Millisecond):
+		}
+	}
+	tk.store32(uaddr, 1)
+	for {
+		if r, _ := tk.syscall64(code, linux.NR_futex, uaddr, testFUTEX_WAKE, 1); r == 1 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("waiter was never woken")
+		case r := <-done:
+			t.Fatalf("waiter returned early: %d", int64(r))
+		case <-time.After(time.Millisecond):
+		}
+	}
+	if r := <-done; r != 0 {
+		t.Fatalf("waiter = %d", int64(r))
+	}
+}
+
+func TestExit(t *testing.T) {
+	for _, nr := range []linux.NR{linux.NR_exit, linux.NR_exit_group} {
+		tk := newTestKernel(t, emulator.ARCH_ARM64)
+		if r, errno := tk.call(nr, 0x103); errno != 0 || r != 0 {
+			t.Fatalf("%v = %d, errno = %v", nr, r, errno)
+		}
+		status := tk.ExitStatus()
+		if status == nil || status.Code != 3 || status.TaskID != 1 {
+			t.Fatalf("%v status = %+v, want code 3", nr, status)
+		} else if status.Group != (nr == linux.NR_exit_group) {
+			t.Fatalf("%v status.Group = %v", nr, status.Group)
+		} else if tk.dbg.emu.stops.Load() != 1 {
+			t.Fatalf("%v stopped the emulator %d times, want 1", nr, tk.dbg.emu.stops.Load())
+		}
+	}
+}
+
+func TestSchedLeave(t *testing.T) {
+	for _, order := range [][]int32{{1, 2, 3}, {2, 3, 1}, {2, 1, 3}, {4, 2, 3, 1}} {
+		var s sched
+		s.leader = 1
+		s.tasks.Store(int32(2), nil)
+		s.tasks.Store(int32(3), nil)
+		last := len(order) - 1
+		for i, pid := range order {
+			if got := s.leave(pid); got != (i == last) {
+				t.Fatalf("order %v: leave(%d) = %v", order, pid, got)
+			}
+		}
+	}
+	var s sched
+	if !s.leave(1) {
+		t.Fatal("leave before any clone did not report the last task")
+	}
+}
@@ -0,0 +1,232 @@
+package kernel
+
+import (
+	"encoding/binary"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+)
+
+type dataModel struct {
+	arch       emulator.Arch
+	order      binary.ByteOrder
+	long       int
+	int64Align int
+}
+
+var (
+	armModel    = &dataModel{arch: emulator.ARCH_ARM, order: binary.LittleEndian, long: 4, int64Align: 8}
+	arm64Model  = &dataModel{arch: emulator.ARCH_ARM64, order: binary.LittleEndian, long: 8, int64Align: 8}
+	x86Model    = &dataModel{arch: emulator.ARCH_X86, order: binary.LittleEndian, long: 4, int64Align: 4}
+	x86_64Model = &dataModel{arch: emulator.ARCH_X86_64, order: binary.LittleEndian, long: 8, int64Align: 8}
+)
+
+type ctype interface {
+	ctype(c *ccodec)
+}
+
+type ccodec struct {
+	model  *dataModel
+	buf    []byte
+	off    int
+	align  int
+	decode bool
+}
+
+func modelOf(arch emulator.Arch) *dataModel {
+	switch arch {
+	case emulator.ARCH_ARM:
+		return armModel
+	case emulator.ARCH_X86:
+		return x86Model
+	case emulator.ARCH_X86_64:
+		return x86_64Model
+	}
+	return arm64Model
+}
+
+func sizeOf(model *dataModel, v ctype) (size, align int) {
+	c := ccodec{model: model, align: 1}
+	v.ctype(&c)
+	return alignUp(c.off, c.align), c.align
+}
+
+func encodeC(model *dataModel, v ctype) []byte {
+	size, _ := sizeOf(model, v)
+	c := ccodec{model: model, buf: make([]byte, size), align: 1}
+	v.ctype(&c)
+	return c.buf
+}
+
+func decodeC(model *dataModel, b []byte, v ctype) {
+	c := ccodec{model: model, buf: b, align: 1, decode: true}
+	v.ctype(&c)
+}
+
+func memWrite(ctx debugger.Context, addr emuptr, v ctype) error {
+	return ctx.ToPointer(addr).MemWrite(encodeC(modelOf(ctx.Debugger().Arch()), v))
+}
+
+func memExtract(ctx debugger.Context, addr emuptr, v ctype) error {
+	model := modelOf(ctx.Debugger().Arch())
+	size, _ := sizeOf(model, v)
+	b, err := ctx.ToPointer(addr).MemRead(uint64(size))
+	if err != nil {
+		return err
+	}
+	decodeC(model, b, v)
+	return nil
+}
+
+func memExtractArray[T any, PT interface {
+	*T
+	ctype
+}](ctx debugger.Context, addr emuptr, arr []T) error {
+	if len(arr) == 0 {
+		return nil
+	}
+	model := modelOf(ctx.Debugger().Arch())
+	size, _ := sizeOf(model, PT(&arr[0]))
+	b, err := ctx.ToPointer(addr).MemRead(uint64(size * len(arr)))
+	if err != nil {
+		return err
+	}
+	for i := range arr {
+		decodeC(model, b[i*size:(i+1)*size], PT(&arr[i]))
+	}
+	return nil
+}
+
+func (c *ccodec) field(size, align int) []byte {
+	c.off = alignUp(c.off, align)
+	c.align = max(c.align, align)
+	off := c.off
+	c.off += size
+	if c.buf == nil {
+		return nil
+	}
+	return c.buf[off:c.off]
+}
+
+func (c *ccodec) pad(size int) {
+	c.field(size, 1)
+}
+
+func (c *ccodec) bytes(p []byte) {
+	b := c.field(len(p), 1)
+	if b == nil {
+		return
+	} else if c.decode {
+		copy(p, b)
+	} else {
+		copy(b, p)
+	}
+}
+
+func (c *ccodec) word(size int, p *uint64) {
+	align := size
+	if size == 8 {
+		align = c.model.int64Align
+	}
+	b := c.field(size, align)
+	if b == nil {
+		return
+	}
+	order := c.model.order
+	if c.decode {
+		switch size {
+		case 2:
+			*p = uint64(order.Uint16(b))
+		case 4:
+			*p = uint64(order.Uint32(b))
+		case 8:
+			*p = order.Uint64(b)
+		}
+		return
+	}
+	switch size {
+	case 2:
+		order.PutUint16(b, uint16(*p))
+	case 4:
+		order.PutUint32(b, uint32(*p))
+	case 8:
+		order.PutUint64(b, *p)
+	}
+}
+
+func (c *ccodec) signed(size int, p *int64) {
+	v := uint64(*p)
+	c.word(size, &v)
+	if c.decode {
+		switch size {
+		case 2:
+			*p = int64(int16(v))
+		case 4:
+			*p = int64(int32(v))
+		default:
+			*p = int64(v)
+		}
+	}
+}
+
+func (c *ccodec) nested(v ctype) {
+	size, align := sizeOf(c.model, v)
+	b := c.field(size, align)
+	if b == nil {
+		return
+	}
+	sub := ccodec{model: c.model, buf: b, align: 1, decode: c.decode}
+	v.ctype(&sub)
+}
+
+func cLong[T ~int](c *ccodec, p *T) {
+	v := int64(*p)
+	c.signed(c.model.long, &v)
+	*p = T(v)
+}
+
+func cULong[T ~uint | ~uint64](c *ccodec, p *T) {
+	v := uint64(*p)
+	c.word(c.model.long, &v)
+	*p = T(v)
+}
+
+func cInt16[T ~int16](c *ccodec, p *T) {
+	v := int64(*p)
+	c.signed(2, &v)
+	*p = T(v)
+}
+
+func cUint16[T ~uint16](c *ccodec, p *T) {
+	v := uint64(*p)
+	c.word(2, &v)
+	*p = T(v)
+}
+
+func cInt32[T ~int32](c *ccodec, p *T) {
+	v := int64(*p)
+	c.signed(4, &v)
+	*p = T(v)
+}
+
+func cUint32[T ~uint32](c *ccodec, p *T) {
+	v := uint64(*p)
+	c.word(4, &v)
+	*p = T(v)
+}
+
+func cInt64[T ~int64](c *ccodec, p *T) {
+	v := int64(*p)
+	c.signed(8, &v)
+	*p = T(v)
+}
+
+func cUint64[T ~uint64](c *ccodec, p *T) {
+	v := uint64(*p)
+	c.word(8, &v)
+	*p = T(v)
+}
+
+func alignUp(n, align int) int {
+	return (n + align - 1) &^ (align - 1)
+}
@@ -0,0 +1,521 @@
+package kernel
+
+import (
+	"io"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+const (
+	F_RDLCK = 0
+	F_WRLCK = 1
+	F_UNLCK = 2
+
+	F_GETLK      = 5
+	F_SETLK      = 6
+	F_SETLKW     = 7
+	F_GETLK64    = 12
+	F_SETLK64    = 13
+	F_SETLKW64   = 14
+	F_OFD_GETLK  = 36
+	F_OFD_SETLK  = 37
+	F_OFD_SETLKW = 38
+
+	LOCK_SH = 1
+	LOCK_EX = 2
+	LOCK_NB = 4
+	LOCK_UN = 8
+)
+
+type flock struct {
+	l_type   int16
+	l_whence int16
+	l_start  off_t
+	l_len    off_t
+	l_pid    pid_t
+}
+
+type flock64 struct {
+	l_type   int16
+	l_whence int16
+	l_start  loff_t
+	l_len    loff_t
+	l_pid    pid_t
+}
+
+type fileDesc struct {
+	refs int
+	file filesystem.File
+	name string
+}
+
+type lockOwner struct {
+	pid  int32
+	desc *fileDesc
+}
+
+type fileLock struct {
+	owner      lockOwner
+	typ        int16
+	start, end int64
+	flock      bool
+}
+
+type lockNode struct {
+	locks []*fileLock
+	wake  chan struct{}
+}
+
+type lockManager struct {
+	mu      sync.Mutex
+	nodes   map[inodeKey]*lockNode
+	waiting map[lockOwner]lockOwner
+	timeout atomic.Int64
+}
+
+func (fl *flock) ctype(c *ccodec) {
+	cInt16(c, &fl.l_type)
+	cInt16(c, &fl.l_whence)
+	cLong(c, &fl.l_start)
+	cLong(c, &fl.l_len)
+	cInt32(c, &fl.l_pid)
+}
+
+func (fl *flock64) ctype(c *ccodec) {
+	cInt16(c, &fl.l_type)
+	cInt16(c, &fl.l_whence)
+	cInt64(c, &fl.l_start)
+	cInt64(c, &fl.l_len)
+	cInt32(c, &fl.l_pid)
+}
+
+func (f *fcntl) flock(ctx linux.Context, fd, cmd uint32) int32 {
+	file, err := f.getFile(ctx.Debugger(), int(fd))
+	if err != nil {
+		ctx.SetErrno(linux.EBADF)
+		return -1
+	}
+	f.rw.RLock()
+	status := f.flags[int(fd)]
+	f.rw.RUnlock()
+	if status&O_PATH != 0 {
+		ctx.SetErrno(linux.EBADF)
+		return -1
+	}
+	var typ int16
+	switch cmd &^ LOCK_NB {
+	case LOCK_SH:
+		typ = F_RDLCK
+	case LOCK_EX:
+		typ = F_WRLCK
+	case LOCK_UN:
+		typ = F_UNLCK
+	default:
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	key, errno := f.lockKey(int(fd), file)
+	if errno == 0 {
+		f.rw.Lock()
+		owner := lockOwner{desc: f.desc(int(fd))}
+		f.rw.Unlock()
+		errno = f.locks.set(taskDone(ctx), key, &fileLock{owner: owner, typ: typ, end: math.MaxInt64, flock: true}, cmd&LOCK_NB == 0)
+	}
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	return 0
+}
+
+func (f *fcntl) fcntlLock(ctx linux.Context, fd int, file filesystem.File, cmd uint32, arg emuptr, wide bool) int32 {
+	f.rw.RLock()
+	status, known := f.flags[fd]
+	f.rw.RUnlock()
+	if status&O_PATH != 0 {
+		ctx.SetErrno(linux.EBADF)
+		return -1
+	}
+	var fl flock64
+	if wide {
+		if memExtract(ctx, arg, &fl) != nil {
+			ctx.SetErrno(linux.EFAULT)
+			return -1
+		}
+	} else {
+		var narrow flock
+		if memExtract(ctx, arg, &narrow) != nil {
+			ctx.SetErrno(linux.EFAULT)
+			return -1
+		}
+		fl = flock64{narrow.l_type, narrow.l_whence, loff_t(narrow.l_start), loff_t(narrow.l_len), narrow.l_pid}
+	}
+	ofd := cmd == F_OFD_GETLK || cmd == F_OFD_SETLK || cmd == F_OFD_SETLKW
+	if ofd && fl.l_pid != 0 {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	l, errno := lockRange(file, &fl)
+	var key inodeKey
+	if errno == 0 {
+		key, errno = f.lockKey(fd, file)
+	}
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	f.rw.Lock()
+	if ofd {
+		l.owner = lockOwner{desc: f.desc(fd)}
+	} else {
+		l.owner = lockOwner{pid: f.sched.tgid(ctx.TaskID())}
+	}
+	f.rw.Unlock()
+	switch cmd {
+	case F_GETLK, F_GETLK64, F_OFD_GETLK:
+		if l.typ == F_UNLCK {
+			ctx.SetErrno(linux.EINVAL)
+			return -1
+		}
+		if blocker := f.locks.test(key, l); blocker == nil {
+			fl.l_type = F_UNLCK
+		} else {
+			fl = flock64{l_type: blocker.typ, l_start: loff_t(blocker.start), l_pid: -1}
+			if blocker.end != math.MaxInt64 {
+				fl.l_len = loff_t(blocker.end - blocker.start + 1)
+			}
+			if blocker.owner.desc == nil {
+				fl.l_pid = pid_t(blocker.owner.pid)
+			}
+		}
+		var err error
+		if wide {
+			err = memWrite(ctx, arg, &fl)
+		} else if modelOf(ctx.Debugger().Arch()).long == 4 && (fl.l_start > math.MaxInt32 || fl.l_len > math.MaxInt32) {
+			ctx.SetErrno(linux.EOVERFLOW)
+			return -1
+		} else {
+			err = memWrite(ctx, arg, &flock{fl.l_type, fl.l_whence, off_t(fl.l_start), off_t(fl.l_len), fl.l_pid})
+		}
+		if err != nil {
+			ctx.SetErrno(linux.EFAULT)
+			return -1
+		}
+		return 0
+	}
+	if known && (l.typ == F_RDLCK && status&O_ACCMODE == O_WRONLY || l.typ == F_WRLCK && status&O_ACCMODE == O_RDONLY) {
+		ctx.SetErrno(linux.EBADF)
+		return -1
+	}
+	wait := cmd == F_SETLKW || cmd == F_SETLKW64 || cmd == F_OFD_SETLKW
+	if errno := f.locks.set(taskDone(ctx), key, l, wait); errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	return 0
+}
+
+func (f *fcntl) desc(fd int) *fileDesc {
+	d, ok := f.descs[fd]
+	if !ok {
+		d = &fileDesc{refs: 1}
+		f.descs[fd] = d
+	}
+	return d
+}
+
+func (f *fcntl) dropDesc(fd int) {
+	d, ok := f.descs[fd]
+	if !ok {
+		return
+	}
+	delete(f.descs, fd)
+	if d.refs--; d.refs == 0 {
+		f.locks.release(func(l *fileLock) bool { return l.owner.desc == d })
+		f.epollRelease(d)
+	}
+}
+
+func (f *fcntl) releasePosix(ctx linux.Context, fd int, file filesystem.File) {
+	if !f.locks.active() {
+		return
+	}
+	key, errno := f.lockKey(fd, file)
+	if errno != 0 {
+		return
+	}
+	owner := lockOwner{pid: f.sched.tgid(ctx.TaskID())}
+	f.locks.releaseAt(key, func(l *fileLock) bool { return l.owner == owner })
+}
+
+func lockRange(file filesystem.File, fl *flock64) (*fileLock, linux.Errno) {
+	if fl.l_type != F_RDLCK && fl.l_type != F_WRLCK && fl.l_type != F_UNLCK {
+		return nil, linux.EINVAL
+	}
+	var base int64
+	switch fl.l_whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		seeker, ok := file.(io.Seeker)
+		if !ok {
+			return nil, linux.ESPIPE
+		}
+		off, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, linux.ToErrno(err)
+		}
+		base = off
+	case io.SeekEnd:
+		info, err := file.Stat()
+		if err != nil {
+			return nil, linux.ToErrno(err)
+		}
+		base = info.Size()
+	default:
+		return nil, linux.EINVAL
+	}
+	start, length := int64(fl.l_start), int64(fl.l_len)
+	if start > math.MaxInt64-base {
+		return nil, linux.EOVERFLOW
+	}
+	start += base
+	l := &fileLock{typ: fl.l_type, start: start, end: math.MaxInt64}
+	switch {
+	case length > 0:
+		if length-1 > math.MaxInt64-start {
+			return nil, linux.EOVERFLOW
+		}
+		l.end = start + length - 1
+	case length < 0:
+		l.start, l.end = start+length, start-1
+	}
+	if l.start < 0 {
+		return nil, linux.EINVAL
+	}
+	return l, 0
+}
+
+func (f *fcntl) lockKey(fd int, file filesystem.File) (inodeKey, linux.Errno) {
+	info, err := file.Stat()
+	if err != nil {
+		return inodeKey{}, linux.ToErrno(err)
+	}
+	st := newKstat(info, f.inoName(fd))
+	return inodeKey{st.dev, st.ino}, 0
+}
+
+func taskDone(ctx linux.Context) <-chan struct{} {
+	if task, ok := taskOf(ctx); ok {
+		return task.Done()
+	}
+	return nil
+}
+
+func (m *lockManager) ctor() {
+	m.nodes = make(map[inodeKey]*lockNode)
+	m.waiting = make(map[lockOwner]lockOwner)
+}
+
+func (m *lockManager) dtor() {
+	m.mu.Lock()
+	for _, node := range m.nodes {
+		close(node.wake)
+	}
+	m.nodes = nil
+	m.waiting = nil
+	m.mu.Unlock()
+}
+
+func (m *lockManager) active() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.nodes) != 0
+}
+
+func (m *lockManager) test(key inodeKey, l *fileLock) *fileLock {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if node, ok := m.nodes[key]; ok {
+		if blocker := node.conflict(l); blocker != nil {
+			c := *blocker
+			return &c
+		}
+	}
+	return nil
+}
+
+func (m *lockManager) set(done <-chan struct{}, key inodeKey, l *fileLock, wait bool) linux.Errno {
+	var timeout <-chan time.Time
+	if d := m.timeout.Load(); wait && d > 0 {
+		timer := time.NewTimer(time.Duration(d))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for {
+		if m.nodes == nil {
+			return linux.EINTR
+		}
+		node := m.node(key)
+		if l.flock && node.drop(func(o *fileLock) bool { return o.flock && o.owner == l.owner }) {
+			m.changed(key, node)
+			node = m.node(key)
+		}
+		if l.typ == F_UNLCK {
+			node.remove(l.owner, l.flock, l.start, l.end)
+			m.changed(key, node)
+			return 0
+		}
+		blocker := node.conflict(l)
+		if blocker == nil {
+			node.insert(l)
+			m.changed(key, node)
+			return 0
+		} else if !wait {
+			return linux.EAGAIN
+		}
+		posix := !l.flock && l.owner.desc == nil
+		if posix {
+			if m.deadlock(l.owner, blocker.owner) {
+				return linux.EDEADLK
+			}
+			m.waiting[l.owner] = blocker.owner
+		}
+		wake := node.wake
+		m.mu.Unlock()
+		var interrupted bool
+		select {
+		case <-wake:
+		case <-done:
+			interrupted = true
+		case <-timeout:
+			interrupted = true
+		}
+		m.mu.Lock()
+		if posix && m.waiting != nil {
+			delete(m.waiting, l.owner)
+		}
+		if interrupted {
+			return linux.EINTR
+		}
+	}
+}
+
+func (m *lockManager) deadlock(owner, blocker lockOwner) bool {
+	for range len(m.waiting) + 1 {
+		if blocker == owner {
+			return true
+		}
+		next, ok := m.waiting[blocker]
+		if !ok {
+			return false
+		}
+		blocker = next
+	}
+	return false
+}
+
+func (m *lockManager) release(match func(*fileLock) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, node := range m.nodes {
+		if node.drop(match) {
+			m.changed(key, node)
+		}
+	}
+}
+
+func (m *lockManager) releaseAt(key inodeKey, match func(*fileLock) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if node, ok := m.nodes[key]; ok && node.drop(match) {
+		m.changed(key, node)
+	}
+}
+
+func (m *lockManager) node(key inodeKey) *lockNode {
+	node, ok := m.nodes[key]
+	if !ok {
+		node = &lockNode{wake: make(chan struct{})}
+		m.nodes[key] = node
+	}
+	return node
+}
+
+func (m *lockManager) changed(key inodeKey, node *lockNode) {
+	close(node.wake)
+	node.wake = make(chan struct{})
+	if len(node.locks) == 0 {
+		delete(m.nodes, key)
+	}
+}
+
+func (n *lockNode) conflict(l *fileLock) *fileLock {
+	for _, o := range n.locks {
+		if o.flock != l.flock || o.owner == l.owner || o.end < l.start || o.start > l.end {
+			continue
+		} else if o.typ == F_WRLCK || l.typ == F_WRLCK {
+			return o
+		}
+	}
+	return nil
+}
+
+func (n *lockNode) insert(l *fileLock) {
+	if !l.flock {
+		n.remove(l.owner, false, l.start, l.end)
+		n.drop(func(o *fileLock) bool {
+			if o.owner != l.owner || o.flock || o.typ != l.typ {
+				return false
+			} else if o.end != math.MaxInt64 && o.end+1 == l.start {
+				l.start = o.start
+				return true
+			} else if l.end != math.MaxInt64 && l.end+1 == o.start {
+				l.end = o.end
+				return true
+			}
+			return false
+		})
+	}
+	n.locks = append(n.locks, l)
+}
+
+func (n *lockNode) remove(owner lockOwner, flock bool, start, end int64) {
+	locks := make([]*fileLock, 0, len(n.locks)+1)
+	for _, o := range n.locks {
+		if o.owner != owner || o.flock != flock || o.end < start || o.start > end {
+			locks = append(locks, o)
+			continue
+		}
+		if o.start < start {
+			left := *o
+			left.end = start - 1
+			locks = append(locks, &left)
+		}
+		if o.end > end {
+			right := *o
+			right.start = end + 1
+			locks = append(locks, &right)
+		}
+	}
+	n.locks = locks
+}
+
+func (n *lockNode) drop(match func(*fileLock) bool) bool {
+	locks := n.locks[:0]
+	for _, o := range n.locks {
+		if !match(o) {
+			locks = append(locks, o)
+		}
+	}
+	dropped := len(locks) != len(n.locks)
+	clear(n.locks[len(locks):])
+	n.locks = locks
+	return dropped
+}
@@ -0,0 +1,316 @@
+package kernel
+
+import (
+	"testing"
+	"time"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/emulator"
+)
+
+func (tk *testKernel) setlk(fd, cmd uint64, typ int16, start, length int64) linux.Errno {
+	tk.tb.Helper()
+	_, errno := tk.call(linux.NR_fcntl, fd, cmd, tk.encode(&flock64{l_type: typ, l_start: loff_t(start), l_len: loff_t(length)}))
+	return errno
+}
+
+func (tk *testKernel) getlk(fd, cmd uint64, typ int16, start, length int64) flock64 {
+	tk.tb.Helper()
+	arg := tk.encode(&flock64{l_type: typ, l_start: loff_t(start), l_len: loff_t(length)})
+	if _, errno := tk.call(linux.NR_fcntl, fd, cmd, arg); errno != 0 {
+		tk.tb.Fatalf("getlk errno = %v", errno)
+	}
+	var fl flock64
+	tk.decode(arg, &fl)
+	return fl
+}
+
+func (tk *testKernel) open(name string, flags uint64) uint64 {
+	tk.tb.Helper()
+	fd, errno := tk.call(linux.NR_openat, uint64(testAT_FDCWD), tk.cstring(name), flags, 0)
+	if errno != 0 {
+		tk.tb.Fatalf("openat(%q) errno = %v", name, errno)
+	}
+	return fd
+}
+
+func TestPosixLock(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_X86_64)
+	fd := uint64(tk.create("file", []byte("0123456789")))
+	other := tk.task(2)
+	ofd := other.open("file", O_RDWR)
+	if errno := tk.setlk(fd, F_SETLK, F_WRLCK, 0, 100); errno != 0 {
+		t.Fatalf("F_SETLK errno = %v", errno)
+	}
+	if errno := tk.setlk(fd, F_SETLK, F_UNLCK, 10, 10); errno != 0 {
+		t.Fatalf("F_SETLK unlock errno = %v", errno)
+	}
+	if fl := tk.getlk(fd, F_GETLK, F_WRLCK, 0, 0); fl.l_type != F_UNLCK {
+		t.Fatalf("F_GETLK on own lock = %+v, want F_UNLCK", fl)
+	}
+	tests := []struct {
+		start, length int64
+		want          flock64
+	}{
+		{5, 1, flock64{l_type: F_WRLCK, l_start: 0, l_len: 10, l_pid: 1}},
+		{15, 1, flock64{l_type: F_UNLCK, l_start: 15, l_len: 1}},
+		{50, 1, flock64{l_type: F_WRLCK, l_start: 20, l_len: 80, l_pid: 1}},
+		{100, 0, flock64{l_type: F_UNLCK, l_start: 100}},
+	}
+	for _, tt := range tests {
+		if fl := other.getlk(ofd, F_GETLK, F_RDLCK, tt.start, tt.length); fl != tt.want {
+			t.Fatalf("F_GETLK(%d, %d) = %+v, want %+v", tt.start, tt.length, fl, tt.want)
+		}
+	}
+	if errno := other.setlk(ofd, F_SETLK, F_RDLCK, 5, 1); errno != linux.EAGAIN {
+		t.Fatalf("conflicting F_SETLK errno = %v, want EAGAIN", errno)
+	}
+	if errno := other.setlk(ofd, F_SETLK, F_RDLCK, 10, 10); errno != 0 {
+		t.Fatalf("F_SETLK in the hole errno = %v", errno)
+	}
+	if errno := tk.setlk(fd, F_SETLK, F_RDLCK, 0, 100); errno != 0 {
+		t.Fatalf("F_SETLK downgrade errno = %v", errno)
+	}
+	if errno := other.setlk(ofd, F_SETLK, F_RDLCK, 0, 0); errno != 0 {
+		t.Fatalf("shared F_SETLK errno = %v", errno)
+	}
+	if fl := tk.getlk(fd, F_GETLK, F_WRLCK, 0, 0); fl.l_type != F_RDLCK || fl.l_pid != 2 {
+		t.Fatalf("F_GETLK = %+v, want a read lock held by 2", fl)
+	}
+	if _, errno := other.call(linux.NR_close, ofd); errno != 0 {
+		t.Fatalf("close errno = %v", errno)
+	}
+	if fl := tk.getlk(fd, F_GETLK, F_WRLCK, 0, 0); fl.l_type != F_UNLCK {
+		t.Fatalf("F_GETLK after close = %+v, want F_UNLCK", fl)
+	}
+	ofd = other.open("file", O_RDWR)
+
+	rdonly := tk.open("file", O_RDONLY)
+	for _, tt := range []struct {
+		name  string
+		fd    uint64
+		cmd   uint64
+		fl    flock64
+		errno linux.Errno
+	}{
+		{"write lock on read-only fd", rdonly, F_SETLK, flock64{l_type: F_WRLCK}, linux.EBADF},
+		{"bad type", fd, F_SETLK, flock64{l_type: 3}, linux.EINVAL},
+		{"bad whence", fd, F_SETLK, flock64{l_type: F_RDLCK, l_whence: 3}, linux.EINVAL},
+		{"negative start", fd, F_SETLK, flock64{l_type: F_RDLCK, l_start: 5, l_len: -6}, linux.EINVAL},
+		{"getlk unlock", fd, F_GETLK, flock64{l_type: F_UNLCK}, linux.EINVAL},
+		{"ofd pid", fd, F_OFD_SETLK, flock64{l_type: F_RDLCK, l_pid: 1}, linux.EINVAL},
+		{"bad fd", 1000, F_SETLK, flock64{l_type: F_RDLCK}, linux.EBADF},
+	} {
+		if _, errno := tk.call(linux.NR_fcntl, tt.fd, tt.cmd, tk.encode(&tt.fl)); errno != tt.errno {
+			t.Fatalf("%s: errno = %v, want %v", tt.name, errno, tt.errno)
+		}
+	}
+	if errno := tk.setlk(fd, F_SETLK, F_WRLCK, -4, 0); errno != linux.EINVAL {
+		t.Fatalf("F_SETLK(-4) errno = %v, want EINVAL", errno)
+	}
+	tk.call(linux.NR_lseek, fd, 0, 2)
+	arg := tk.encode(&flock64{l_type: F_WRLCK, l_whence: 1, l_start: -2, l_len: 2})
+	if _, errno := tk.call(linux.NR_fcntl, fd, F_SETLK, arg); errno != 0 {
+		t.Fatalf("F_SETLK(SEEK_CUR) errno = %v", errno)
+	}
+	if fl := other.getlk(ofd, F_GETLK, F_RDLCK, 0, 0); fl.l_start != 8 || fl.l_len != 2 {
+		t.Fatalf("F_GETLK = %+v, want [8, 10)", fl)
+	}
+}
+
+func TestOFDLock(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	fd1 := uint64(tk.create("file", nil))
+	fd2 := tk.open("file", O_RDWR)
+	if errno := tk.setlk(fd1, F_OFD_SETLK, F_WRLCK, 0, 0); errno != 0 {
+		t.Fatalf("F_OFD_SETLK errno = %v", errno)
+	}
+	if errno := tk.setlk(fd2, F_OFD_SETLK, F_RDLCK, 100, 1); errno != linux.EAGAIN {
+		t.Fatalf("F_OFD_SETLK on second description errno = %v, want EAGAIN", errno)
+	}
+	if errno := tk.setlk(fd2, F_SETLK, F_RDLCK, 100, 1); errno != linux.EAGAIN {
+		t.Fatalf("F_SETLK against OFD lock errno = %v, want EAGAIN", errno)
+	}
+	if fl := tk.getlk(fd2, F_OFD_GETLK, F_RDLCK, 0, 0); fl.l_type != F_WRLCK || fl.l_pid != -1 || fl.l_len != 0 {
+		t.Fatalf("F_OFD_GETLK = %+v", fl)
+	}
+	dup, _ := tk.call(linux.NR_dup, fd1)
+	if errno := tk.setlk(dup, F_OFD_SETLK, F_WRLCK, 0, 10); errno != 0 {
+		t.Fatalf("F_OFD_SETLK through dup errno = %v", errno)
+	}
+	if fl := tk.getlk(fd2, F_OFD_GETLK, F_RDLCK, 50, 1); fl.l_type != F_WRLCK || fl.l_start != 0 {
+		t.Fatalf("F_OFD_GETLK after dup = %+v, want the merged lock", fl)
+	}
+	if errno := tk.setlk(fd1, F_OFD_SETLK, F_UNLCK, 0, 0); errno != 0 {
+		t.Fatalf("F_OFD_SETLK unlock errno = %v", errno)
+	}
+	if errno := tk.setlk(fd2, F_OFD_SETLK, F_WRLCK, 0, 0); errno != 0 {
+		t.Fatalf("F_OFD_SETLK after unlock errno = %v", errno)
+	}
+	if errno := tk.setlk(fd1, F_OFD_SETLK, F_RDLCK, 0, 1); errno != linux.EAGAIN {
+		t.Fatalf("F_OFD_SETLK errno = %v, want EAGAIN", errno)
+	}
+	tk.call(linux.NR_close, fd2)
+	if errno := tk.setlk(fd1, F_OFD_SETLK, F_RDLCK, 0, 1); errno != 0 {
+		t.Fatalf("F_OFD_SETLK after close errno = %v", errno)
+	}
+}
+
+func TestLockWait(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	fd1 := uint64(tk.create("file", nil))
+	fd2 := tk.open("file", O_RDWR)
+	if errno := tk.setlk(fd1, F_OFD_SETLK, F_WRLCK, 0, 0); errno != 0 {
+		t.Fatalf("F_OFD_SETLK errno = %v", errno)
+	}
+	waiter := tk.task(2)
+	arg := tk.encode(&flock64{l_type: F_WRLCK})
+	done := make(chan linux.Errno, 1)
+	go func() {
+		_, errno := waiter.call(linux.NR_fcntl, fd2, F_OFD_SETLKW, arg)
+		done <- errno
+	}()
+	select {
+	case errno := <-done:
+		t.Fatalf("F_OFD_SETLKW returned early, errno = %v", errno)
+	case <-time.After(20 * time.Millisecond):
+	}
+	tk.setlk(fd1, F_OFD_SETLK, F_UNLCK, 0, 0)
+	select {
+	case errno := <-done:
+		if errno != 0 {
+			t.Fatalf("F_OFD_SETLKW errno = %v", errno)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("F_OFD_SETLKW was not woken by unlock")
+	}
+
+	tk.SetLockTimeout(10 * time.Millisecond)
+	if errno := tk.setlk(fd1, F_OFD_SETLKW, F_RDLCK, 0, 0); errno != linux.EINTR {
+		t.Fatalf("F_OFD_SETLKW with timeout errno = %v, want EINTR", errno)
+	}
+	tk.SetLockTimeout(0)
+	tk.setlk(fd2, F_OFD_SETLK, F_UNLCK, 0, 0)
+
+	other := tk.task(2)
+	tk.setlk(fd1, F_SETLK, F_WRLCK, 0, 1)
+	other.setlk(fd2, F_SETLK, F_WRLCK, 1, 1)
+	arg = other.encode(&flock64{l_type: F_WRLCK, l_len: 1})
+	go func() {
+		_, errno := other.call(linux.NR_fcntl, fd2, F_SETLKW, arg)
+		done <- errno
+	}()
+	for locks := &tk.sys.fcntl.locks; ; time.Sleep(time.Millisecond) {
+		locks.mu.Lock()
+		_, ok := locks.waiting[lockOwner{pid: 2}]
+		locks.mu.Unlock()
+		if ok {
+			break
+		}
+	}
+	if errno := tk.setlk(fd1, F_SETLKW, F_WRLCK, 1, 1); errno != linux.EDEADLK {
+		t.Fatalf("F_SETLKW errno = %v, want EDEADLK", errno)
+	}
+	tk.setlk(fd1, F_SETLK, F_UNLCK, 0, 0)
+	if errno := <-done; errno != 0 {
+		t.Fatalf("F_SETLKW after deadlock errno = %v", errno)
+	}
+
+	arg = tk.encode(&flock64{l_type: F_WRLCK})
+	go func() {
+		_, errno := tk.call(linux.NR_fcntl, fd1, F_SETLKW, arg)
+		done <- errno
+	}()
+	time.Sleep(10 * time.Millisecond)
+	tk.sys.fcntl.locks.dtor()
+	if errno := <-done; errno != linux.EINTR {
+		t.Fatalf("F_SETLKW during shutdown errno = %v, want EINTR", errno)
+	}
+}
+
+func TestFlock(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	fd1 := uint64(tk.create("file", nil))
+	fd2 := tk.open("file", O_RDONLY)
+	if _, errno := tk.call(linux.NR_flock, fd1, LOCK_EX); errno != 0 {
+		t.Fatalf("flock(LOCK_EX) errno = %v", errno)
+	}
+	if _, errno := tk.call(linux.NR_flock, fd2, LOCK_SH|LOCK_NB); errno != linux.EAGAIN {
+		t.Fatalf("flock(LOCK_SH|LOCK_NB) errno = %v, want EAGAIN", errno)
+	}
+	if errno := tk.setlk(fd2, F_OFD_SETLK, F_RDLCK, 0, 0); errno != 0 {
+		t.Fatalf("F_OFD_SETLK beside flock errno = %v", errno)
+	}
+	if _, errno := tk.call(linux.NR_flock, fd1, LOCK_SH); errno != 0 {
+		t.Fatalf("flock(LOCK_SH) errno = %v", errno)
+	}
+	if _, errno := tk.call(linux.NR_flock, fd2, LOCK_SH|LOCK_NB); errno != 0 {
+		t.Fatalf("shared flock errno = %v", errno)
+	}
+	if _, errno := tk.call(linux.NR_flock, fd1, LOCK_EX|LOCK_NB); errno != linux.EAGAIN {
+		t.Fatalf("flock upgrade errno = %v, want EAGAIN", errno)
+	}
+	if _, errno := tk.call(linux.NR_flock, fd2, LOCK_UN); errno != 0 {
+		t.Fatalf("flock(LOCK_UN) errno = %v", errno)
+	}
+	dup, _ := tk.call(linux.NR_dup, fd1)
+	if _, errno := tk.call(linux.NR_flock, dup, LOCK_EX|LOCK_NB); errno != 0 {
+		t.Fatalf("flock through dup errno = %v", errno)
+	}
+	if _, errno := tk.call(linux.NR_flock, fd2, LOCK_SH|LOCK_NB); errno != linux.EAGAIN {
+		t.Fatalf("flock(LOCK_SH|LOCK_NB) errno = %v, want EAGAIN", errno)
+	}
+	tk.call(linux.NR_close, dup)
+	if _, errno := tk.call(linux.NR_flock, fd2, LOCK_SH|LOCK_NB); errno != linux.EAGAIN {
+		t.Fatalf("flock after closing dup errno = %v, want EAGAIN", errno)
+	}
+	tk.call(linux.NR_close, fd1)
+	if _, errno := tk.call(linux.NR_flock, fd2, LOCK_SH|LOCK_NB); errno != 0 {
+		t.Fatalf("flock after last reference errno = %v", errno)
+	}
+	if _, errno := tk.call(linux.NR_flock, fd2, LOCK_SH|LOCK_EX); errno != linux.EINVAL {
+		t.Fatalf("flock(bad op) errno = %v, want EINVAL", errno)
+	}
+	if _, errno := tk.call(linux.NR_flock, 1000, LOCK_SH); errno != linux.EBADF {
+		t.Fatalf("flock(bad fd) errno = %v, want EBADF", errno)
+	}
+}
+
+func TestFcntl64Lock(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM)
+	for _, tt := range []struct {
+		v    ctype
+		size int
+	}{{new(flock), 16}, {new(flock64), 32}} {
+		if size, _ := sizeOf(armModel, tt.v); size != tt.size {
+			t.Fatalf("sizeof(%T) = %d, want %d", tt.v, size, tt.size)
+		}
+	}
+	fd1 := uint64(tk.create("file", nil))
+	fd2 := tk.open("file", O_RDWR)
+	if _, errno := tk.call(linux.NR_fcntl64, fd1, F_OFD_SETLK, tk.encode(&flock64{l_type: F_WRLCK, l_start: 1 << 32})); errno != 0 {
+		t.Fatalf("fcntl64(F_OFD_SETLK) errno = %v", errno)
+	}
+	arg := tk.encode(&flock64{l_type: F_RDLCK})
+	if _, errno := tk.call(linux.NR_fcntl64, fd2, F_OFD_GETLK, arg); errno != 0 {
+		t.Fatalf("fcntl64(F_OFD_GETLK) errno = %v", errno)
+	}
+	var fl flock64
+	tk.decode(arg, &fl)
+	if fl.l_type != F_WRLCK || fl.l_start != 1<<32 || fl.l_pid != -1 {
+		t.Fatalf("fcntl64(F_OFD_GETLK) = %+v", fl)
+	}
+	if _, errno := tk.call(linux.NR_fcntl64, fd2, F_GETLK, tk.encode(&flock{l_type: F_RDLCK})); errno != linux.EOVERFLOW {
+		t.Fatalf("fcntl64(F_GETLK) errno = %v, want EOVERFLOW", errno)
+	}
+	if _, errno := tk.call(linux.NR_fcntl, fd2, F_SETLK, tk.encode(&flock{l_type: F_WRLCK, l_len: 16})); errno != 0 {
+		t.Fatalf("fcntl(F_SETLK) errno = %v", errno)
+	}
+	arg = tk.encode(&flock{l_type: F_WRLCK})
+	if _, errno := tk.call(linux.NR_fcntl, fd1, F_OFD_GETLK, tk.encode(&flock64{l_type: F_RDLCK})); errno != 0 {
+		t.Fatalf("fcntl(F_OFD_GETLK) errno = %v", errno)
+	}
+	if _, errno := tk.call(linux.NR_fcntl64, fd1, F_GETLK64, arg); errno != 0 {
+		t.Fatalf("fcntl64(F_GETLK64) errno = %v", errno)
+	}
+}
@@ -12,6 +12,8 @@ import (
 const PAGE_SIZE = 4096
 
 type mman struct {
+	fcntl *fcntl
+	traps trapSites
 }
 
 func (k *mman) munmap(ctx linux.Context, addr emuptr, len size_t) int32 {
@@ -20,6 +22,7 @@ func (k *mman) munmap(ctx linux.Context, addr emuptr, len size_t) int32 {
 		ctx.SetErrno(linux.EINVAL)
 		return -1
 	}
+	k.traps.forget(addr, uint64(len))
 	return 0
 }
 
@@ -35,7 +38,7 @@ func (k *mman) mmap(ctx linux.Context, addr emuptr, len size_t, prot emulator.Me
 	dbg := ctx.Debugger()
 	var f filesystem.ReadFile
 	if flags&MAP_ANONYMOUS == 0 && fd >= 0 {
-		file, err := dbg.GetFile(int(fd))
+		file, err := k.fcntl.getFile(dbg, int(fd))
 		if err != nil {
 			ctx.SetErrno(linux.EBADF)
 			return MAP_FAILED
@@ -52,12 +55,13 @@ func (k *mman) mmap(ctx linux.Context, addr emuptr, len size_t, prot emulator.Me
 				_, err = io.ReadFull(f, make([]byte, offset))
 			}
 			if err != nil {
-				ctx.SetErrno(linux.ENODEV)
+				ctx.SetErrno(linux.ToErrno(err))
 				return MAP_FAILED
 			}
 		}
 	}
 	if flags&MAP_FIXED != 0 {
+		k.traps.forget(addr, uint64(len))
 		dbg.MemUnmap(addr, uint64(len))
 		region, err := dbg.MemMap(addr, uint64(len), prot)
 		if err != nil {
@@ -68,7 +72,7 @@ func (k *mman) mmap(ctx linux.Context, addr emuptr, len size_t, prot emulator.Me
 	} else {
 		region, err := dbg.MapAlloc(uint64(len), prot)
 		if err != nil {
-			ctx.SetErrno(linux.EINVAL)
+			ctx.SetErrno(linux.ENOMEM)
 			return MAP_FAILED
 		}
 		addr = region.Addr
@@ -77,9 +81,9 @@ func (k *mman) mmap(ctx linux.Context, addr emuptr, len size_t, prot emulator.Me
 		return addr
 	}
 	_, err := io.CopyN(io.NewOffsetWriter(ctx.ToPointer(addr), 0), f, int64(len))
-	if err != nil {
+	if err != nil && err != io.EOF {
 		dbg.MapFree(addr, uint64(len))
-		ctx.SetErrno(linux.ENODEV)
+		ctx.SetErrno(linux.ToErrno(err))
 		return MAP_FAILED
 	}
 	return addr
@@ -95,5 +99,10 @@ func (k *mman) mprotect(ctx linux.Context, start emuptr, len size_t, prot emulat
 		ctx.SetErrno(linux.EINVAL)
 		return -1
 	}
+	if prot&emulator.MEM_PROT_WRITE != 0 {
+		for _, site := range k.traps.forget(start, uint64(len)) {
+			ctx.ToPointer(site).MemWrite([]byte(X86_INSN_SYSCALL))
+		}
+	}
 	return 0
 }
@@ -0,0 +1,82 @@
+package kernel
+
+import (
+	"math"
+	"testing"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/emulator"
+)
+
+const (
+	testPROT_RW       = uint64(emulator.MEM_PROT_READ | emulator.MEM_PROT_WRITE)
+	testMAP_PRIVATE   = 0x02
+	testMAP_FIXED     = 0x10
+	testMAP_ANONYMOUS = 0x20
+)
+
+func TestMmap(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	fd := uint64(tk.create("file", []byte("0123456789")))
+	tk.call(linux.NR_lseek, fd, 0, 0)
+	var noFD = int64(-1)
+	tests := []struct {
+		name   string
+		addr   uint64
+		flags  uint64
+		fd     uint64
+		offset uint64
+		data   string
+		errno  linux.Errno
+	}{
+		{"anonymous", 0, testMAP_PRIVATE | testMAP_ANONYMOUS, uint64(noFD), 0, "\x00\x00\x00\x00", 0},
+		{"file", 0, testMAP_PRIVATE, fd, 0, "0123456789", 0},
+		{"file offset", 0, testMAP_PRIVATE, fd, 4, "456789", 0},
+		{"fixed", 0x7f000000, testMAP_PRIVATE | testMAP_ANONYMOUS | testMAP_FIXED, uint64(noFD), 0, "\x00\x00", 0},
+		{"bad fd", 0, testMAP_PRIVATE, 1000, 0, "", linux.EBADF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, errno := tk.call(linux.NR_mmap, tt.addr, PAGE_SIZE, testPROT_RW, tt.flags, tt.fd, tt.offset)
+			if errno != tt.errno {
+				t.Fatalf("mmap errno = %d, want %d", errno, tt.errno)
+			}
+			if tt.errno != 0 {
+				if r != math.MaxUint64 {
+					t.Fatalf("mmap = %#x, want MAP_FAILED", r)
+				}
+				return
+			}
+			if tt.addr != 0 && r != tt.addr {
+				t.Fatalf("mmap = %#x, want %#x", r, tt.addr)
+			}
+			if got := tk.bytes(r, len(tt.data)); string(got) != tt.data {
+				t.Fatalf("mapped data = %q, want %q", got, tt.data)
+			}
+		})
+	}
+}
+
+func TestMunmapMprotect(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	var noFD = int64(-1)
+	addr, errno := tk.call(linux.NR_mmap, 0, PAGE_SIZE, testPROT_RW, testMAP_PRIVATE|testMAP_ANONYMOUS, uint64(noFD), 0)
+	if errno != 0 {
+		t.Fatalf("mmap errno = %d", errno)
+	}
+	if _, errno := tk.call(linux.NR_mprotect, addr, PAGE_SIZE, uint64(emulator.MEM_PROT_READ)); errno != 0 {
+		t.Fatalf("mprotect errno = %d", errno)
+	}
+	if _, errno := tk.call(linux.NR_munmap, addr, PAGE_SIZE); errno != 0 {
+		t.Fatalf("munmap errno = %d", errno)
+	}
+	if _, err := tk.ctx.ToPointer(addr).MemRead(1); err == nil {
+		t.Fatal("memory still readable after munmap")
+	}
+	if _, errno := tk.call(linux.NR_mprotect, addr, PAGE_SIZE, testPROT_RW); errno != linux.EINVAL {
+		t.Fatalf("mprotect(unmapped) errno = %d, want EINVAL", errno)
+	}
+	if _, errno := tk.call(linux.NR_munmap, 0x1234, PAGE_SIZE); errno != linux.EINVAL {
+		t.Fatalf("munmap(0x1234) errno = %d, want EINVAL", errno)
+	}
+}
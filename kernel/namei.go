@@ -0,0 +1,284 @@
+package kernel
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+const (
+	AT_REMOVEDIR      = 0x200
+	AT_SYMLINK_FOLLOW = 0x400
+
+	RENAME_NOREPLACE = 0x1
+	RENAME_EXCHANGE  = 0x2
+
+	MAXSYMLINKS = 40
+)
+
+type RemoveFS interface {
+	filesystem.FS
+	Remove(name string) error
+}
+
+type SymlinkFS interface {
+	filesystem.FS
+	Symlink(oldname, newname string) error
+}
+
+type LinkFS interface {
+	filesystem.FS
+	Link(oldname string, newdir filesystem.FS, newname string) error
+}
+
+type RenameFS interface {
+	filesystem.FS
+	Rename(oldname string, newdir filesystem.FS, newname string) error
+}
+
+type NoReplaceFS interface {
+	RenameFS
+	RenameNoReplace(oldname string, newdir filesystem.FS, newname string) error
+}
+
+type ExchangeFS interface {
+	RenameFS
+	Exchange(oldname string, newdir filesystem.FS, newname string) error
+}
+
+func (f *fcntl) mkdirat(ctx linux.Context, dfd int32, pathname emuptr, mode mode_t) int32 {
+	dir, name, errno := f.pathat(ctx, dfd, pathname)
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	mkdir, ok := dir.(filesystem.DirFS)
+	if !ok {
+		ctx.SetErrno(linux.EROFS)
+		return -1
+	}
+	if _, err := lstat(dir, name); err == nil {
+		ctx.SetErrno(linux.EEXIST)
+		return -1
+	}
+	_, err := mkdir.Mkdir(name, f.creatMode(mode&0777))
+	if err != nil {
+		ctx.SetErrno(linux.ToErrno(err))
+		return -1
+	}
+	return 0
+}
+
+func (f *fcntl) unlinkat(ctx linux.Context, dfd int32, pathname emuptr, flag int32) int32 {
+	if flag&^AT_REMOVEDIR != 0 {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	dir, name, errno := f.pathat(ctx, dfd, pathname)
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	remove, ok := dir.(RemoveFS)
+	if !ok {
+		ctx.SetErrno(linux.EROFS)
+		return -1
+	}
+	info, err := lstat(dir, name)
+	if err != nil {
+		ctx.SetErrno(linux.ToErrno(err))
+		return -1
+	} else if flag&AT_REMOVEDIR != 0 && !info.IsDir() {
+		ctx.SetErrno(linux.ENOTDIR)
+		return -1
+	} else if flag&AT_REMOVEDIR == 0 && info.IsDir() {
+		ctx.SetErrno(linux.EISDIR)
+		return -1
+	} else if flag&AT_REMOVEDIR != 0 {
+		removeTmps(remove, name)
+	}
+	err = remove.Remove(name)
+	if err != nil {
+		ctx.SetErrno(linux.ToErrno(err))
+		return -1
+	}
+	return 0
+}
+
+// removeTmps drops the hidden O_TMPFILE names from the directory when
+// nothing else is left in it, so that rmdir sees it as empty.
+func removeTmps(remove RemoveFS, name string) {
+	entries, err := fs.ReadDir(remove, name)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), tmpfilePrefix) {
+			return
+		}
+	}
+	for _, entry := range entries {
+		remove.Remove(path.Join(name, entry.Name()))
+	}
+}
+
+func (f *fcntl) renameat2(ctx linux.Context, olddfd int32, oldname emuptr, newdfd int32, newname emuptr, flags uint32) int32 {
+	if flags&^(RENAME_NOREPLACE|RENAME_EXCHANGE) != 0 || flags == RENAME_NOREPLACE|RENAME_EXCHANGE {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	olddir, oldpath, errno := f.pathat(ctx, olddfd, oldname)
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	newdir, newpath, errno := f.pathat(ctx, newdfd, newname)
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	rename, ok := olddir.(RenameFS)
+	if !ok {
+		ctx.SetErrno(linux.EROFS)
+		return -1
+	}
+	if _, err := lstat(olddir, oldpath); err != nil {
+		ctx.SetErrno(linux.ToErrno(err))
+		return -1
+	}
+	var err error
+	switch {
+	case flags&RENAME_NOREPLACE != 0:
+		noreplace, ok := rename.(NoReplaceFS)
+		if !ok {
+			ctx.SetErrno(linux.EINVAL)
+			return -1
+		}
+		err = noreplace.RenameNoReplace(oldpath, newdir, newpath)
+	case flags&RENAME_EXCHANGE != 0:
+		if _, err := lstat(newdir, newpath); err != nil {
+			ctx.SetErrno(linux.ToErrno(err))
+			return -1
+		}
+		exchange, ok := rename.(ExchangeFS)
+		if !ok {
+			ctx.SetErrno(linux.EINVAL)
+			return -1
+		}
+		err = exchange.Exchange(oldpath, newdir, newpath)
+	default:
+		err = rename.Rename(oldpath, newdir, newpath)
+	}
+	if err != nil {
+		ctx.SetErrno(linux.ToErrno(err))
+		return -1
+	}
+	return 0
+}
+
+func (f *fcntl) symlinkat(ctx linux.Context, oldname emuptr, newdfd int32, newname emuptr) int32 {
+	target, err := ctx.ToPointer(oldname).MemReadString()
+	if err != nil {
+		ctx.SetErrno(linux.EFAULT)
+		return -1
+	} else if target == "" {
+		ctx.SetErrno(linux.ENOENT)
+		return -1
+	}
+	dir, name, errno := f.pathat(ctx, newdfd, newname)
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	symlink, ok := dir.(SymlinkFS)
+	if !ok {
+		ctx.SetErrno(linux.EROFS)
+		return -1
+	}
+	err = symlink.Symlink(target, name)
+	if err != nil {
+		ctx.SetErrno(linux.ToErrno(err))
+		return -1
+	}
+	return 0
+}
+
+func (f *fcntl) linkat(ctx linux.Context, olddfd int32, oldname emuptr, newdfd int32, newname emuptr, flags int32) int32 {
+	if flags&^(AT_SYMLINK_FOLLOW|AT_EMPTY_PATH) != 0 {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	name, err := ctx.ToPointer(oldname).MemReadString()
+	if err != nil {
+		ctx.SetErrno(linux.EFAULT)
+		return -1
+	}
+	var olddir filesystem.FS
+	var oldpath string
+	var errno linux.Errno
+	if name == "" && flags&AT_EMPTY_PATH != 0 && olddfd != AT_FDCWD {
+		olddir, oldpath, errno = f.linkFd(ctx, olddfd)
+	} else {
+		olddir, oldpath, errno = f.lookup(ctx, olddfd, name)
+	}
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	newdir, newpath, errno := f.pathat(ctx, newdfd, newname)
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	link, ok := olddir.(LinkFS)
+	if !ok {
+		ctx.SetErrno(linux.EROFS)
+		return -1
+	}
+	if flags&AT_SYMLINK_FOLLOW != 0 {
+		oldpath, errno = f.followLink(olddir, oldpath)
+		if errno != 0 {
+			ctx.SetErrno(errno)
+			return -1
+		}
+	}
+	err = link.Link(oldpath, newdir, newpath)
+	if err != nil {
+		ctx.SetErrno(linux.ToErrno(err))
+		return -1
+	}
+	return 0
+}
+
+func (f *fcntl) linkFd(ctx linux.Context, fd int32) (filesystem.FS, string, linux.Errno) {
+	if _, err := ctx.Debugger().GetFile(int(fd)); err != nil {
+		return nil, "", linux.EBADF
+	}
+	f.rw.RLock()
+	defer f.rw.RUnlock()
+	if tmp, ok := f.tmps[int(fd)]; ok {
+		if tmp.excl {
+			return nil, "", linux.ENOENT
+		}
+		return tmp.dir, tmp.name, 0
+	} else if name, ok := f.paths[int(fd)]; ok {
+		return ctx.Debugger().GetFS(), name, 0
+	}
+	return nil, "", linux.ENOENT
+}
+
+func (f *fcntl) pathat(ctx linux.Context, dfd int32, pathname emuptr) (filesystem.FS, string, linux.Errno) {
+	name, err := ctx.ToPointer(pathname).MemReadString()
+	if err != nil {
+		return nil, "", linux.EFAULT
+	}
+	return f.lookup(ctx, dfd, name)
+}
+
+func (f *fcntl) followLink(dir filesystem.FS, name string) (string, linux.Errno) {
+	_, name, errno := f.walk(dir, path.Dir(name), path.Base(name), 0, true)
+	return name, errno
+}
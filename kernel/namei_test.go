@@ -0,0 +1,156 @@
+package kernel
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/emulator"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+func (tk *testKernel) readFile(name string) string {
+	tk.tb.Helper()
+	b, err := os.ReadFile(filepath.Join(tk.dbg.root, name))
+	if err != nil {
+		tk.tb.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestMkdirUnlink(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	at := uint64(testAT_FDCWD)
+	tk.create("file", nil)
+	tests := []struct {
+		name  string
+		nr    linux.NR
+		args  []uint64
+		errno linux.Errno
+	}{
+		{"mkdirat", linux.NR_mkdirat, []uint64{at, tk.cstring("dir"), 0755}, 0},
+		{"mkdirat exists", linux.NR_mkdirat, []uint64{at, tk.cstring("dir"), 0755}, linux.EEXIST},
+		{"mkdirat no parent", linux.NR_mkdirat, []uint64{at, tk.cstring("a/b"), 0755}, linux.ENOENT},
+		{"mkdirat nested", linux.NR_mkdirat, []uint64{at, tk.cstring("dir/sub"), 0755}, 0},
+		{"unlinkat dir", linux.NR_unlinkat, []uint64{at, tk.cstring("dir"), 0}, linux.EISDIR},
+		{"rmdir file", linux.NR_unlinkat, []uint64{at, tk.cstring("file"), AT_REMOVEDIR}, linux.ENOTDIR},
+		{"rmdir not empty", linux.NR_unlinkat, []uint64{at, tk.cstring("dir"), AT_REMOVEDIR}, linux.ENOTEMPTY},
+		{"rmdir", linux.NR_unlinkat, []uint64{at, tk.cstring("dir/sub"), AT_REMOVEDIR}, 0},
+		{"unlinkat", linux.NR_unlinkat, []uint64{at, tk.cstring("file"), 0}, 0},
+		{"unlinkat missing", linux.NR_unlinkat, []uint64{at, tk.cstring("file"), 0}, linux.ENOENT},
+		{"unlinkat bad flag", linux.NR_unlinkat, []uint64{at, tk.cstring("dir"), 1}, linux.EINVAL},
+		{"unlinkat empty", linux.NR_unlinkat, []uint64{at, tk.cstring(""), 0}, linux.ENOENT},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, errno := tk.call(tt.nr, tt.args...); errno != tt.errno {
+				t.Fatalf("errno = %v, want %v", errno, tt.errno)
+			}
+		})
+	}
+
+	dir, _ := tk.call(linux.NR_openat, at, tk.cstring("dir"), 0, 0)
+	if _, errno := tk.call(linux.NR_mkdirat, dir, tk.cstring("rel"), 0700); errno != 0 {
+		t.Fatalf("mkdirat(dirfd) errno = %v", errno)
+	}
+	if info, err := os.Stat(filepath.Join(tk.dbg.root, "dir", "rel")); err != nil || !info.IsDir() {
+		t.Fatalf("mkdirat(dirfd) did not create dir/rel: %v", err)
+	}
+}
+
+func TestRenameat2(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	at := uint64(testAT_FDCWD)
+	tk.create("a", []byte("a"))
+	tk.create("b", []byte("b"))
+	rename := func(oldname, newname string, flags uint64) linux.Errno {
+		_, errno := tk.call(linux.NR_renameat2, at, tk.cstring(oldname), at, tk.cstring(newname), flags)
+		return errno
+	}
+	if errno := rename("a", "b", RENAME_NOREPLACE); errno != linux.EEXIST {
+		t.Fatalf("RENAME_NOREPLACE errno = %v, want EEXIST", errno)
+	}
+	if errno := rename("a", "b", RENAME_EXCHANGE); errno != 0 || tk.readFile("a") != "b" || tk.readFile("b") != "a" {
+		t.Fatalf("RENAME_EXCHANGE errno = %v", errno)
+	}
+	if errno := rename("a", "c", RENAME_EXCHANGE); errno != linux.ENOENT {
+		t.Fatalf("RENAME_EXCHANGE missing errno = %v, want ENOENT", errno)
+	}
+	if errno := rename("a", "b", RENAME_NOREPLACE|RENAME_EXCHANGE); errno != linux.EINVAL {
+		t.Fatalf("both flags errno = %v, want EINVAL", errno)
+	}
+	if errno := rename("a", "c", RENAME_NOREPLACE); errno != 0 || tk.readFile("c") != "b" {
+		t.Fatalf("RENAME_NOREPLACE errno = %v", errno)
+	}
+	tk.call(linux.NR_mkdirat, at, tk.cstring("dir"), 0755)
+	dir, _ := tk.call(linux.NR_openat, at, tk.cstring("dir"), 0, 0)
+	if _, errno := tk.call(linux.NR_renameat, at, tk.cstring("c"), dir, tk.cstring("d")); errno != 0 || tk.readFile("dir/d") != "b" {
+		t.Fatalf("renameat into dirfd errno = %v", errno)
+	}
+	if errno := rename("missing", "e", 0); errno != linux.ENOENT {
+		t.Fatalf("rename missing errno = %v, want ENOENT", errno)
+	}
+	if errno := rename("dir", "a", RENAME_NOREPLACE); errno != linux.EEXIST {
+		t.Fatalf("RENAME_NOREPLACE dir errno = %v, want EEXIST", errno)
+	}
+	tk.dbg.fs = struct{ RenameFS }{tk.dbg.fs.(RenameFS)}
+	if errno := rename("a", "e", RENAME_NOREPLACE); errno != linux.EINVAL {
+		t.Fatalf("RENAME_NOREPLACE unsupported errno = %v, want EINVAL", errno)
+	}
+}
+
+func TestSymlinkLink(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	at := uint64(testAT_FDCWD)
+	tk.create("file", []byte("data"))
+	if _, errno := tk.call(linux.NR_symlinkat, tk.cstring("file"), at, tk.cstring("sym")); errno != 0 {
+		t.Fatalf("symlinkat errno = %v", errno)
+	}
+	buf := tk.alloc(64)
+	if n, errno := tk.call(linux.NR_readlinkat, at, tk.cstring("sym"), buf, 64); errno != 0 || string(tk.bytes(buf, int(n))) != "file" {
+		t.Fatalf("readlinkat = %q, errno = %v", tk.bytes(buf, int(n)), errno)
+	}
+	if _, errno := tk.call(linux.NR_symlinkat, tk.cstring("file"), at, tk.cstring("sym")); errno != linux.EEXIST {
+		t.Fatalf("symlinkat exists errno = %v, want EEXIST", errno)
+	}
+	if _, errno := tk.call(linux.NR_linkat, at, tk.cstring("sym"), at, tk.cstring("hard"), AT_SYMLINK_FOLLOW); errno != 0 {
+		t.Fatalf("linkat errno = %v", errno)
+	}
+	if _, errno := tk.call(linux.NR_linkat, at, tk.cstring("sym"), at, tk.cstring("hardsym"), 0); errno != 0 {
+		t.Fatalf("linkat nofollow errno = %v", errno)
+	}
+	file, _ := os.Stat(filepath.Join(tk.dbg.root, "file"))
+	hard, _ := os.Lstat(filepath.Join(tk.dbg.root, "hard"))
+	hardsym, _ := os.Lstat(filepath.Join(tk.dbg.root, "hardsym"))
+	if !os.SameFile(file, hard) || hardsym.Mode().Type() != os.ModeSymlink {
+		t.Fatalf("hard = %v, hardsym = %v", hard.Mode(), hardsym.Mode())
+	}
+	if _, errno := tk.call(linux.NR_linkat, at, tk.cstring("file"), at, tk.cstring("x"), 1); errno != linux.EINVAL {
+		t.Fatalf("linkat bad flag errno = %v, want EINVAL", errno)
+	}
+}
+
+func TestReadOnlyFS(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	at := uint64(testAT_FDCWD)
+	tk.create("file", nil)
+	tk.dbg.fs = filesystem.SysDirFS(tk.dbg.root)
+	tests := []struct {
+		name string
+		nr   linux.NR
+		args []uint64
+	}{
+		{"unlinkat", linux.NR_unlinkat, []uint64{at, tk.cstring("file"), 0}},
+		{"renameat2", linux.NR_renameat2, []uint64{at, tk.cstring("file"), at, tk.cstring("new"), 0}},
+		{"symlinkat", linux.NR_symlinkat, []uint64{tk.cstring("file"), at, tk.cstring("sym")}},
+		{"linkat", linux.NR_linkat, []uint64{at, tk.cstring("file"), at, tk.cstring("hard"), 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, errno := tk.call(tt.nr, tt.args...); errno != linux.EROFS {
+				t.Fatalf("errno = %v, want EROFS", errno)
+			}
+		})
+	}
+}
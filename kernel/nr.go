@@ -0,0 +1,70 @@
+package kernel
+
+import (
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/emulator"
+)
+
+const __ARM_NR_BASE = 0x0f0000
+
+type nrTable struct {
+	nr  []linux.NR
+	ext map[uint64]linux.NR
+	no  map[linux.NR]uint64
+}
+
+var nrTables = map[emulator.Arch]*nrTable{
+	emulator.ARCH_ARM: newNRTable(armNR[:], map[uint64]linux.NR{
+		__ARM_NR_BASE + 1: linux.NR_reject,
+		__ARM_NR_BASE + 2: linux.NR_ignore,
+		__ARM_NR_BASE + 3: linux.NR_reject,
+		__ARM_NR_BASE + 4: linux.NR_reject,
+		__ARM_NR_BASE + 5: linux.NR_set_tls,
+	}),
+	emulator.ARCH_ARM64:  newNRTable(arm64NR[:], nil),
+	emulator.ARCH_X86:    newNRTable(x86NR[:], nil),
+	emulator.ARCH_X86_64: newNRTable(x86_64NR[:], nil),
+}
+
+func newNRTable(nr []linux.NR, ext map[uint64]linux.NR) *nrTable {
+	t := &nrTable{nr: nr, ext: ext, no: make(map[linux.NR]uint64, len(nr))}
+	for no, v := range nr {
+		if v > linux.NR_ignore {
+			if _, ok := t.no[v]; !ok {
+				t.no[v] = uint64(no)
+			}
+		}
+	}
+	for no, v := range ext {
+		if v > linux.NR_ignore {
+			t.no[v] = no
+		}
+	}
+	return t
+}
+
+func (t *nrTable) NR(no uint64) linux.NR {
+	if no < uint64(len(t.nr)) {
+		return t.nr[no]
+	}
+	return t.ext[no]
+}
+
+func (t *nrTable) No(nr linux.NR) (uint64, bool) {
+	no, ok := t.no[nr]
+	return no, ok
+}
+
+func LookupNR(arch emulator.Arch, no uint64) linux.NR {
+	if t, ok := nrTables[arch]; ok {
+		return t.NR(no)
+	}
+	return linux.NR_none
+}
+
+func LookupNo(arch emulator.Arch, nr linux.NR) (uint64, bool) {
+	if t, ok := nrTables[arch]; ok {
+		return t.No(nr)
+	}
+	return 0, false
+}
@@ -0,0 +1,47 @@
+package kernel
+
+import (
+	"testing"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/emulator"
+	emu_arm "github.com/wnxd/microdbg/emulator/arm"
+)
+
+func TestLookupNR(t *testing.T) {
+	tests := []struct {
+		arch emulator.Arch
+		no   uint64
+		nr   linux.NR
+	}{
+		{emulator.ARCH_ARM, 3, linux.NR_read},
+		{emulator.ARCH_ARM, __ARM_NR_BASE + 1, linux.NR_reject},
+		{emulator.ARCH_ARM, __ARM_NR_BASE + 2, linux.NR_ignore},
+		{emulator.ARCH_ARM, __ARM_NR_BASE + 5, linux.NR_set_tls},
+		{emulator.ARCH_ARM, __ARM_NR_BASE + 6, linux.NR_none},
+		{emulator.ARCH_ARM64, __ARM_NR_BASE + 5, linux.NR_none},
+		{emulator.ARCH_X86_64, 158, linux.NR_arch_prctl},
+	}
+	for _, tt := range tests {
+		if nr := LookupNR(tt.arch, tt.no); nr != tt.nr {
+			t.Errorf("LookupNR(%v, %#x) = %v, want %v", tt.arch, tt.no, nr, tt.nr)
+		}
+	}
+	if no, ok := LookupNo(emulator.ARCH_ARM, linux.NR_set_tls); !ok || no != __ARM_NR_BASE+5 {
+		t.Errorf("LookupNo(ARM, set_tls) = %#x, %v", no, ok)
+	}
+}
+
+func TestSetTLS(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM)
+	if r, errno := tk.call(LookupNR(emulator.ARCH_ARM, __ARM_NR_BASE+5), 0xdead0000); errno != 0 || r != 0 {
+		t.Fatalf("set_tls = %d, errno = %v", r, errno)
+	}
+	if tls, _ := tk.ctx.RegRead(emu_arm.ARM_REG_C13_C0_3); tls != 0xdead0000 {
+		t.Fatalf("TLS register = %#x, want %#x", tls, 0xdead0000)
+	}
+	tk = newTestKernel(t, emulator.ARCH_ARM64)
+	if _, errno := tk.call(linux.NR_set_tls, 0xdead0000); errno != linux.ENOSYS {
+		t.Fatalf("set_tls on arm64 errno = %v, want ENOSYS", errno)
+	}
+}
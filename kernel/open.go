@@ -0,0 +1,352 @@
+package kernel
+
+import (
+	"errors"
+	"io/fs"
+	"math"
+	"math/rand/v2"
+	"path"
+	"strconv"
+	"strings"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/emulator"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+const (
+	__O_TMPFILE = 0x400000
+
+	O_PATH_FLAGS     = O_DIRECTORY | O_NOFOLLOW | O_PATH | O_CLOEXEC
+	VALID_OPEN_FLAGS = O_ACCMODE | O_CREAT | O_EXCL | O_NOCTTY | O_TRUNC | O_APPEND | O_NONBLOCK | O_DSYNC | O_ASYNC |
+		O_DIRECT | O_LARGEFILE | O_DIRECTORY | O_NOFOLLOW | O_NOATIME | O_CLOEXEC | O_SYNC | O_PATH | O_TMPFILE
+
+	RESOLVE_NO_XDEV       = 0x01
+	RESOLVE_NO_MAGICLINKS = 0x02
+	RESOLVE_NO_SYMLINKS   = 0x04
+	RESOLVE_BENEATH       = 0x08
+	RESOLVE_IN_ROOT       = 0x10
+	RESOLVE_CACHED        = 0x20
+
+	OPEN_HOW_SIZE_VER0 = 24
+	OPEN_HOW_SIZE_MAX  = 4096
+)
+
+const tmpfilePrefix = ".#tmpfile."
+
+type NonblockFile interface {
+	filesystem.File
+	SetNonblock(nonblocking bool) error
+}
+
+type open_how struct {
+	flags   uint64
+	mode    uint64
+	resolve uint64
+}
+
+type pathFile struct {
+	dir    filesystem.FS
+	name   string
+	follow bool
+}
+
+type tmpFile struct {
+	dir  filesystem.FS
+	name string
+	excl bool
+	refs int
+}
+
+func (how *open_how) ctype(c *ccodec) {
+	cUint64(c, &how.flags)
+	cUint64(c, &how.mode)
+	cUint64(c, &how.resolve)
+}
+
+func (f *fcntl) creat(ctx linux.Context, filename emuptr, mode mode_t) int32 {
+	return f.doOpen(ctx, AT_FDCWD, filename, O_CREAT|O_WRONLY|O_TRUNC, mode&S_IALLUGO, 0)
+}
+
+func (f *fcntl) openat2(ctx linux.Context, dfd int32, filename, how emuptr, size size_t) int32 {
+	if size < OPEN_HOW_SIZE_VER0 {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	} else if size > OPEN_HOW_SIZE_MAX {
+		ctx.SetErrno(linux.E2BIG)
+		return -1
+	}
+	var h open_how
+	if memExtract(ctx, how, &h) != nil {
+		ctx.SetErrno(linux.EFAULT)
+		return -1
+	}
+	if size > OPEN_HOW_SIZE_VER0 {
+		rest, err := ctx.ToPointer(how + OPEN_HOW_SIZE_VER0).MemRead(uint64(size - OPEN_HOW_SIZE_VER0))
+		if err != nil {
+			ctx.SetErrno(linux.EFAULT)
+			return -1
+		} else if strings.Trim(string(rest), "\x00") != "" {
+			ctx.SetErrno(linux.E2BIG)
+			return -1
+		}
+	}
+	flags := openFlags(ctx.Debugger().Arch(), int32(h.flags))
+	switch {
+	case h.flags > math.MaxUint32 || flags&^VALID_OPEN_FLAGS != 0:
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	case h.mode&^S_IALLUGO != 0 || h.mode != 0 && flags&(O_CREAT|__O_TMPFILE) == 0:
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	case flags&O_PATH != 0 && flags&^O_PATH_FLAGS != 0:
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	case h.resolve&^(RESOLVE_NO_XDEV|RESOLVE_NO_MAGICLINKS|RESOLVE_NO_SYMLINKS|RESOLVE_BENEATH|RESOLVE_IN_ROOT|RESOLVE_CACHED) != 0:
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	case h.resolve&(RESOLVE_BENEATH|RESOLVE_IN_ROOT) == RESOLVE_BENEATH|RESOLVE_IN_ROOT:
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	case h.resolve&RESOLVE_CACHED != 0 && flags&(O_CREAT|O_TRUNC|__O_TMPFILE) != 0:
+		ctx.SetErrno(linux.EAGAIN)
+		return -1
+	}
+	return f.doOpen(ctx, dfd, filename, flags, mode_t(h.mode), h.resolve)
+}
+
+func (f *fcntl) doOpen(ctx linux.Context, dfd int32, filename emuptr, flags int32, mode mode_t, resolve uint64) int32 {
+	name, err := ctx.ToPointer(filename).MemReadString()
+	if err != nil {
+		ctx.SetErrno(linux.EFAULT)
+		return -1
+	}
+	switch {
+	case flags&__O_TMPFILE != 0 && (flags&(O_TMPFILE|O_CREAT) != O_TMPFILE || flags&O_ACCMODE == O_RDONLY):
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	case flags&(O_CREAT|O_DIRECTORY) == O_CREAT|O_DIRECTORY:
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	follow := flags&O_NOFOLLOW == 0 && flags&(O_CREAT|O_EXCL) != O_CREAT|O_EXCL
+	dir, name, errno := f.resolveAt(ctx, dfd, name, resolve, follow)
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	var file filesystem.File
+	var tmp *tmpFile
+	switch {
+	case flags&O_PATH != 0:
+		file, errno = openPath(dir, name, flags)
+	case flags&__O_TMPFILE != 0:
+		file, tmp, errno = f.openTmp(dir, name, flags, mode)
+	default:
+		file, errno = f.openFile(dir, name, flags, mode)
+	}
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	if nb, ok := file.(NonblockFile); ok && flags&O_NONBLOCK != 0 {
+		nb.SetNonblock(true)
+	}
+	fd := ctx.Debugger().CreateFileDescriptor(file)
+	f.rw.Lock()
+	f.setFlags(fd, flags)
+	if tmp != nil {
+		f.install(fd, file, "")
+		f.tmps[fd] = tmp
+	} else if name[0] == '/' {
+		f.install(fd, file, name)
+		f.paths[fd] = name
+	} else {
+		f.install(fd, file, "")
+	}
+	f.rw.Unlock()
+	return int32(fd)
+}
+
+func (f *fcntl) openFile(dir filesystem.FS, name string, flags int32, mode mode_t) (filesystem.File, linux.Errno) {
+	if flags&O_NOFOLLOW != 0 {
+		if info, err := lstat(dir, name); err == nil && info.Mode().Type() == fs.ModeSymlink {
+			return nil, linux.ELOOP
+		}
+	}
+	if flags&O_DIRECTORY != 0 {
+		if info, err := stat(dir, name); err != nil {
+			return nil, linux.ToErrno(err)
+		} else if !info.IsDir() {
+			return nil, linux.ENOTDIR
+		}
+	}
+	file, err := dir.OpenFile(name, toFileFlag(flags), f.creatMode(mode))
+	if err != nil {
+		return nil, linux.ToErrno(err)
+	}
+	return file, 0
+}
+
+func (f *fcntl) openTmp(dir filesystem.FS, name string, flags int32, mode mode_t) (filesystem.File, *tmpFile, linux.Errno) {
+	if info, err := stat(dir, name); err != nil {
+		return nil, nil, linux.ToErrno(err)
+	} else if !info.IsDir() {
+		return nil, nil, linux.ENOTDIR
+	} else if _, ok := dir.(RemoveFS); !ok {
+		return nil, nil, linux.EOPNOTSUPP
+	}
+	flag := toFileFlag(flags&^(O_TMPFILE|O_TRUNC)) | filesystem.O_CREATE | filesystem.O_EXCL
+	for range MAXSYMLINKS {
+		tmp := &tmpFile{
+			dir:  dir,
+			name: path.Join(name, tmpfilePrefix+strconv.FormatUint(rand.Uint64(), 36)),
+			excl: flags&O_EXCL != 0,
+			refs: 1,
+		}
+		file, err := dir.OpenFile(tmp.name, flag, f.creatMode(mode))
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		} else if err != nil {
+			return nil, nil, linux.ToErrno(err)
+		}
+		// An O_EXCL tmpfile can never be linked, so drop its name at once
+		// where the FS lets an open file be unlinked.
+		if tmp.excl && dir.(RemoveFS).Remove(tmp.name) == nil {
+			tmp.name = ""
+		}
+		return file, tmp, 0
+	}
+	return nil, nil, linux.EEXIST
+}
+
+func (f *fcntl) releaseTmp(fd int) {
+	tmp, ok := f.tmps[fd]
+	if !ok {
+		return
+	}
+	delete(f.tmps, fd)
+	if tmp.refs--; tmp.refs == 0 && tmp.name != "" {
+		tmp.dir.(RemoveFS).Remove(tmp.name)
+	}
+}
+
+func (f *fcntl) walk(fsys filesystem.FS, top, name string, resolve uint64, follow bool) (filesystem.FS, string, linux.Errno) {
+	f.rw.RLock()
+	root := f.root
+	f.rw.RUnlock()
+	if top == "." || resolve&RESOLVE_IN_ROOT != 0 {
+		root = top
+	}
+	var dev uint64
+	if resolve&RESOLVE_NO_XDEV != 0 {
+		info, err := stat(fsys, top)
+		if err != nil {
+			return nil, "", linux.ToErrno(err)
+		}
+		dev = newKstat(info, info.Name()).dev
+	}
+	cur, comps := top, strings.Split(name, "/")
+	if path.IsAbs(name) {
+		if resolve&RESOLVE_BENEATH != 0 {
+			return nil, "", linux.EXDEV
+		}
+		cur = root
+	}
+	for links := 0; len(comps) > 0; {
+		c := comps[0]
+		comps = comps[1:]
+		switch c {
+		case "", ".":
+			continue
+		case "..":
+			if cur == root || cur == top && resolve&RESOLVE_BENEATH != 0 {
+				if resolve&RESOLVE_BENEATH != 0 {
+					return nil, "", linux.EXDEV
+				}
+			} else {
+				cur = path.Dir(cur)
+			}
+			continue
+		}
+		if strings.HasPrefix(c, tmpfilePrefix) {
+			return nil, "", linux.ENOENT
+		}
+		next := path.Join(cur, c)
+		info, err := lstat(fsys, next)
+		if err != nil {
+			if len(comps) == 0 && errors.Is(err, fs.ErrNotExist) {
+				return fsys, next, 0
+			}
+			return nil, "", linux.ToErrno(err)
+		} else if resolve&RESOLVE_NO_XDEV != 0 && newKstat(info, info.Name()).dev != dev {
+			return nil, "", linux.EXDEV
+		}
+		if info.Mode().Type() == fs.ModeSymlink && (len(comps) > 0 || follow) {
+			readlink, ok := fsys.(filesystem.ReadlinkFS)
+			if resolve&RESOLVE_NO_SYMLINKS != 0 || !ok {
+				return nil, "", linux.ELOOP
+			} else if links++; links > MAXSYMLINKS {
+				return nil, "", linux.ELOOP
+			}
+			target, err := readlink.Readlink(next)
+			if err != nil {
+				return nil, "", linux.ToErrno(err)
+			} else if path.IsAbs(target) {
+				if resolve&RESOLVE_BENEATH != 0 {
+					return nil, "", linux.EXDEV
+				}
+				cur = root
+			}
+			comps = append(strings.Split(target, "/"), comps...)
+			continue
+		} else if len(comps) > 0 && !info.IsDir() {
+			return nil, "", linux.ENOTDIR
+		}
+		cur = next
+	}
+	return fsys, cur, 0
+}
+
+func openPath(dir filesystem.FS, name string, flags int32) (filesystem.File, linux.Errno) {
+	file := &pathFile{dir: dir, name: name, follow: flags&O_NOFOLLOW == 0}
+	info, err := file.Stat()
+	if err != nil {
+		return nil, linux.ToErrno(err)
+	} else if flags&O_DIRECTORY != 0 && !info.IsDir() {
+		return nil, linux.ENOTDIR
+	}
+	return file, 0
+}
+
+func (p *pathFile) Close() error {
+	return nil
+}
+
+func (p *pathFile) Stat() (fs.FileInfo, error) {
+	if p.follow {
+		return stat(p.dir, p.name)
+	}
+	return lstat(p.dir, p.name)
+}
+
+func (p *pathFile) Open(name string) (fs.File, error) {
+	return p.dir.Open(path.Join(p.name, name))
+}
+
+func (p *pathFile) OpenFile(name string, flag filesystem.FileFlag, perm fs.FileMode) (filesystem.File, error) {
+	return p.dir.OpenFile(path.Join(p.name, name), flag, perm)
+}
+
+func openFlags(arch emulator.Arch, flags int32) int32 {
+	const (
+		lo = O_DIRECT | O_LARGEFILE
+		hi = O_DIRECTORY | O_NOFOLLOW
+	)
+
+	switch arch {
+	case emulator.ARCH_ARM, emulator.ARCH_ARM64:
+		return flags&^(lo|hi) | flags&lo<<2 | flags&hi>>2
+	}
+	return flags
+}
@@ -0,0 +1,269 @@
+package kernel
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/emulator"
+)
+
+func (tk *testKernel) openHow(dfd uint64, name string, how open_how, size uint64) (uint64, linux.Errno) {
+	tk.tb.Helper()
+	addr := tk.alloc(size + OPEN_HOW_SIZE_VER0)
+	memWrite(tk.ctx, addr, &how)
+	return tk.call(linux.NR_openat2, dfd, tk.cstring(name), addr, size)
+}
+
+func TestOpenFlags(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_X86_64)
+	at := uint64(testAT_FDCWD)
+	tk.create("file", nil)
+	tk.call(linux.NR_mkdirat, at, tk.cstring("dir"), 0755)
+	tk.call(linux.NR_symlinkat, tk.cstring("file"), at, tk.cstring("link"))
+	tk.call(linux.NR_symlinkat, tk.cstring("dir"), at, tk.cstring("dirlink"))
+	tests := []struct {
+		name  string
+		path  string
+		flags uint64
+		errno linux.Errno
+	}{
+		{"directory on file", "file", O_DIRECTORY, linux.ENOTDIR},
+		{"directory", "dir", O_DIRECTORY, 0},
+		{"directory via link", "dirlink", O_DIRECTORY, 0},
+		{"nofollow link", "link", O_NOFOLLOW, linux.ELOOP},
+		{"nofollow file", "file", O_NOFOLLOW, 0},
+		{"create directory", "new", O_CREAT | O_DIRECTORY, linux.EINVAL},
+		{"tmpfile read only", "dir", O_TMPFILE, linux.EINVAL},
+		{"tmpfile on file", "file", O_TMPFILE | O_RDWR, linux.ENOTDIR},
+		{"path missing", "missing", O_PATH, linux.ENOENT},
+		{"nonblock", "file", O_NONBLOCK | O_RDWR, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, errno := tk.call(linux.NR_openat, at, tk.cstring(tt.path), tt.flags, 0); errno != tt.errno {
+				t.Fatalf("errno = %v, want %v", errno, tt.errno)
+			}
+		})
+	}
+	if fd, errno := tk.call(linux.NR_creat, tk.cstring("created"), 0600); errno != 0 {
+		t.Fatalf("creat errno = %v", errno)
+	} else if flags, _ := tk.call(linux.NR_fcntl, fd, 3, 0); flags != O_WRONLY {
+		t.Fatalf("creat flags = %#x", flags)
+	}
+}
+
+func TestOpenPath(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_X86_64)
+	at := uint64(testAT_FDCWD)
+	tk.call(linux.NR_mkdirat, at, tk.cstring("dir"), 0755)
+	tk.create("dir/file", []byte("data"))
+	tk.call(linux.NR_symlinkat, tk.cstring("dir/file"), at, tk.cstring("link"))
+	fd, errno := tk.call(linux.NR_openat, at, tk.cstring("link"), O_PATH|O_NOFOLLOW|O_RDWR, 0)
+	if errno != 0 {
+		t.Fatalf("openat(O_PATH) errno = %v", errno)
+	}
+	if stx := tk.statAt(fd, "", AT_EMPTY_PATH); stx.stx_mode&S_IFMT != S_IFLNK {
+		t.Fatalf("fstat mode = %#o, want symlink", stx.stx_mode)
+	}
+	if flags, _ := tk.call(linux.NR_fcntl, fd, 3, 0); flags != O_PATH|O_NOFOLLOW {
+		t.Fatalf("flags = %#x, want O_PATH|O_NOFOLLOW", flags)
+	}
+	buf := tk.alloc(4)
+	if _, errno := tk.call(linux.NR_read, fd, buf, 4); errno != linux.EBADF {
+		t.Fatalf("read errno = %v, want EBADF", errno)
+	}
+	dfd, errno := tk.call(linux.NR_openat, at, tk.cstring("dir"), O_PATH|O_DIRECTORY, 0)
+	if errno != 0 {
+		t.Fatalf("openat(dir) errno = %v", errno)
+	}
+	if stx := tk.statAt(dfd, "file", 0); stx.stx_size != 4 {
+		t.Fatalf("size = %d, want 4", stx.stx_size)
+	}
+	rfd, errno := tk.call(linux.NR_openat, dfd, tk.cstring("file"), O_RDONLY, 0)
+	if errno != 0 {
+		t.Fatalf("openat(dfd) errno = %v", errno)
+	}
+	if n, errno := tk.call(linux.NR_read, rfd, buf, 4); errno != 0 || string(tk.bytes(buf, int(n))) != "data" {
+		t.Fatalf("read = %q, errno = %v", tk.bytes(buf, int(n)), errno)
+	}
+	if _, errno := tk.call(linux.NR_openat, at, tk.cstring("dir/file"), O_PATH|O_DIRECTORY, 0); errno != linux.ENOTDIR {
+		t.Fatalf("O_PATH|O_DIRECTORY errno = %v, want ENOTDIR", errno)
+	}
+}
+
+func TestOpenTmpfile(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_X86_64)
+	at := uint64(testAT_FDCWD)
+	tk.call(linux.NR_mkdirat, at, tk.cstring("dir"), 0755)
+	listing := func() []string {
+		entries, err := os.ReadDir(filepath.Join(tk.dbg.root, "dir"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		return names
+	}
+
+	fd, errno := tk.call(linux.NR_openat, at, tk.cstring("dir"), O_TMPFILE|O_RDWR, 0600)
+	if errno != 0 {
+		t.Fatalf("openat(O_TMPFILE) errno = %v", errno)
+	}
+	buf := tk.alloc(4)
+	tk.ctx.ToPointer(buf).MemWrite([]byte("data"))
+	tk.call(linux.NR_write, fd, buf, 4)
+	dfd, _ := tk.call(linux.NR_openat, at, tk.cstring("dir"), O_DIRECTORY, 0)
+	if dirents, _ := tk.getdents(dfd, 256); len(dirents) != 2 {
+		t.Fatalf("getdents = %+v, want only . and ..", dirents)
+	}
+	if names := listing(); len(names) != 1 {
+		t.Fatalf("listing = %v, want the hidden tmpfile", names)
+	} else if _, errno := tk.call(linux.NR_openat, at, tk.cstring("dir/"+names[0]), O_RDWR, 0); errno != linux.ENOENT {
+		t.Fatalf("openat(hidden tmpfile) errno = %v, want ENOENT", errno)
+	}
+	if _, errno := tk.call(linux.NR_linkat, fd, tk.cstring(""), at, tk.cstring("dir/named"), AT_EMPTY_PATH); errno != 0 {
+		t.Fatalf("linkat errno = %v", errno)
+	}
+	dup, _ := tk.call(linux.NR_fcntl, fd, 0, 0)
+	tk.call(linux.NR_close, fd)
+	if names := listing(); len(names) != 2 {
+		t.Fatalf("listing after first close = %v", names)
+	}
+	tk.call(linux.NR_close, dup)
+	if names := listing(); len(names) != 1 || names[0] != "named" {
+		t.Fatalf("listing = %v, want [named]", names)
+	}
+	if got := tk.readFile("dir/named"); got != "data" {
+		t.Fatalf("linked contents = %q", got)
+	}
+
+	fd, errno = tk.call(linux.NR_openat, at, tk.cstring("dir"), O_TMPFILE|O_EXCL|O_WRONLY, 0600)
+	if errno != 0 {
+		t.Fatalf("openat(O_TMPFILE|O_EXCL) errno = %v", errno)
+	}
+	if names := listing(); len(names) != 1 || names[0] != "named" {
+		t.Fatalf("listing after O_EXCL = %v, want [named]", names)
+	}
+	if _, errno := tk.call(linux.NR_linkat, fd, tk.cstring(""), at, tk.cstring("dir/excl"), AT_EMPTY_PATH); errno != linux.ENOENT {
+		t.Fatalf("linkat(O_EXCL) errno = %v, want ENOENT", errno)
+	}
+	tk.sys.fcntl.dtor()
+	for _, name := range listing() {
+		if strings.HasPrefix(name, tmpfilePrefix) {
+			t.Fatalf("tmpfile %q survived dtor", name)
+		}
+	}
+	tk.sys.fcntl.ctor()
+
+	tk.call(linux.NR_mkdirat, at, tk.cstring("empty"), 0755)
+	if _, errno := tk.call(linux.NR_openat, at, tk.cstring("empty"), O_TMPFILE|O_RDWR, 0600); errno != 0 {
+		t.Fatalf("openat(O_TMPFILE) errno = %v", errno)
+	}
+	if _, errno := tk.call(linux.NR_unlinkat, at, tk.cstring("empty"), AT_REMOVEDIR); errno != 0 {
+		t.Fatalf("rmdir with a tmpfile errno = %v", errno)
+	}
+}
+
+func TestOpenat2(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_X86_64)
+	at := uint64(testAT_FDCWD)
+	tk.create("file", nil)
+	tk.call(linux.NR_mkdirat, at, tk.cstring("dir"), 0755)
+	tk.create("dir/inner", nil)
+	tk.call(linux.NR_symlinkat, tk.cstring("/file"), at, tk.cstring("dir/abs"))
+	tk.call(linux.NR_symlinkat, tk.cstring("inner"), at, tk.cstring("dir/rel"))
+	tk.call(linux.NR_symlinkat, tk.cstring("../file"), at, tk.cstring("dir/esc"))
+	dfd, _ := tk.call(linux.NR_openat, at, tk.cstring("dir"), O_PATH|O_DIRECTORY, 0)
+
+	resolve := []struct {
+		name    string
+		dfd     uint64
+		path    string
+		resolve uint64
+		errno   linux.Errno
+	}{
+		{"plain", dfd, "inner", 0, 0},
+		{"beneath", dfd, "inner", RESOLVE_BENEATH, 0},
+		{"beneath dotdot", dfd, "../file", RESOLVE_BENEATH, linux.EXDEV},
+		{"beneath absolute", dfd, "/file", RESOLVE_BENEATH, linux.EXDEV},
+		{"beneath absolute link", dfd, "abs", RESOLVE_BENEATH, linux.EXDEV},
+		{"beneath escaping link", dfd, "esc", RESOLVE_BENEATH, linux.EXDEV},
+		{"beneath relative link", dfd, "rel", RESOLVE_BENEATH, 0},
+		{"beneath cwd", at, "dir/../file", RESOLVE_BENEATH, 0},
+		{"no symlinks", dfd, "rel", RESOLVE_NO_SYMLINKS, linux.ELOOP},
+		{"in root dotdot", dfd, "../inner", RESOLVE_IN_ROOT, 0},
+		{"in root absolute link", dfd, "abs", RESOLVE_IN_ROOT, linux.ENOENT},
+		{"no xdev", dfd, "inner", RESOLVE_NO_XDEV, 0},
+		{"not dir", dfd, "inner/x", RESOLVE_BENEATH, linux.ENOTDIR},
+	}
+	for _, tt := range resolve {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, errno := tk.openHow(tt.dfd, tt.path, open_how{resolve: tt.resolve}, OPEN_HOW_SIZE_VER0); errno != tt.errno {
+				t.Fatalf("errno = %v, want %v", errno, tt.errno)
+			}
+		})
+	}
+
+	invalid := []struct {
+		name  string
+		how   open_how
+		size  uint64
+		errno linux.Errno
+	}{
+		{"small", open_how{}, 16, linux.EINVAL},
+		{"large", open_how{}, 8192, linux.E2BIG},
+		{"extended", open_how{}, 32, 0},
+		{"unknown flag", open_how{flags: 1 << 31}, OPEN_HOW_SIZE_VER0, linux.EINVAL},
+		{"high flag", open_how{flags: 1 << 32}, OPEN_HOW_SIZE_VER0, linux.EINVAL},
+		{"mode without create", open_how{mode: 0644}, OPEN_HOW_SIZE_VER0, linux.EINVAL},
+		{"bad mode", open_how{flags: O_CREAT, mode: 010000}, OPEN_HOW_SIZE_VER0, linux.EINVAL},
+		{"path with access", open_how{flags: O_PATH | O_RDWR}, OPEN_HOW_SIZE_VER0, linux.EINVAL},
+		{"unknown resolve", open_how{resolve: 0x40}, OPEN_HOW_SIZE_VER0, linux.EINVAL},
+		{"beneath in root", open_how{resolve: RESOLVE_BENEATH | RESOLVE_IN_ROOT}, OPEN_HOW_SIZE_VER0, linux.EINVAL},
+		{"cached create", open_how{flags: O_CREAT, resolve: RESOLVE_CACHED}, OPEN_HOW_SIZE_VER0, linux.EAGAIN},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, errno := tk.openHow(at, "file", tt.how, tt.size); errno != tt.errno {
+				t.Fatalf("errno = %v, want %v", errno, tt.errno)
+			}
+		})
+	}
+	addr := tk.alloc(32)
+	memWrite(tk.ctx, addr, &open_how{})
+	tk.ctx.ToPointer(addr + 31).MemWrite([]byte{1})
+	if _, errno := tk.call(linux.NR_openat2, at, tk.cstring("file"), addr, 32); errno != linux.E2BIG {
+		t.Fatalf("nonzero tail errno = %v, want E2BIG", errno)
+	}
+}
+
+func TestOpenFlagsARM(t *testing.T) {
+	const (
+		arm_O_DIRECTORY = 0x4000
+		arm_O_NOFOLLOW  = 0x8000
+	)
+
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	at := uint64(testAT_FDCWD)
+	tk.create("file", nil)
+	tk.call(linux.NR_mkdirat, at, tk.cstring("dir"), 0755)
+	tk.call(linux.NR_symlinkat, tk.cstring("file"), at, tk.cstring("link"))
+	if _, errno := tk.call(linux.NR_openat, at, tk.cstring("file"), arm_O_DIRECTORY, 0); errno != linux.ENOTDIR {
+		t.Fatalf("O_DIRECTORY errno = %v, want ENOTDIR", errno)
+	}
+	if _, errno := tk.call(linux.NR_openat, at, tk.cstring("link"), arm_O_NOFOLLOW, 0); errno != linux.ELOOP {
+		t.Fatalf("O_NOFOLLOW errno = %v, want ELOOP", errno)
+	}
+	fd, errno := tk.call(linux.NR_openat, at, tk.cstring("dir"), arm_O_DIRECTORY, 0)
+	if errno != 0 {
+		t.Fatalf("openat(dir) errno = %v", errno)
+	}
+	if flags, _ := tk.call(linux.NR_fcntl, fd, 3, 0); flags != arm_O_DIRECTORY {
+		t.Fatalf("F_GETFL = %#x, want %#x", flags, arm_O_DIRECTORY)
+	}
+}
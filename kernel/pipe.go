@@ -0,0 +1,372 @@
+package kernel
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"math/bits"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+const (
+	PIPE_BUF = 4096
+
+	F_SETPIPE_SZ = 1031
+	F_GETPIPE_SZ = 1032
+
+	FIONREAD = 0x541B
+)
+
+const (
+	pipeDefSize = 16 * PAGE_SIZE
+	pipeMaxSize = 1 << 20
+)
+
+var pipeIno atomic.Uint64
+
+type pipeBuf struct {
+	data   []byte
+	packet bool
+}
+
+type pipe struct {
+	mu      sync.Mutex
+	bufs    []pipeBuf
+	len     int
+	size    int
+	readers int
+	writers int
+	wake    chan struct{}
+	name    string
+	mtime   time.Time
+}
+
+type pipeEnd struct {
+	*pipe
+	writer   bool
+	direct   bool
+	nonblock atomic.Bool
+	closed   atomic.Bool
+}
+
+type pipeIO struct {
+	*pipeEnd
+	done     <-chan struct{}
+	nonblock bool
+}
+
+type pipeInfo struct {
+	name  string
+	mtime time.Time
+}
+
+func newPipe(flags int32) (r, w *pipeEnd) {
+	p := &pipe{
+		size:    pipeDefSize,
+		readers: 1,
+		writers: 1,
+		wake:    make(chan struct{}),
+		name:    fmt.Sprintf("pipe:[%d]", pipeIno.Add(1)),
+		mtime:   time.Now(),
+	}
+	r = &pipeEnd{pipe: p, direct: flags&O_DIRECT != 0}
+	w = &pipeEnd{pipe: p, writer: true, direct: flags&O_DIRECT != 0}
+	r.nonblock.Store(flags&O_NONBLOCK != 0)
+	w.nonblock.Store(flags&O_NONBLOCK != 0)
+	return r, w
+}
+
+func (f *fcntl) pipeOf(fd int) *pipeEnd {
+	f.rw.RLock()
+	defer f.rw.RUnlock()
+	return f.pipes[fd]
+}
+
+func (f *fcntl) stream(ctx linux.Context, fd int, file filesystem.File) filesystem.File {
+	if p := f.pipeOf(fd); p != nil {
+		return pipeIO{p, taskDone(ctx), false}
+	}
+	return file
+}
+
+func (f *fcntl) pipeSize(ctx linux.Context, fd int, cmd uint32, arg ulong_t) int32 {
+	p := f.pipeOf(fd)
+	if p == nil {
+		ctx.SetErrno(linux.EBADF)
+		return -1
+	} else if cmd == F_GETPIPE_SZ {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return int32(p.size)
+	}
+	size, errno := p.resize(uint64(arg))
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	return int32(size)
+}
+
+func (f *fcntl) pipeIoctl(ctx linux.Context, p *pipeEnd, cmd uint32, arg emuptr) int32 {
+	if cmd != FIONREAD {
+		ctx.SetErrno(linux.ENOTTY)
+		return -1
+	}
+	p.mu.Lock()
+	n := int32(p.len)
+	p.mu.Unlock()
+	if ctx.ToPointer(arg).MemWritePtr(4, unsafe.Pointer(&n)) != nil {
+		ctx.SetErrno(linux.EFAULT)
+		return -1
+	}
+	return 0
+}
+
+func (p *pipe) resize(size uint64) (int, linux.Errno) {
+	if size > 1<<31 {
+		return 0, linux.EINVAL
+	}
+	pages := max((size+PAGE_SIZE-1)/PAGE_SIZE, 1)
+	n := PAGE_SIZE << bits.Len64(pages-1)
+	if n > pipeMaxSize {
+		return 0, linux.EPERM
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.len > n {
+		return 0, linux.EBUSY
+	}
+	p.size = n
+	p.changed()
+	return n, 0
+}
+
+func (p *pipe) changed() {
+	close(p.wake)
+	p.wake = make(chan struct{})
+}
+
+func (p *pipe) wait(done <-chan struct{}) bool {
+	wake := p.wake
+	p.mu.Unlock()
+	defer p.mu.Lock()
+	select {
+	case <-wake:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+func (p *pipe) hangup() {
+	p.mu.Lock()
+	p.readers, p.writers = 0, 0
+	p.changed()
+	p.mu.Unlock()
+}
+
+func (p *pipe) push(b []byte, packet bool) {
+	if packet {
+		for len(b) > 0 {
+			n := min(len(b), PIPE_BUF)
+			p.bufs = append(p.bufs, pipeBuf{data: append([]byte(nil), b[:n]...), packet: true})
+			b = b[n:]
+		}
+	} else if last := len(p.bufs) - 1; last >= 0 && !p.bufs[last].packet {
+		p.bufs[last].data = append(p.bufs[last].data, b...)
+	} else {
+		p.bufs = append(p.bufs, pipeBuf{data: append([]byte(nil), b...)})
+	}
+}
+
+func (p *pipe) consume(n int, packet bool) {
+	if packet {
+		n = len(p.bufs[0].data)
+	}
+	p.len -= n
+	for n > 0 {
+		if n < len(p.bufs[0].data) {
+			p.bufs[0].data = p.bufs[0].data[n:]
+			break
+		}
+		n -= len(p.bufs[0].data)
+		p.bufs[0] = pipeBuf{}
+		p.bufs = p.bufs[1:]
+	}
+	p.changed()
+}
+
+func (e *pipeEnd) recv(b []byte, done <-chan struct{}, peek, nonblock bool) (int, error) {
+	if e.writer {
+		return 0, linux.EBADF
+	} else if len(b) == 0 {
+		return 0, nil
+	}
+	p := e.pipe
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.len == 0 {
+		if p.writers == 0 {
+			return 0, io.EOF
+		} else if nonblock || e.nonblock.Load() {
+			return 0, linux.EAGAIN
+		} else if !p.wait(done) {
+			return 0, linux.EINTR
+		}
+	}
+	var n int
+	for _, buf := range p.bufs {
+		n += copy(b[n:], buf.data)
+		if n == len(b) || e.direct {
+			break
+		}
+	}
+	if !peek {
+		p.consume(n, e.direct)
+	}
+	return n, nil
+}
+
+func (e *pipeEnd) send(b []byte, done <-chan struct{}, nonblock bool) (int, error) {
+	if !e.writer {
+		return 0, linux.EBADF
+	} else if len(b) == 0 {
+		return 0, nil
+	}
+	p := e.pipe
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	nonblock = nonblock || e.nonblock.Load()
+	var n int
+	for n < len(b) {
+		if p.readers == 0 {
+			return n, linux.EPIPE
+		}
+		free := p.size - p.len
+		if free == 0 || len(b) <= PIPE_BUF && free < len(b) {
+			if n > 0 && nonblock {
+				break
+			} else if nonblock {
+				return 0, linux.EAGAIN
+			} else if !p.wait(done) {
+				if n > 0 {
+					break
+				}
+				return 0, linux.EINTR
+			}
+			continue
+		}
+		m := min(len(b)-n, free)
+		p.push(b[n:n+m], e.direct)
+		p.len += m
+		n += m
+		p.changed()
+	}
+	return n, nil
+}
+
+func (e *pipeEnd) Read(b []byte) (int, error) {
+	return e.recv(b, nil, false, false)
+}
+
+func (e *pipeEnd) Write(b []byte) (int, error) {
+	return e.send(b, nil, false)
+}
+
+func (e *pipeEnd) Peek(n int) ([]byte, error) {
+	b := make([]byte, n)
+	n, err := e.recv(b, nil, true, false)
+	return b[:n], err
+}
+
+func (e *pipeEnd) SetNonblock(nonblocking bool) error {
+	e.nonblock.Store(nonblocking)
+	return nil
+}
+
+func (e *pipeEnd) Poll() (uint32, <-chan struct{}) {
+	p := e.pipe
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var events uint32
+	if e.writer {
+		if p.size-p.len >= PIPE_BUF {
+			events |= POLLOUT | POLLWRNORM
+		}
+		if p.readers == 0 {
+			events |= POLLERR
+		}
+	} else {
+		if p.len > 0 {
+			events |= POLLIN | POLLRDNORM
+		}
+		if p.writers == 0 {
+			events |= POLLHUP
+		}
+	}
+	return events, p.wake
+}
+
+func (e *pipeEnd) Stat() (fs.FileInfo, error) {
+	return pipeInfo{e.name, e.mtime}, nil
+}
+
+func (e *pipeEnd) Close() error {
+	if !e.closed.CompareAndSwap(false, true) {
+		return fs.ErrClosed
+	}
+	p := e.pipe
+	p.mu.Lock()
+	if e.writer {
+		p.writers = max(p.writers-1, 0)
+	} else {
+		p.readers = max(p.readers-1, 0)
+	}
+	p.changed()
+	p.mu.Unlock()
+	return nil
+}
+
+func (s pipeIO) Read(b []byte) (int, error) {
+	return s.recv(b, s.done, false, s.nonblock)
+}
+
+func (s pipeIO) Write(b []byte) (int, error) {
+	return s.send(b, s.done, s.nonblock)
+}
+
+func (s pipeIO) Peek(n int) ([]byte, error) {
+	b := make([]byte, n)
+	n, err := s.recv(b, s.done, true, s.nonblock)
+	return b[:n], err
+}
+
+func (info pipeInfo) Name() string {
+	return info.name
+}
+
+func (info pipeInfo) Size() int64 {
+	return 0
+}
+
+func (info pipeInfo) Mode() fs.FileMode {
+	return fs.ModeNamedPipe | 0600
+}
+
+func (info pipeInfo) ModTime() time.Time {
+	return info.mtime
+}
+
+func (info pipeInfo) IsDir() bool {
+	return false
+}
+
+func (info pipeInfo) Sys() any {
+	return nil
+}
@@ -0,0 +1,193 @@
+package kernel
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/emulator"
+)
+
+func (tk *testKernel) pipeFlags(flags uint64) (uint64, uint64) {
+	tk.tb.Helper()
+	fds := tk.alloc(8)
+	if _, errno := tk.call(linux.NR_pipe2, fds, flags); errno != 0 {
+		tk.tb.Fatalf("pipe2 errno = %v", errno)
+	}
+	b := tk.bytes(fds, 8)
+	return uint64(binary.LittleEndian.Uint32(b)), uint64(binary.LittleEndian.Uint32(b[4:]))
+}
+
+func TestPipeNonblock(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_X86_64)
+	r, w := tk.pipeFlags(O_NONBLOCK)
+	buf := tk.alloc(PIPE_BUF * 2)
+	if _, errno := tk.call(linux.NR_read, r, buf, 16); errno != linux.EAGAIN {
+		t.Fatalf("read(empty) errno = %v, want EAGAIN", errno)
+	}
+	if size, errno := tk.call(linux.NR_fcntl, w, F_GETPIPE_SZ); errno != 0 || size != pipeDefSize {
+		t.Fatalf("F_GETPIPE_SZ = %d, errno = %v", size, errno)
+	}
+	if size, errno := tk.call(linux.NR_fcntl, w, F_SETPIPE_SZ, 5000); errno != 0 || size != 2*PAGE_SIZE {
+		t.Fatalf("F_SETPIPE_SZ(5000) = %d, errno = %v", size, errno)
+	}
+	if n, errno := tk.call(linux.NR_write, w, buf, PIPE_BUF+100); errno != 0 || n != PIPE_BUF+100 {
+		t.Fatalf("write = %d, errno = %v", n, errno)
+	}
+	if _, errno := tk.call(linux.NR_write, w, buf, PIPE_BUF); errno != linux.EAGAIN {
+		t.Fatalf("atomic write into a short pipe errno = %v, want EAGAIN", errno)
+	}
+	if n, errno := tk.call(linux.NR_write, w, buf, PIPE_BUF*2); errno != 0 || n != PIPE_BUF-100 {
+		t.Fatalf("partial write = %d, errno = %v, want %d", n, errno, PIPE_BUF-100)
+	}
+	if _, errno := tk.call(linux.NR_write, w, buf, PIPE_BUF*2); errno != linux.EAGAIN {
+		t.Fatalf("write(full) errno = %v, want EAGAIN", errno)
+	}
+	nread := tk.alloc(4)
+	if _, errno := tk.call(linux.NR_ioctl, r, FIONREAD, nread); errno != 0 || !bytes.Equal(tk.bytes(nread, 4), []byte{0, 0x20, 0, 0}) {
+		t.Fatalf("FIONREAD = %v, errno = %v", tk.bytes(nread, 4), errno)
+	}
+	if _, errno := tk.call(linux.NR_fcntl, w, F_SETPIPE_SZ, PAGE_SIZE); errno != linux.EBUSY {
+		t.Fatalf("F_SETPIPE_SZ below contents errno = %v, want EBUSY", errno)
+	}
+	if _, errno := tk.call(linux.NR_fcntl, w, F_SETPIPE_SZ, pipeMaxSize+1); errno != linux.EPERM {
+		t.Fatalf("F_SETPIPE_SZ above the limit errno = %v, want EPERM", errno)
+	}
+	if _, errno := tk.call(linux.NR_ioctl, r, 0x5401, nread); errno != linux.ENOTTY {
+		t.Fatalf("ioctl(TCGETS) errno = %v, want ENOTTY", errno)
+	}
+	file := uint64(tk.create("file", nil))
+	if _, errno := tk.call(linux.NR_fcntl, file, F_GETPIPE_SZ); errno != linux.EBADF {
+		t.Fatalf("F_GETPIPE_SZ(file) errno = %v, want EBADF", errno)
+	}
+
+	tk.call(linux.NR_fcntl, r, 4, 0)
+	if n, errno := tk.call(linux.NR_read, r, buf, PIPE_BUF*2); errno != 0 || n != PIPE_BUF*2 {
+		t.Fatalf("read = %d, errno = %v", n, errno)
+	}
+	reader := tk.task(2)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if n, errno := reader.call(linux.NR_read, r, buf, 16); errno != 0 || n != 0 {
+			t.Errorf("blocking read after hangup = %d, errno = %v", n, errno)
+		}
+	}()
+	select {
+	case <-done:
+		t.Fatal("blocking read returned on an empty pipe")
+	case <-time.After(20 * time.Millisecond):
+	}
+	tk.call(linux.NR_close, w)
+	<-done
+}
+
+func TestPipeBlocking(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	r, w := tk.pipe()
+	reader := tk.task(2)
+	buf := reader.alloc(16)
+	done := make(chan string, 1)
+	go func() {
+		n, _ := reader.call(linux.NR_read, r, buf, 16)
+		done <- string(reader.bytes(buf, int(n)))
+	}()
+	time.Sleep(10 * time.Millisecond)
+	tk.call(linux.NR_write, w, tk.cstring("wake"), 4)
+	if s := <-done; s != "wake" {
+		t.Fatalf("read = %q, want %q", s, "wake")
+	}
+
+	tk.call(linux.NR_fcntl, w, F_SETPIPE_SZ, PAGE_SIZE)
+	data := bytes.Repeat([]byte{'x'}, PAGE_SIZE*3)
+	src := tk.alloc(uint64(len(data)))
+	tk.ctx.ToPointer(src).MemWrite(data)
+	written := make(chan uint64, 1)
+	go func() {
+		n, _ := reader.call(linux.NR_write, w, src, uint64(len(data)))
+		written <- n
+	}()
+	dst := tk.alloc(uint64(len(data)))
+	var total uint64
+	for total < uint64(len(data)) {
+		n, errno := tk.call(linux.NR_read, r, dst+total, uint64(len(data))-total)
+		if errno != 0 {
+			t.Fatalf("read errno = %v", errno)
+		}
+		total += n
+	}
+	if n := <-written; n != uint64(len(data)) || !bytes.Equal(tk.bytes(dst, len(data)), data) {
+		t.Fatalf("blocking write = %d", n)
+	}
+}
+
+func TestPipePacket(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_X86_64)
+	r, w := tk.pipeFlags(O_DIRECT | O_NONBLOCK)
+	for _, s := range []string{"one", "three"} {
+		tk.call(linux.NR_write, w, tk.cstring(s), uint64(len(s)))
+	}
+	buf := tk.alloc(16)
+	for _, tt := range []struct {
+		size uint64
+		want string
+	}{{16, "one"}, {2, "th"}} {
+		if n, errno := tk.call(linux.NR_read, r, buf, tt.size); errno != 0 || string(tk.bytes(buf, int(n))) != tt.want {
+			t.Fatalf("read = %q, errno = %v, want %q", tk.bytes(buf, int(n)), errno, tt.want)
+		}
+	}
+	if _, errno := tk.call(linux.NR_read, r, buf, 16); errno != linux.EAGAIN {
+		t.Fatalf("read after truncated packet errno = %v, want EAGAIN", errno)
+	}
+	if r, _ := tk.call(linux.NR_fcntl, r, 3, 0); r != O_DIRECT|O_NONBLOCK {
+		t.Fatalf("F_GETFL = %#x, want O_DIRECT|O_NONBLOCK", r)
+	}
+}
+
+func TestPipeBroken(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM)
+	r, w := tk.pipe()
+	file, err := tk.dbg.GetFile(int(w))
+	if err != nil {
+		t.Fatal(err)
+	}
+	poll := file.(PollFile)
+	if events, _ := poll.Poll(); events != POLLOUT|POLLWRNORM {
+		t.Fatalf("write end events = %#x", events)
+	}
+	rfile, _ := tk.dbg.GetFile(int(r))
+	events, changed := rfile.(PollFile).Poll()
+	if events != 0 {
+		t.Fatalf("read end events = %#x", events)
+	}
+	tk.call(linux.NR_write, w, tk.cstring("x"), 1)
+	select {
+	case <-changed:
+	default:
+		t.Fatal("write did not signal readiness")
+	}
+	if events, _ := rfile.(PollFile).Poll(); events != POLLIN|POLLRDNORM {
+		t.Fatalf("read end events after write = %#x", events)
+	}
+	statbuf := tk.alloc(256)
+	var st stat3264
+	tk.call(linux.NR_fstat64, r, statbuf)
+	tk.decode(statbuf, &st)
+	if st.st_mode&S_IFMT != S_IFIFO {
+		t.Fatalf("fstat64 mode = %o, want S_IFIFO", st.st_mode)
+	}
+
+	tk.call(linux.NR_rt_sigaction, SIGPIPE, tk.encode(&sigaction{sa_handler: SIG_IGN}), 0, 8)
+	tk.call(linux.NR_close, r)
+	if events, _ := poll.Poll(); events&POLLERR == 0 {
+		t.Fatalf("write end events after close = %#x, want POLLERR", events)
+	}
+	if _, errno := tk.call(linux.NR_write, w, tk.cstring("x"), 1); errno != linux.EPIPE {
+		t.Fatalf("write(broken pipe) errno = %v, want EPIPE", errno)
+	}
+	if tk.ExitStatus() != nil {
+		t.Fatal("ignored SIGPIPE terminated the process")
+	}
+}
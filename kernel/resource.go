@@ -12,11 +12,16 @@ type rlimit struct {
 type resource struct {
 }
 
+func (rl *rlimit) ctype(c *ccodec) {
+	cULong(c, &rl.rlim_cur)
+	cULong(c, &rl.rlim_max)
+}
+
 func (r *resource) getrlimit(ctx linux.Context, resource int32, rlim emuptr) int32 {
 	switch resource {
 	case RLIMIT_STACK:
 		dbg := ctx.Debugger()
-		dbg.MemWrite(rlim, rlimit{
+		memWrite(ctx, rlim, &rlimit{
 			rlim_cur: ulong_t(dbg.StackSize()),
 			rlim_max: ulong_t(dbg.StackSize()),
 		})
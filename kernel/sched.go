@@ -2,6 +2,7 @@ package kernel
 
 import (
 	"sync"
+	"sync/atomic"
 	"unsafe"
 
 	linux "github.com/wnxd/microdbg-linux"
@@ -12,15 +13,33 @@ import (
 	emu_x86 "github.com/wnxd/microdbg/emulator/x86"
 )
 
+type cloneCall struct {
+	fn, arg emuptr
+}
+
 type sched struct {
-	tasks sync.Map
+	futex    *futex
+	tasks    sync.Map
+	clearTID sync.Map
+	groups   sync.Map
+	status   atomic.Pointer[linux.ExitStatus]
+	mu       sync.Mutex
+	leader   int32
+	exited   bool
+}
+
+func (call *cloneCall) ctype(c *ccodec) {
+	cULong(c, &call.fn)
+	cULong(c, &call.arg)
 }
 
 func (s *sched) clone(ctx linux.Context, flags int32, child_stack, parent_tid, tls, child_tid emuptr) int32 {
 	const (
-		CLONE_VM     = 0x00000100
-		CLONE_VFORK  = 0x00004000
-		CLONE_SETTLS = 0x00080000
+		CLONE_VM             = 0x00000100
+		CLONE_VFORK          = 0x00004000
+		CLONE_THREAD         = 0x00010000
+		CLONE_SETTLS         = 0x00080000
+		CLONE_CHILD_CLEARTID = 0x00200000
 	)
 
 	task, err := ctx.TaskFork()
@@ -34,9 +53,9 @@ func (s *sched) clone(ctx linux.Context, flags int32, child_stack, parent_tid, t
 		taskCtx.RetWrite(nil)
 	} else {
 		taskCtx.RegWrite(taskCtx.SP(), child_stack)
-		var call struct{ fn, arg uintptr }
-		dbg.MemExtract(child_stack, &call)
-		err = dbg.CallTaskOf(task, uint64(call.fn))
+		var call cloneCall
+		memExtract(ctx, child_stack, &call)
+		err = dbg.CallTaskOf(task, call.fn)
 		if err != nil {
 			task.Close()
 			ctx.SetErrno(linux.EAGAIN)
@@ -56,30 +75,157 @@ func (s *sched) clone(ctx linux.Context, flags int32, child_stack, parent_tid, t
 			taskCtx.RegWrite(emu_x86.X86_REG_FS, tls)
 		}
 	}
+	pid := int32(task.ID())
+	s.mu.Lock()
+	if s.leader == 0 {
+		s.leader = s.tgid(ctx.TaskID())
+	}
+	s.tasks.Store(pid, task)
+	s.mu.Unlock()
+	if flags&CLONE_THREAD != 0 {
+		s.groups.Store(int(pid), s.tgid(ctx.TaskID()))
+	}
+	if child_tid != emunullptr {
+		ctx.ToPointer(child_tid).MemWritePtr(4, unsafe.Pointer(&pid))
+		if flags&CLONE_CHILD_CLEARTID != 0 {
+			s.clearTID.Store(int(pid), child_tid)
+		}
+	}
 	err = task.Run()
 	if err != nil {
+		s.tasks.Delete(pid)
+		s.groups.Delete(int(pid))
+		s.clearTID.Delete(int(pid))
 		task.Close()
 		ctx.SetErrno(linux.EAGAIN)
 		return -1
 	}
-	pid := int32(task.ID())
-	if child_tid != emunullptr {
-		ctx.ToPointer(child_tid).MemWritePtr(4, unsafe.Pointer(&pid))
-	}
-	if flags&CLONE_VFORK != 0 {
+	reap := func() {
 		<-task.Done()
+		s.tasks.Delete(pid)
+		s.exitTask(dbg, pid)
 		task.Close()
+	}
+	if flags&CLONE_VFORK != 0 {
+		reap()
 	} else {
-		s.tasks.Store(pid, task)
-		go func() {
-			<-task.Done()
-			s.tasks.Delete(pid)
-			task.Close()
-		}()
+		go reap()
 	}
 	return pid
 }
 
+func (s *sched) exitTask(dbg debugger.Debugger, pid int32) {
+	s.groups.Delete(int(pid))
+	if k, ok := dbg.(linux.Kernel); ok {
+		k.SetTaskErrno(int(pid), 0)
+	}
+	if addr, ok := s.clearTID.LoadAndDelete(int(pid)); ok {
+		var zero uint32
+		err := dbg.ToPointer(addr.(emuptr)).MemWritePtr(4, unsafe.Pointer(&zero))
+		if err == nil {
+			s.futex.wake(addr.(emuptr), zero, 1)
+		}
+	}
+}
+
+func (s *sched) tgid(tid int) int32 {
+	if group, ok := s.groups.Load(tid); ok {
+		return group.(int32)
+	}
+	return int32(tid)
+}
+
+func (s *sched) set_tid_address(ctx linux.Context, tidptr emuptr) pid_t {
+	tid := ctx.TaskID()
+	if tidptr == emunullptr {
+		s.clearTID.Delete(tid)
+	} else {
+		s.clearTID.Store(tid, tidptr)
+	}
+	return pid_t(tid)
+}
+
+func (s *sched) set_tls(ctx linux.Context, tls emuptr) int32 {
+	if ctx.Debugger().Arch() != emulator.ARCH_ARM {
+		ctx.SetErrno(linux.ENOSYS)
+		return -1
+	}
+	ctx.RegWrite(emu_arm.ARM_REG_C13_C0_3, tls)
+	return 0
+}
+
+func (s *sched) exit(ctx linux.Context, code int32) int32 {
+	status := &linux.ExitStatus{TaskID: ctx.TaskID(), Code: int(code & 0xff)}
+	dbg := ctx.Debugger()
+	task, ok := taskOf(ctx)
+	last := s.leave(int32(ctx.TaskID()))
+	if last || !ok {
+		s.status.CompareAndSwap(nil, status)
+	}
+	s.exitTask(dbg, int32(ctx.TaskID()))
+	if ok {
+		task.CancelCause(status)
+	}
+	if last || !ok {
+		dbg.Emulator().Stop()
+	}
+	return 0
+}
+
+func (s *sched) exit_group(ctx linux.Context, code int32) int32 {
+	s.kill(ctx, &linux.ExitStatus{Code: int(code & 0xff), Group: true})
+	return 0
+}
+
+func (s *sched) kill(ctx linux.Context, status *linux.ExitStatus) {
+	status.TaskID = ctx.TaskID()
+	if !s.status.CompareAndSwap(nil, status) {
+		status = s.status.Load()
+	}
+	dbg := ctx.Debugger()
+	for pid, value := range s.tasks.Range {
+		s.exitTask(dbg, pid.(int32))
+		value.(debugger.Task).CancelCause(status)
+	}
+	s.exitTask(dbg, int32(ctx.TaskID()))
+	if task, ok := taskOf(ctx); ok {
+		task.CancelCause(status)
+	}
+	dbg.Emulator().Stop()
+}
+
+// leave reports whether pid was the last running task. The initial task is
+// never stored in tasks; leader holds its pid once the first clone happens,
+// and before that the caller is the only task.
+func (s *sched) leave(pid int32) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks.Delete(pid)
+	if s.leader == 0 || pid == s.leader {
+		s.exited = true
+	}
+	if !s.exited {
+		return false
+	}
+	for range s.tasks.Range {
+		return false
+	}
+	return true
+}
+
+func taskOf(ctx debugger.Context) (debugger.Task, bool) {
+	for {
+		switch v := ctx.(type) {
+		case debugger.Task:
+			return v, true
+		case interface{ Unwrap() debugger.Context }:
+			ctx = v.Unwrap()
+		default:
+			return nil, false
+		}
+	}
+}
+
 func (s *sched) execve(ctx linux.Context, filename, argv, envp emuptr) int32 {
 	ctx.SetErrno(linux.ENOSYS)
 	return -1
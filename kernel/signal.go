@@ -6,19 +6,27 @@ import (
 	linux "github.com/wnxd/microdbg-linux"
 )
 
-type sigset_t ulong_t
+const (
+	SIG_DFL = 0
+	SIG_IGN = 1
+
+	SIGPIPE = 13
+)
+
+type sigset_t uint64
 
 type sigaction struct {
-	sa_handler  uintptr
-	sa_flags    int32
+	sa_handler  emuptr
+	sa_flags    ulong_t
+	sa_restorer emuptr
 	sa_mask     sigset_t
-	sa_restorer uintptr
 }
 
 type signal struct {
 	set   sigset_t
 	rw    sync.RWMutex
 	table map[int32]*sigaction
+	sched *sched
 }
 
 type siginfo_t struct {
@@ -39,6 +47,33 @@ var (
 	_ = siginfo_t{}._si_pad
 )
 
+func (act *sigaction) ctype(c *ccodec) {
+	cULong(c, &act.sa_handler)
+	cULong(c, &act.sa_flags)
+	cULong(c, &act.sa_restorer)
+	act.sa_mask.ctype(c)
+}
+
+func (si *siginfo_t) ctype(c *ccodec) {
+	cInt32(c, &si.si_signo)
+	cInt32(c, &si.si_errno)
+	cInt32(c, &si.si_code)
+	for i := range si._si_pad {
+		cInt32(c, &si._si_pad[i])
+	}
+}
+
+func (set *sigset_t) ctype(c *ccodec) {
+	if c.model.long == 8 {
+		cUint64(c, set)
+		return
+	}
+	lo, hi := uint32(*set), uint32(*set>>32)
+	cUint32(c, &lo)
+	cUint32(c, &hi)
+	*set = sigset_t(hi)<<32 | sigset_t(lo)
+}
+
 func (set *sigset_t) sigemptyset() {
 	*set = 0
 }
@@ -63,10 +98,28 @@ func (s *signal) dtor() {
 	s.table = nil
 }
 
+func (s *signal) raise(ctx linux.Context, sig int32) {
+	s.rw.RLock()
+	act, ok := s.table[sig]
+	blocked := s.set&(1<<uint(sig-1)) != 0
+	s.rw.RUnlock()
+	if blocked || ok && act.sa_handler != SIG_DFL {
+		return
+	}
+	s.sched.kill(ctx, &linux.ExitStatus{Signal: int(sig), Group: true})
+}
+
+func (s *signal) swapMask(set sigset_t) sigset_t {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+	old := s.set
+	s.set = set
+	return old
+}
+
 func (s *signal) rt_sigaction(ctx linux.Context, signal int32, act, oldact emuptr, size size_t) int32 {
-	dbg := ctx.Debugger()
 	action := new(sigaction)
-	err := dbg.MemExtract(act, action)
+	err := memExtract(ctx, act, action)
 	if err != nil {
 		ctx.SetErrno(linux.EFAULT)
 		return -1
@@ -78,7 +131,7 @@ func (s *signal) rt_sigaction(ctx linux.Context, signal int32, act, oldact emupt
 	if oldact == emunullptr || !ok {
 		return 0
 	}
-	dbg.MemWrite(oldact, *old)
+	memWrite(ctx, oldact, old)
 	return 0
 }
 
@@ -89,16 +142,15 @@ func (s *signal) rt_sigprocmask(ctx linux.Context, how int32, set, oldset emuptr
 		SIG_SETMASK
 	)
 
-	dbg := ctx.Debugger()
 	if oldset != emunullptr {
-		_, err := dbg.MemWrite(oldset, s.set)
+		err := memWrite(ctx, oldset, &s.set)
 		if err != nil {
 			ctx.SetErrno(linux.EFAULT)
 			return -1
 		}
 	}
 	var value sigset_t
-	err := dbg.MemExtract(set, &value)
+	err := memExtract(ctx, set, &value)
 	if err != nil {
 		ctx.SetErrno(linux.EFAULT)
 		return -1
@@ -119,13 +171,10 @@ func (s *signal) rt_sigprocmask(ctx linux.Context, how int32, set, oldset emuptr
 
 func (s *signal) rt_tgsigqueueinfo(ctx linux.Context, tgid, tid, sig int32, info emuptr) int32 {
 	var si siginfo_t
-	dbg := ctx.Debugger()
-	err := dbg.MemExtract(info, &si)
+	err := memExtract(ctx, info, &si)
 	if err != nil {
 		ctx.SetErrno(linux.EFAULT)
 		return -1
 	}
 	panic("rt_tgsigqueueinfo")
-	ctx.SetErrno(linux.ENOSYS)
-	return -1
 }
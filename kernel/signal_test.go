@@ -0,0 +1,86 @@
+package kernel
+
+import (
+	"testing"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/emulator"
+)
+
+func TestRtSigaction(t *testing.T) {
+	for _, arch := range []emulator.Arch{emulator.ARCH_ARM, emulator.ARCH_ARM64, emulator.ARCH_X86, emulator.ARCH_X86_64} {
+		tk := newTestKernel(t, arch)
+		first := &sigaction{sa_handler: 0x1000, sa_flags: 0x4000000, sa_restorer: 0x2000, sa_mask: 1 << 1}
+		second := &sigaction{sa_handler: 0x3000}
+		if _, errno := tk.call(linux.NR_rt_sigaction, 2, tk.encode(first), 0, 8); errno != 0 {
+			t.Fatalf("%v: rt_sigaction errno = %d", arch, errno)
+		}
+		oldact := tk.alloc(32)
+		if _, errno := tk.call(linux.NR_rt_sigaction, 2, tk.encode(second), oldact, 8); errno != 0 {
+			t.Fatalf("%v: rt_sigaction errno = %d", arch, errno)
+		}
+		var old sigaction
+		tk.decode(oldact, &old)
+		if old != *first {
+			t.Fatalf("%v: oldact = %+v, want %+v", arch, old, *first)
+		}
+		if _, errno := tk.call(linux.NR_rt_sigaction, 2, 0x1000, 0, 8); errno != linux.EFAULT {
+			t.Fatalf("%v: rt_sigaction(bad act) errno = %d, want EFAULT", arch, errno)
+		}
+	}
+}
+
+func TestRtSigprocmask(t *testing.T) {
+	const (
+		SIG_BLOCK = iota + 1
+		SIG_UNBLOCK
+		SIG_SETMASK
+	)
+
+	tk := newTestKernel(t, emulator.ARCH_ARM)
+	tests := []struct {
+		name  string
+		how   uint64
+		set   sigset_t
+		old   sigset_t
+		errno linux.Errno
+	}{
+		{"block", SIG_BLOCK, 0b0110, 0, 0},
+		{"block more", SIG_BLOCK, 1 << 40, 0b0110, 0},
+		{"unblock", SIG_UNBLOCK, 0b0010, 1<<40 | 0b0110, 0},
+		{"setmask", SIG_SETMASK, 0b1000, 1<<40 | 0b0100, 0},
+		{"invalid how", 0, 0, 0b1000, linux.EINVAL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := tt.set
+			oldset := tk.alloc(8)
+			_, errno := tk.call(linux.NR_rt_sigprocmask, tt.how, tk.encode(&set), oldset, 8)
+			if errno != tt.errno {
+				t.Fatalf("rt_sigprocmask errno = %d, want %d", errno, tt.errno)
+			}
+			var old sigset_t
+			tk.decode(oldset, &old)
+			if old != tt.old {
+				t.Fatalf("oldset = %#x, want %#x", old, tt.old)
+			}
+		})
+	}
+}
+
+func TestSigactionLayout(t *testing.T) {
+	tests := []struct {
+		arch emulator.Arch
+		size int
+	}{
+		{emulator.ARCH_ARM, 20},
+		{emulator.ARCH_X86, 20},
+		{emulator.ARCH_ARM64, 32},
+		{emulator.ARCH_X86_64, 32},
+	}
+	for _, tt := range tests {
+		if size, _ := sizeOf(modelOf(tt.arch), new(sigaction)); size != tt.size {
+			t.Errorf("%v: sizeof(struct sigaction) = %d, want %d", tt.arch, size, tt.size)
+		}
+	}
+}
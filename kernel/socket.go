@@ -64,6 +64,7 @@ const (
 )
 
 type network struct {
+	fcntl *fcntl
 }
 
 func (n *network) socket(ctx linux.Context, domain, typ, protocol int32) int32 {
@@ -94,9 +95,12 @@ func (n *network) socket(ctx linux.Context, domain, typ, protocol int32) int32 {
 	dbg := ctx.Debugger()
 	s, err := dbg.NewSocket(network)
 	if err != nil {
-		ctx.SetErrno(linux.EACCES)
+		ctx.SetErrno(linux.ToErrno(err))
 		return -1
 	}
 	fd := dbg.CreateFileDescriptor(s)
+	n.fcntl.rw.Lock()
+	n.fcntl.install(fd, s, "")
+	n.fcntl.rw.Unlock()
 	return int32(fd)
 }
@@ -0,0 +1,280 @@
+package kernel
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+const (
+	SPLICE_F_MOVE     = 0x1
+	SPLICE_F_NONBLOCK = 0x2
+	SPLICE_F_MORE     = 0x4
+	SPLICE_F_GIFT     = 0x8
+)
+
+const spliceChunk = 0x10000
+
+type peekFile interface {
+	filesystem.File
+	Peek(n int) ([]byte, error)
+}
+
+type fileOff struct {
+	off  int64
+	wide bool
+}
+
+type spliceEnd struct {
+	file filesystem.File
+	addr emuptr
+	off  fileOff
+}
+
+func (o *fileOff) ctype(c *ccodec) {
+	if o.wide {
+		cInt64(c, &o.off)
+		return
+	}
+	off := off_t(o.off)
+	cLong(c, &off)
+	o.off = int64(off)
+}
+
+func (f *fcntl) sendfile(ctx linux.Context, outFd, inFd uint32, offset emuptr, count size_t, wide bool) ssize_t {
+	in, out, errno := f.spliceFiles(ctx, inFd, offset, outFd, emunullptr, wide)
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	return f.transfer(ctx, in, out, count, false)
+}
+
+func (f *fcntl) copy_file_range(ctx linux.Context, inFd uint32, offIn emuptr, outFd uint32, offOut emuptr, count size_t, flags uint32) ssize_t {
+	if flags != 0 {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	in, out, errno := f.spliceFiles(ctx, inFd, offIn, outFd, offOut, true)
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	inInfo, errno := regularInfo(in.file)
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	outInfo, errno := regularInfo(out.file)
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	if os.SameFile(inInfo, outInfo) {
+		inPos, outPos := in.pos(), out.pos()
+		if inPos < 0 || outPos < 0 || inPos < outPos+int64(count) && outPos < inPos+int64(count) {
+			ctx.SetErrno(linux.EINVAL)
+			return -1
+		}
+	}
+	return f.transfer(ctx, in, out, count, false)
+}
+
+func (f *fcntl) splice(ctx linux.Context, inFd uint32, offIn emuptr, outFd uint32, offOut emuptr, count size_t, flags uint32) ssize_t {
+	if flags&^(SPLICE_F_MOVE|SPLICE_F_NONBLOCK|SPLICE_F_MORE|SPLICE_F_GIFT) != 0 {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	in, out, errno := f.spliceFiles(ctx, inFd, offIn, outFd, offOut, true)
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	if flags&SPLICE_F_NONBLOCK != 0 {
+		in.nonblock()
+		out.nonblock()
+	}
+	inPipe, outPipe := isPipe(in.file), isPipe(out.file)
+	switch {
+	case !inPipe && !outPipe:
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	case inPipe && offIn != emunullptr, outPipe && offOut != emunullptr:
+		ctx.SetErrno(linux.ESPIPE)
+		return -1
+	}
+	return f.transfer(ctx, in, out, count, false)
+}
+
+func (f *fcntl) tee(ctx linux.Context, inFd, outFd uint32, count size_t, flags uint32) ssize_t {
+	if flags&^(SPLICE_F_MOVE|SPLICE_F_NONBLOCK|SPLICE_F_MORE|SPLICE_F_GIFT) != 0 {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	in, out, errno := f.spliceFiles(ctx, inFd, emunullptr, outFd, emunullptr, true)
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	} else if !isPipe(in.file) || !isPipe(out.file) || inFd == outFd {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	} else if _, ok := in.file.(peekFile); !ok {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	} else if flags&SPLICE_F_NONBLOCK != 0 {
+		in.nonblock()
+		out.nonblock()
+	}
+	return f.transfer(ctx, in, out, count, true)
+}
+
+func (f *fcntl) spliceFiles(ctx linux.Context, inFd uint32, offIn emuptr, outFd uint32, offOut emuptr, wide bool) (in, out spliceEnd, errno linux.Errno) {
+	dbg := ctx.Debugger()
+	var err error
+	in.file, err = f.getFile(dbg, int(inFd))
+	if err != nil {
+		return in, out, linux.EBADF
+	}
+	out.file, err = f.getFile(dbg, int(outFd))
+	if err != nil {
+		return in, out, linux.EBADF
+	}
+	in.file = f.stream(ctx, int(inFd), in.file)
+	out.file = f.stream(ctx, int(outFd), out.file)
+	in.addr, in.off.wide = offIn, wide
+	out.addr, out.off.wide = offOut, wide
+	for _, end := range []*spliceEnd{&in, &out} {
+		if end.addr == emunullptr {
+			end.off.off = -1
+		} else if memExtract(ctx, end.addr, &end.off) != nil {
+			return in, out, linux.EFAULT
+		} else if end.off.off < 0 {
+			return in, out, linux.EINVAL
+		}
+	}
+	return in, out, 0
+}
+
+func (f *fcntl) transfer(ctx linux.Context, in, out spliceEnd, count size_t, peek bool) ssize_t {
+	count = min(count, MAX_RW_COUNT)
+	var r io.Reader
+	var pipe peekFile
+	if peek {
+		b, err := in.file.(peekFile).Peek(int(min(count, spliceChunk)))
+		if err != nil && err != io.EOF {
+			ctx.SetErrno(linux.ToErrno(err))
+			return -1
+		}
+		r = bytes.NewReader(b)
+	} else if in.off.off >= 0 {
+		ra, ok := in.file.(io.ReaderAt)
+		if !ok {
+			ctx.SetErrno(linux.ESPIPE)
+			return -1
+		}
+		r = io.NewSectionReader(ra, in.off.off, math.MaxInt64-in.off.off)
+	} else if r, _ = in.file.(io.Reader); r == nil {
+		ctx.SetErrno(linux.EBADF)
+		return -1
+	} else {
+		pipe, _ = in.file.(peekFile)
+	}
+	var w io.Writer
+	if out.off.off >= 0 {
+		wa, ok := out.file.(io.WriterAt)
+		if !ok {
+			ctx.SetErrno(linux.ESPIPE)
+			return -1
+		}
+		w = io.NewOffsetWriter(wa, out.off.off)
+	} else if w, _ = out.file.(io.Writer); w == nil {
+		ctx.SetErrno(linux.EBADF)
+		return -1
+	}
+	_, regular := in.file.(io.ReaderAt)
+	buf := make([]byte, min(count, spliceChunk))
+	var n size_t
+	var err error
+	for n < count {
+		b := buf[:min(size_t(len(buf)), count-n)]
+		var m, wn int
+		if pipe != nil {
+			var data []byte
+			data, err = pipe.Peek(len(b))
+			m = copy(b, data)
+		} else {
+			m, err = r.Read(b)
+		}
+		if m > 0 {
+			wn, err = w.Write(b[:m])
+			n += size_t(wn)
+			if pipe != nil {
+				r.Read(b[:wn])
+			} else if seek, ok := in.file.(io.Seeker); ok && wn < m && in.off.off < 0 {
+				seek.Seek(int64(wn-m), io.SeekCurrent)
+			}
+		}
+		if err != nil || m < len(b) || wn < m || !regular {
+			break
+		}
+	}
+	if errors.Is(err, linux.EPIPE) {
+		f.signal.raise(ctx, SIGPIPE)
+	}
+	if err != nil && err != io.EOF && n == 0 {
+		ctx.SetErrno(linux.ToErrno(err))
+		return -1
+	}
+	for _, end := range []*spliceEnd{&in, &out} {
+		if end.addr == emunullptr || peek {
+			continue
+		}
+		end.off.off += int64(n)
+		if memWrite(ctx, end.addr, &end.off) != nil {
+			ctx.SetErrno(linux.EFAULT)
+			return -1
+		}
+	}
+	return ssize_t(n)
+}
+
+func (end *spliceEnd) nonblock() {
+	if s, ok := end.file.(pipeIO); ok {
+		s.nonblock = true
+		end.file = s
+	}
+}
+
+func (end *spliceEnd) pos() int64 {
+	if end.off.off >= 0 {
+		return end.off.off
+	} else if seek, ok := end.file.(io.Seeker); ok {
+		if pos, err := seek.Seek(0, io.SeekCurrent); err == nil {
+			return pos
+		}
+	}
+	return -1
+}
+
+func regularInfo(file filesystem.File) (fs.FileInfo, linux.Errno) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, linux.ToErrno(err)
+	} else if info.IsDir() {
+		return nil, linux.EISDIR
+	} else if !info.Mode().IsRegular() {
+		return nil, linux.EINVAL
+	}
+	return info, 0
+}
+
+func isPipe(file filesystem.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&fs.ModeNamedPipe != 0
+}
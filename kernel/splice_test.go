@@ -0,0 +1,200 @@
+package kernel
+
+import (
+	"bytes"
+	"testing"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/emulator"
+)
+
+func (tk *testKernel) pipe() (uint64, uint64) {
+	tk.tb.Helper()
+	return tk.pipeFlags(0)
+}
+
+func (tk *testKernel) loff(off int64) emuptr {
+	tk.tb.Helper()
+	return tk.encode(&fileOff{off: off, wide: true})
+}
+
+func (tk *testKernel) readAt(addr emuptr) int64 {
+	tk.tb.Helper()
+	off := fileOff{wide: true}
+	tk.decode(addr, &off)
+	return off.off
+}
+
+func TestSendfile(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM)
+	in := uint64(tk.create("in", []byte("hello, world")))
+	out := uint64(tk.create("out", nil))
+	tk.call(linux.NR_lseek, in, 0, 0)
+	if n, errno := tk.call(linux.NR_sendfile, out, in, 0, 5); errno != 0 || n != 5 {
+		t.Fatalf("sendfile = %d, errno = %v", n, errno)
+	}
+	if pos, _ := tk.call(linux.NR_lseek, in, 0, 1); pos != 5 {
+		t.Fatalf("sendfile(NULL offset) left in_fd at %d, want 5", pos)
+	}
+	off := tk.encode(&fileOff{off: 7})
+	if n, errno := tk.call(linux.NR_sendfile, out, in, off, 100); errno != 0 || n != 5 {
+		t.Fatalf("sendfile(offset) = %d, errno = %v", n, errno)
+	}
+	var got fileOff
+	tk.decode(off, &got)
+	if pos, _ := tk.call(linux.NR_lseek, in, 0, 1); got.off != 12 || pos != 5 {
+		t.Fatalf("sendfile(offset) *offset = %d, in_fd at %d", got.off, pos)
+	}
+	if s := tk.readFile("out"); s != "helloworld" {
+		t.Fatalf("out = %q, want %q", s, "helloworld")
+	}
+	off64 := tk.loff(0)
+	r, w := tk.pipe()
+	if n, errno := tk.call(linux.NR_sendfile64, w, in, off64, 5); errno != 0 || n != 5 || tk.readAt(off64) != 5 {
+		t.Fatalf("sendfile64 to pipe = %d, errno = %v", n, errno)
+	}
+	buf := tk.alloc(16)
+	if n, _ := tk.call(linux.NR_read, r, buf, 16); string(tk.bytes(buf, int(n))) != "hello" {
+		t.Fatalf("pipe read = %q", tk.bytes(buf, int(n)))
+	}
+	if _, errno := tk.call(linux.NR_sendfile, out, 1000, 0, 5); errno != linux.EBADF {
+		t.Fatalf("sendfile(bad fd) errno = %v, want EBADF", errno)
+	}
+}
+
+func TestCopyFileRange(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	in := uint64(tk.create("in", []byte("0123456789")))
+	out := uint64(tk.create("out", []byte("abcdefghij")))
+	offIn, offOut := tk.loff(2), tk.loff(4)
+	if n, errno := tk.call(linux.NR_copy_file_range, in, offIn, out, offOut, 3, 0); errno != 0 || n != 3 {
+		t.Fatalf("copy_file_range = %d, errno = %v", n, errno)
+	}
+	if s := tk.readFile("out"); s != "abcd234hij" || tk.readAt(offIn) != 5 || tk.readAt(offOut) != 7 {
+		t.Fatalf("out = %q, off_in = %d, off_out = %d", s, tk.readAt(offIn), tk.readAt(offOut))
+	}
+	if n, errno := tk.call(linux.NR_copy_file_range, in, tk.loff(8), out, tk.loff(0), 10, 0); errno != 0 || n != 2 {
+		t.Fatalf("copy_file_range past EOF = %d, errno = %v", n, errno)
+	}
+	tk.call(linux.NR_mkdirat, uint64(testAT_FDCWD), tk.cstring("dir"), 0755)
+	dir, _ := tk.call(linux.NR_openat, uint64(testAT_FDCWD), tk.cstring("dir"), 0, 0)
+	r, _ := tk.pipe()
+	tests := []struct {
+		name  string
+		args  []uint64
+		errno linux.Errno
+	}{
+		{"flags", []uint64{in, 0, out, 0, 1, 1}, linux.EINVAL},
+		{"overlap", []uint64{in, tk.loff(0), in, tk.loff(2), 4, 0}, linux.EINVAL},
+		{"negative", []uint64{in, tk.loff(-1), out, 0, 4, 0}, linux.EINVAL},
+		{"fault", []uint64{in, 0x1000, out, 0, 4, 0}, linux.EFAULT},
+		{"dir", []uint64{dir, 0, out, 0, 4, 0}, linux.EISDIR},
+		{"pipe", []uint64{r, 0, out, 0, 4, 0}, linux.EINVAL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, errno := tk.call(linux.NR_copy_file_range, tt.args...); errno != tt.errno {
+				t.Fatalf("errno = %v, want %v", errno, tt.errno)
+			}
+		})
+	}
+}
+
+func TestSplice(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	in := uint64(tk.create("in", []byte("spliced data")))
+	out := uint64(tk.create("out", nil))
+	r, w := tk.pipe()
+	off := tk.loff(8)
+	if n, errno := tk.call(linux.NR_splice, in, off, w, 0, 4, 0); errno != 0 || n != 4 || tk.readAt(off) != 12 {
+		t.Fatalf("splice file->pipe = %d, errno = %v", n, errno)
+	}
+	if n, errno := tk.call(linux.NR_splice, r, 0, out, 0, 64, 0); errno != 0 || n != 4 || tk.readFile("out") != "data" {
+		t.Fatalf("splice pipe->file = %d, errno = %v, out = %q", n, errno, tk.readFile("out"))
+	}
+	if _, errno := tk.call(linux.NR_splice, in, 0, out, 0, 4, 0); errno != linux.EINVAL {
+		t.Fatalf("splice file->file errno = %v, want EINVAL", errno)
+	}
+	if _, errno := tk.call(linux.NR_splice, in, 0, w, tk.loff(0), 4, 0); errno != linux.ESPIPE {
+		t.Fatalf("splice with pipe offset errno = %v, want ESPIPE", errno)
+	}
+	r2, w2 := tk.pipe()
+	tk.call(linux.NR_write, w2, tk.cstring("teed"), 4)
+	if n, errno := tk.call(linux.NR_tee, r2, w, 64, 0); errno != 0 || n != 4 {
+		t.Fatalf("tee = %d, errno = %v", n, errno)
+	}
+	buf := tk.alloc(16)
+	for _, fd := range []uint64{r2, r} {
+		if n, _ := tk.call(linux.NR_read, fd, buf, 16); string(tk.bytes(buf, int(n))) != "teed" {
+			t.Fatalf("read(%d) after tee = %q, want %q", fd, tk.bytes(buf, int(n)), "teed")
+		}
+	}
+	if _, errno := tk.call(linux.NR_tee, r2, r2, 4, 0); errno != linux.EINVAL {
+		t.Fatalf("tee(same pipe) errno = %v, want EINVAL", errno)
+	}
+	if _, errno := tk.call(linux.NR_tee, in, w2, 4, 0); errno != linux.EINVAL {
+		t.Fatalf("tee(file) errno = %v, want EINVAL", errno)
+	}
+}
+
+func TestSpliceShortWrite(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	data := make([]byte, 5000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	in := uint64(tk.create("in", data))
+	tk.call(linux.NR_lseek, in, 0, 0)
+	r, w := tk.pipeFlags(O_NONBLOCK)
+	fill := tk.alloc(pipeDefSize)
+	if n, errno := tk.call(linux.NR_write, w, fill, pipeDefSize-10); errno != 0 || n != pipeDefSize-10 {
+		t.Fatalf("fill = %d, errno = %v", n, errno)
+	}
+	if n, errno := tk.call(linux.NR_sendfile, w, in, 0, 5000); errno != 0 || n != 10 {
+		t.Fatalf("sendfile into full pipe = %d, errno = %v", n, errno)
+	}
+	if pos, _ := tk.call(linux.NR_lseek, in, 0, 1); pos != 10 {
+		t.Fatalf("in_fd at %d after short write, want 10", pos)
+	}
+	src, dst := tk.pipe()
+	tk.call(linux.NR_write, dst, tk.cstring(string(data)), 5000)
+	buf := tk.alloc(5000)
+	tk.call(linux.NR_read, r, buf, 20)
+	if n, errno := tk.call(linux.NR_splice, src, 0, w, 0, 5000, 0); errno != 0 || n != 20 {
+		t.Fatalf("splice pipe->full pipe = %d, errno = %v", n, errno)
+	}
+	if n, _ := tk.call(linux.NR_read, src, buf, 5000); n != 4980 || !bytes.Equal(tk.bytes(buf, int(n)), data[20:]) {
+		t.Fatalf("source pipe kept %d bytes, want the 4980 unwritten ones", n)
+	}
+}
+
+func TestSpliceFullPipe(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	out := uint64(tk.create("out", nil))
+	r, w := tk.pipe()
+	fill := tk.alloc(pipeDefSize)
+	if n, errno := tk.call(linux.NR_write, w, fill, pipeDefSize); errno != 0 || n != pipeDefSize {
+		t.Fatalf("fill = %d, errno = %v", n, errno)
+	}
+	if n, errno := tk.call(linux.NR_splice, r, 0, out, 0, 2*pipeDefSize, 0); errno != 0 || n != pipeDefSize {
+		t.Fatalf("splice full pipe->file = %d, errno = %v", n, errno)
+	}
+	r2, w2 := tk.pipe()
+	if _, errno := tk.call(linux.NR_splice, r, 0, out, 0, 16, SPLICE_F_NONBLOCK); errno != linux.EAGAIN {
+		t.Fatalf("splice(SPLICE_F_NONBLOCK) from empty pipe errno = %v, want EAGAIN", errno)
+	}
+	if _, errno := tk.call(linux.NR_tee, r, w2, 16, SPLICE_F_NONBLOCK); errno != linux.EAGAIN {
+		t.Fatalf("tee(SPLICE_F_NONBLOCK) from empty pipe errno = %v, want EAGAIN", errno)
+	}
+	tk.call(linux.NR_write, w2, fill, pipeDefSize)
+	tk.call(linux.NR_write, w, tk.cstring("more"), 4)
+	if _, errno := tk.call(linux.NR_splice, r, 0, w2, 0, 4, SPLICE_F_NONBLOCK); errno != linux.EAGAIN {
+		t.Fatalf("splice(SPLICE_F_NONBLOCK) into full pipe errno = %v, want EAGAIN", errno)
+	}
+	if n, _ := tk.call(linux.NR_read, r2, fill, pipeDefSize); n != pipeDefSize {
+		t.Fatalf("drain = %d, want %d", n, pipeDefSize)
+	}
+	if n, errno := tk.call(linux.NR_splice, r, 0, w2, 0, 16, SPLICE_F_NONBLOCK); errno != 0 || n != 4 {
+		t.Fatalf("splice(SPLICE_F_NONBLOCK) = %d, errno = %v", n, errno)
+	}
+}
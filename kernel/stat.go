@@ -0,0 +1,435 @@
+package kernel
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/fs"
+	"path"
+	"sync/atomic"
+	"time"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+const (
+	AT_SYMLINK_NOFOLLOW = 0x100
+	AT_NO_AUTOMOUNT     = 0x800
+	AT_EMPTY_PATH       = 0x1000
+	AT_STATX_SYNC_TYPE  = 0x6000
+
+	S_IFMT  = 0xF000
+	S_ISUID = 0x800
+	S_ISGID = 0x400
+	S_ISVTX = 0x200
+
+	STATX_TYPE        = 0x1
+	STATX_MODE        = 0x2
+	STATX_NLINK       = 0x4
+	STATX_UID         = 0x8
+	STATX_GID         = 0x10
+	STATX_ATIME       = 0x20
+	STATX_MTIME       = 0x40
+	STATX_CTIME       = 0x80
+	STATX_INO         = 0x100
+	STATX_SIZE        = 0x200
+	STATX_BLOCKS      = 0x400
+	STATX_BASIC_STATS = 0x7FF
+	STATX__RESERVED   = 0x80000000
+)
+
+const (
+	statBlockSize = 512
+	statIOBlock   = 4096
+	statFakeDev   = 0x1
+)
+
+var anonIno atomic.Uint64
+
+type kstat struct {
+	dev     uint64
+	ino     uint64
+	mode    mode_t
+	nlink   uint32
+	uid     uid_t
+	gid     gid_t
+	rdev    uint64
+	size    int64
+	blksize uint32
+	blocks  uint64
+	atim    timespec
+	mtim    timespec
+	ctim    timespec
+}
+
+type statx_timestamp struct {
+	tv_sec  int64
+	tv_nsec uint32
+}
+
+type statx struct {
+	stx_mask            uint32
+	stx_blksize         uint32
+	stx_attributes      uint64
+	stx_nlink           uint32
+	stx_uid             uint32
+	stx_gid             uint32
+	stx_mode            uint16
+	stx_ino             uint64
+	stx_size            uint64
+	stx_blocks          uint64
+	stx_attributes_mask uint64
+	stx_atime           statx_timestamp
+	stx_btime           statx_timestamp
+	stx_ctime           statx_timestamp
+	stx_mtime           statx_timestamp
+	stx_rdev_major      uint32
+	stx_rdev_minor      uint32
+	stx_dev_major       uint32
+	stx_dev_minor       uint32
+}
+
+type symlinkInfo struct {
+	name   string
+	target string
+}
+
+type lstatFS interface {
+	Lstat(name string) (fs.FileInfo, error)
+}
+
+func (ts *statx_timestamp) ctype(c *ccodec) {
+	cInt64(c, &ts.tv_sec)
+	cUint32(c, &ts.tv_nsec)
+	c.pad(4)
+}
+
+func (stx *statx) ctype(c *ccodec) {
+	cUint32(c, &stx.stx_mask)
+	cUint32(c, &stx.stx_blksize)
+	cUint64(c, &stx.stx_attributes)
+	cUint32(c, &stx.stx_nlink)
+	cUint32(c, &stx.stx_uid)
+	cUint32(c, &stx.stx_gid)
+	cUint16(c, &stx.stx_mode)
+	c.pad(2)
+	cUint64(c, &stx.stx_ino)
+	cUint64(c, &stx.stx_size)
+	cUint64(c, &stx.stx_blocks)
+	cUint64(c, &stx.stx_attributes_mask)
+	c.nested(&stx.stx_atime)
+	c.nested(&stx.stx_btime)
+	c.nested(&stx.stx_ctime)
+	c.nested(&stx.stx_mtime)
+	cUint32(c, &stx.stx_rdev_major)
+	cUint32(c, &stx.stx_rdev_minor)
+	cUint32(c, &stx.stx_dev_major)
+	cUint32(c, &stx.stx_dev_minor)
+	c.pad(112)
+}
+
+func (info symlinkInfo) Name() string {
+	return info.name
+}
+
+func (info symlinkInfo) Size() int64 {
+	return int64(len(info.target))
+}
+
+func (info symlinkInfo) Mode() fs.FileMode {
+	return fs.ModeSymlink | 0777
+}
+
+func (info symlinkInfo) ModTime() time.Time {
+	return time.Time{}
+}
+
+func (info symlinkInfo) IsDir() bool {
+	return false
+}
+
+func (info symlinkInfo) Sys() any {
+	return nil
+}
+
+func newKstat(info fs.FileInfo, name string) *kstat {
+	st := new(kstat)
+	if sysStat(info, st) {
+		return st
+	}
+	st.dev = statFakeDev
+	st.ino = inoOf(name)
+	st.mode = toMode(info.Mode())
+	st.nlink = 1
+	if info.IsDir() {
+		st.nlink = 2
+	}
+	st.size = info.Size()
+	st.blksize = statIOBlock
+	st.blocks = uint64(alignUp(int(max(st.size, 0)), statIOBlock) / statBlockSize)
+	st.mtim = toTimespec(info.ModTime())
+	st.atim = st.mtim
+	st.ctim = st.mtim
+	return st
+}
+
+func (st *kstat) stat3264() stat3264 {
+	return stat3264{
+		st_dev:     st.dev,
+		__st_ino:   ino_t(st.ino),
+		st_mode:    st.mode,
+		st_nlink:   nlink_t(st.nlink),
+		st_uid:     st.uid,
+		st_gid:     st.gid,
+		st_rdev:    st.rdev,
+		st_size:    st.size,
+		st_blksize: ulong_t(st.blksize),
+		st_blocks:  st.blocks,
+		st_atim:    st.atim,
+		st_mtim:    st.mtim,
+		st_ctim:    st.ctim,
+		st_ino:     st.ino,
+	}
+}
+
+func (st *kstat) stat64() stat64 {
+	return stat64{
+		st_dev:     dev_t(st.dev),
+		st_ino:     ino_t(st.ino),
+		st_mode:    st.mode,
+		st_nlink:   nlink_t(st.nlink),
+		st_uid:     st.uid,
+		st_gid:     st.gid,
+		st_rdev:    dev_t(st.rdev),
+		st_size:    off_t(st.size),
+		st_blksize: int32(st.blksize),
+		st_blocks:  long_t(st.blocks),
+		st_atim:    st.atim,
+		st_mtim:    st.mtim,
+		st_ctim:    st.ctim,
+	}
+}
+
+func (st *kstat) statx(mask uint32) statx {
+	stx := statx{
+		stx_mask:       mask&STATX_BASIC_STATS | STATX_TYPE,
+		stx_blksize:    st.blksize,
+		stx_mode:       uint16(st.mode & S_IFMT),
+		stx_rdev_major: devMajor(st.rdev),
+		stx_rdev_minor: devMinor(st.rdev),
+		stx_dev_major:  devMajor(st.dev),
+		stx_dev_minor:  devMinor(st.dev),
+	}
+	if mask&STATX_MODE != 0 {
+		stx.stx_mode = uint16(st.mode)
+	}
+	if mask&STATX_NLINK != 0 {
+		stx.stx_nlink = st.nlink
+	}
+	if mask&STATX_UID != 0 {
+		stx.stx_uid = uint32(st.uid)
+	}
+	if mask&STATX_GID != 0 {
+		stx.stx_gid = uint32(st.gid)
+	}
+	if mask&STATX_ATIME != 0 {
+		stx.stx_atime = toStatxTimestamp(st.atim)
+	}
+	if mask&STATX_MTIME != 0 {
+		stx.stx_mtime = toStatxTimestamp(st.mtim)
+	}
+	if mask&STATX_CTIME != 0 {
+		stx.stx_ctime = toStatxTimestamp(st.ctim)
+	}
+	if mask&STATX_INO != 0 {
+		stx.stx_ino = st.ino
+	}
+	if mask&STATX_SIZE != 0 {
+		stx.stx_size = uint64(st.size)
+	}
+	if mask&STATX_BLOCKS != 0 {
+		stx.stx_blocks = st.blocks
+	}
+	return stx
+}
+
+func (f *fcntl) statat(ctx linux.Context, dfd int32, filename emuptr, flag int32) (*kstat, linux.Errno) {
+	if flag&^(AT_SYMLINK_NOFOLLOW|AT_NO_AUTOMOUNT|AT_EMPTY_PATH|AT_STATX_SYNC_TYPE) != 0 {
+		return nil, linux.EINVAL
+	}
+	path, err := ctx.ToPointer(filename).MemReadString()
+	if err != nil {
+		return nil, linux.EFAULT
+	} else if path == "" {
+		if flag&AT_EMPTY_PATH == 0 {
+			return nil, linux.ENOENT
+		} else if dfd != AT_FDCWD {
+			file, err := f.getFile(ctx.Debugger(), int(dfd))
+			if err != nil {
+				return nil, linux.EBADF
+			}
+			info, err := file.Stat()
+			if err != nil {
+				return nil, linux.ToErrno(err)
+			}
+			return f.applyAttrs(newKstat(info, f.inoName(int(dfd)))), 0
+		}
+		path = "."
+	}
+	dir, path, errno := f.lookup(ctx, dfd, path)
+	if errno != 0 {
+		return nil, errno
+	}
+	if flag&AT_SYMLINK_NOFOLLOW == 0 {
+		if path, errno = f.followLink(dir, path); errno != 0 {
+			return nil, errno
+		}
+	}
+	info, err := lstat(dir, path)
+	if err != nil {
+		return nil, linux.ToErrno(err)
+	}
+	return f.applyAttrs(newKstat(info, path)), 0
+}
+
+func (f *fcntl) statfd(ctx linux.Context, fd uint32) (*kstat, linux.Errno) {
+	file, err := f.getFile(ctx.Debugger(), int(fd))
+	if err != nil {
+		return nil, linux.EBADF
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return nil, linux.ToErrno(err)
+	}
+	return f.applyAttrs(newKstat(info, f.inoName(int(fd)))), 0
+}
+
+func (f *fcntl) inoName(fd int) string {
+	f.rw.Lock()
+	defer f.rw.Unlock()
+	d := f.desc(fd)
+	if d.name == "" {
+		d.name = fmt.Sprintf("anon_inode:[%d]", anonIno.Add(1))
+	}
+	return d.name
+}
+
+func (f *fcntl) statx(ctx linux.Context, dfd int32, filename emuptr, flag int32, mask uint32, statxbuf emuptr) int32 {
+	if mask&STATX__RESERVED != 0 || flag&AT_STATX_SYNC_TYPE == AT_STATX_SYNC_TYPE {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	st, errno := f.statat(ctx, dfd, filename, flag)
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	stx := st.statx(mask)
+	if memWrite(ctx, statxbuf, &stx) != nil {
+		ctx.SetErrno(linux.EFAULT)
+		return -1
+	}
+	return 0
+}
+
+func lstat(dir fs.FS, name string) (fs.FileInfo, error) {
+	if fsys, ok := dir.(lstatFS); ok {
+		return fsys.Lstat(name)
+	}
+	if fsys, ok := dir.(filesystem.ReadlinkFS); ok {
+		if target, err := fsys.Readlink(name); err == nil {
+			return symlinkInfo{name: path.Base(name), target: target}, nil
+		}
+	}
+	return stat(dir, name)
+}
+
+func stat(dir fs.FS, name string) (fs.FileInfo, error) {
+	if fsys, ok := dir.(fs.StatFS); ok {
+		return fsys.Stat(name)
+	}
+	if fsys, ok := dir.(filesystem.FS); ok {
+		file, err := fsys.OpenFile(name, filesystem.O_RDONLY, 0)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return file.Stat()
+	}
+	return fs.Stat(dir, name)
+}
+
+func inoOf(name string) uint64 {
+	h := fnv.New64a()
+	io.WriteString(h, name)
+	if ino := h.Sum64(); ino != 0 {
+		return ino
+	}
+	return 1
+}
+
+func toMode(mode fs.FileMode) mode_t {
+	m := mode_t(mode.Perm())
+	switch mode.Type() {
+	case fs.ModeDevice | fs.ModeCharDevice:
+		m |= S_IFCHR
+	case fs.ModeDevice:
+		m |= S_IFBLK
+	case fs.ModeDir:
+		m |= S_IFDIR
+	case fs.ModeNamedPipe:
+		m |= S_IFIFO
+	case fs.ModeSymlink:
+		m |= S_IFLNK
+	case fs.ModeSocket:
+		m |= S_IFSOCK
+	case fs.ModeIrregular:
+	default:
+		m |= S_IFREG
+	}
+	if mode&fs.ModeSetuid != 0 {
+		m |= S_ISUID
+	}
+	if mode&fs.ModeSetgid != 0 {
+		m |= S_ISGID
+	}
+	if mode&fs.ModeSticky != 0 {
+		m |= S_ISVTX
+	}
+	return m
+}
+
+func fromMode(mode mode_t) fs.FileMode {
+	m := fs.FileMode(mode & 0777)
+	if mode&S_ISUID != 0 {
+		m |= fs.ModeSetuid
+	}
+	if mode&S_ISGID != 0 {
+		m |= fs.ModeSetgid
+	}
+	if mode&S_ISVTX != 0 {
+		m |= fs.ModeSticky
+	}
+	return m
+}
+
+func toTimespec(t time.Time) timespec {
+	if t.IsZero() {
+		return timespec{}
+	}
+	nano := t.UnixNano()
+	return timespec{tv_sec: time_t(nano / 1e9), tv_nsec: long_t(nano % 1e9)}
+}
+
+func toStatxTimestamp(ts timespec) statx_timestamp {
+	return statx_timestamp{tv_sec: int64(ts.tv_sec), tv_nsec: uint32(ts.tv_nsec)}
+}
+
+func devMajor(dev uint64) uint32 {
+	return uint32((dev>>8)&0xFFF | (dev>>32)&^0xFFF)
+}
+
+func devMinor(dev uint64) uint32 {
+	return uint32(dev&0xFF | (dev>>12)&^0xFF)
+}
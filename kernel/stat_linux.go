@@ -0,0 +1,27 @@
+package kernel
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+func sysStat(info fs.FileInfo, st *kstat) bool {
+	sys, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+	st.dev = uint64(sys.Dev)
+	st.ino = uint64(sys.Ino)
+	st.mode = mode_t(sys.Mode)
+	st.nlink = uint32(sys.Nlink)
+	st.uid = uid_t(sys.Uid)
+	st.gid = gid_t(sys.Gid)
+	st.rdev = uint64(sys.Rdev)
+	st.size = int64(sys.Size)
+	st.blksize = uint32(sys.Blksize)
+	st.blocks = uint64(sys.Blocks)
+	st.atim = timespec{tv_sec: time_t(sys.Atim.Sec), tv_nsec: long_t(sys.Atim.Nsec)}
+	st.mtim = timespec{tv_sec: time_t(sys.Mtim.Sec), tv_nsec: long_t(sys.Mtim.Nsec)}
+	st.ctim = timespec{tv_sec: time_t(sys.Ctim.Sec), tv_nsec: long_t(sys.Ctim.Nsec)}
+	return true
+}
@@ -0,0 +1,47 @@
+package kernel
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/emulator"
+)
+
+func TestStatFields(t *testing.T) {
+	for _, arch := range []emulator.Arch{emulator.ARCH_ARM, emulator.ARCH_ARM64, emulator.ARCH_X86, emulator.ARCH_X86_64} {
+		tk := newTestKernel(t, arch)
+		tk.create("file", make([]byte, 5000))
+		host, err := os.Stat(filepath.Join(tk.dbg.root, "file"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sys, ok := host.Sys().(*syscall.Stat_t)
+		if !ok {
+			t.Fatalf("Sys() = %T, want *syscall.Stat_t", host.Sys())
+		}
+		statbuf := tk.alloc(256)
+		if _, errno := tk.call(linux.NR_fstatat64, uint64(testAT_FDCWD), tk.cstring("file"), statbuf, 0); errno != 0 {
+			t.Fatalf("%v: fstatat64 errno = %v", arch, errno)
+		}
+		var st kstat
+		switch arch {
+		case emulator.ARCH_ARM, emulator.ARCH_X86:
+			var stat stat3264
+			tk.decode(statbuf, &stat)
+			st = kstat{ino: stat.st_ino, nlink: uint32(stat.st_nlink), uid: stat.st_uid, blksize: uint32(stat.st_blksize), blocks: stat.st_blocks, dev: stat.st_dev, atim: stat.st_atim}
+		default:
+			var stat stat64
+			tk.decode(statbuf, &stat)
+			st = kstat{ino: uint64(stat.st_ino), nlink: uint32(stat.st_nlink), uid: stat.st_uid, blksize: uint32(stat.st_blksize), blocks: uint64(stat.st_blocks), dev: uint64(stat.st_dev), atim: stat.st_atim}
+		}
+		if st.ino != uint64(sys.Ino) || st.dev != uint64(sys.Dev) || st.nlink != 1 || st.uid != uid_t(sys.Uid) {
+			t.Errorf("%v: ino = %d, dev = %d, nlink = %d, uid = %d", arch, st.ino, st.dev, st.nlink, st.uid)
+		}
+		if st.blksize == 0 || st.blocks != uint64(sys.Blocks) || st.atim.tv_sec != time_t(sys.Atim.Sec) {
+			t.Errorf("%v: blksize = %d, blocks = %d, atime = %d", arch, st.blksize, st.blocks, st.atim.tv_sec)
+		}
+	}
+}
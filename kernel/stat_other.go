@@ -0,0 +1,9 @@
+//go:build !linux
+
+package kernel
+
+import "io/fs"
+
+func sysStat(info fs.FileInfo, st *kstat) bool {
+	return false
+}
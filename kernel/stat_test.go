@@ -0,0 +1,189 @@
+package kernel
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/emulator"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+type virtFS struct {
+	filesystem.FS
+}
+
+type virtFile struct {
+	filesystem.File
+}
+
+type virtInfo struct {
+	fs.FileInfo
+}
+
+type virtEntry struct {
+	fs.DirEntry
+}
+
+func (v virtFS) OpenFile(name string, flag filesystem.FileFlag, perm fs.FileMode) (filesystem.File, error) {
+	file, err := v.FS.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return virtFile{file}, nil
+}
+
+func (f virtFile) Stat() (fs.FileInfo, error) {
+	info, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return virtInfo{info}, nil
+}
+
+func (f virtFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	entries, err := f.File.(filesystem.DirFS).ReadDir("")
+	for i, entry := range entries {
+		entries[i] = virtEntry{entry}
+	}
+	return entries, err
+}
+
+func (e virtEntry) Info() (fs.FileInfo, error) {
+	info, err := e.DirEntry.Info()
+	if err != nil {
+		return nil, err
+	}
+	return virtInfo{info}, nil
+}
+
+func (virtInfo) Sys() any {
+	return nil
+}
+
+func TestStatxLayout(t *testing.T) {
+	for _, arch := range []emulator.Arch{emulator.ARCH_ARM, emulator.ARCH_ARM64, emulator.ARCH_X86, emulator.ARCH_X86_64} {
+		if size, _ := sizeOf(modelOf(arch), new(statx)); size != 256 {
+			t.Errorf("%v: sizeof(struct statx) = %d, want 256", arch, size)
+		}
+	}
+}
+
+func TestStatx(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	fd := uint64(tk.create("file", []byte("hello")))
+	if err := os.Symlink("file", filepath.Join(tk.dbg.root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	statx := func(dfd uint64, name string, flags, mask uint64) (statx, linux.Errno) {
+		t.Helper()
+		var stx statx
+		buf := tk.alloc(256)
+		_, errno := tk.call(linux.NR_statx, dfd, tk.cstring(name), flags, mask, buf)
+		if errno == 0 {
+			tk.decode(buf, &stx)
+		}
+		return stx, errno
+	}
+
+	stx, errno := statx(uint64(testAT_FDCWD), "file", 0, STATX_BASIC_STATS)
+	if errno != 0 || stx.stx_mask != STATX_BASIC_STATS || stx.stx_size != 5 || stx.stx_mode != S_IFREG|0644 || stx.stx_ino == 0 || stx.stx_nlink != 1 {
+		t.Fatalf("statx(file) = %+v, errno = %v", stx, errno)
+	}
+	ino := stx.stx_ino
+	if stx, errno := statx(uint64(testAT_FDCWD), "file", 0, STATX_SIZE); errno != 0 || stx.stx_mask != STATX_TYPE|STATX_SIZE || stx.stx_size != 5 || stx.stx_ino != 0 || stx.stx_mode != S_IFREG {
+		t.Fatalf("statx(STATX_SIZE) = %+v, errno = %v", stx, errno)
+	}
+	if stx, errno := statx(fd, "", AT_EMPTY_PATH, STATX_BASIC_STATS); errno != 0 || stx.stx_ino != ino || stx.stx_size != 5 {
+		t.Fatalf("statx(AT_EMPTY_PATH) = %+v, errno = %v", stx, errno)
+	}
+	if _, errno := statx(fd, "", 0, STATX_BASIC_STATS); errno != linux.ENOENT {
+		t.Fatalf("statx(\"\") errno = %v, want ENOENT", errno)
+	}
+	if stx, errno := statx(uint64(testAT_FDCWD), "link", 0, STATX_BASIC_STATS); errno != 0 || stx.stx_ino != ino {
+		t.Fatalf("statx(link) = %+v, errno = %v", stx, errno)
+	}
+	if stx, errno := statx(uint64(testAT_FDCWD), "link", AT_SYMLINK_NOFOLLOW, STATX_BASIC_STATS); errno != 0 || stx.stx_mode&S_IFMT != S_IFLNK || stx.stx_size != 4 {
+		t.Fatalf("statx(link, AT_SYMLINK_NOFOLLOW) = %+v, errno = %v", stx, errno)
+	}
+	if _, errno := statx(uint64(testAT_FDCWD), "file", 0, STATX__RESERVED); errno != linux.EINVAL {
+		t.Fatalf("statx(STATX__RESERVED) errno = %v, want EINVAL", errno)
+	}
+	if _, errno := statx(uint64(testAT_FDCWD), "missing", 0, STATX_BASIC_STATS); errno != linux.ENOENT {
+		t.Fatalf("statx(missing) errno = %v, want ENOENT", errno)
+	}
+
+	dir, _ := tk.call(linux.NR_openat, uint64(testAT_FDCWD), tk.cstring("."), 0, 0)
+	dirents, _ := tk.getdents(dir, 1024)
+	for _, d := range dirents {
+		if d.name == "file" && d.ino != ino {
+			t.Fatalf("getdents64 d_ino = %d, want %d", d.ino, ino)
+		}
+	}
+}
+
+func TestVirtualInode(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	at := uint64(testAT_FDCWD)
+	for _, dir := range []string{"a", "b"} {
+		os.Mkdir(filepath.Join(tk.dbg.root, dir), 0755)
+		os.WriteFile(filepath.Join(tk.dbg.root, dir, "lock"), nil, 0644)
+	}
+	tk.dbg.fs = virtFS{tk.dbg.fs}
+	a, b := tk.statAt(at, "a/lock", 0), tk.statAt(at, "b/lock", 0)
+	if a.stx_ino == b.stx_ino {
+		t.Fatalf("a/lock and b/lock share inode %d", a.stx_ino)
+	}
+	fd := tk.open("a/lock", O_RDONLY)
+	if stx := tk.statAt(fd, "", AT_EMPTY_PATH); stx.stx_ino != a.stx_ino {
+		t.Fatalf("fstat ino = %d, want %d", stx.stx_ino, a.stx_ino)
+	}
+	dir := tk.open("a", O_RDONLY|O_DIRECTORY)
+	if stx := tk.statAt(dir, "lock", 0); stx.stx_ino != a.stx_ino {
+		t.Fatalf("statx(dirfd, lock) ino = %d, want %d", stx.stx_ino, a.stx_ino)
+	}
+	dirents, _ := tk.getdents(dir, 4096)
+	if i := slices.IndexFunc(dirents, func(d testDirent) bool { return d.name == "lock" }); i < 0 || dirents[i].ino != a.stx_ino {
+		t.Fatalf("getdents = %+v, want lock ino %d", dirents, a.stx_ino)
+	}
+	other := tk.open("b/lock", O_RDONLY)
+	if _, errno := tk.call(linux.NR_flock, fd, LOCK_EX|LOCK_NB); errno != 0 {
+		t.Fatalf("flock(a/lock) errno = %v", errno)
+	}
+	if _, errno := tk.call(linux.NR_flock, other, LOCK_EX|LOCK_NB); errno != 0 {
+		t.Fatalf("flock(b/lock) errno = %v, want 0", errno)
+	}
+	tk.call(linux.NR_fchmodat, at, tk.cstring("a/lock"), 0400)
+	if stx := tk.statAt(at, "b/lock", 0); stx.stx_mode != S_IFREG|0644 {
+		t.Fatalf("b/lock mode = %#o, want %#o", stx.stx_mode, S_IFREG|0644)
+	}
+	if stx := tk.statAt(at, "a/lock", 0); stx.stx_mode != S_IFREG|0400 {
+		t.Fatalf("a/lock mode = %#o, want %#o", stx.stx_mode, S_IFREG|0400)
+	}
+	var fds []uint64
+	for _, name := range []string{"a/lock", "b/lock"} {
+		file, err := tk.dbg.fs.OpenFile(name, filesystem.O_RDONLY, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fds = append(fds, uint64(tk.dbg.CreateFileDescriptor(file)))
+	}
+	ep1, _ := tk.call(linux.NR_epoll_create1, 0)
+	ep2, _ := tk.call(linux.NR_epoll_create1, 0)
+	fds = append(fds, ep1, ep2)
+	seen := make(map[uint64]uint64)
+	for _, fd := range fds {
+		ino := tk.statAt(fd, "", AT_EMPTY_PATH).stx_ino
+		if prev, ok := seen[ino]; ok {
+			t.Fatalf("fds %d and %d without a path share inode %d", prev, fd, ino)
+		}
+		seen[ino] = fd
+		dup, _ := tk.call(linux.NR_dup, fd)
+		if stx := tk.statAt(dup, "", AT_EMPTY_PATH); stx.stx_ino != ino {
+			t.Fatalf("dup(%d) ino = %d, want %d", fd, stx.stx_ino, ino)
+		}
+	}
+}
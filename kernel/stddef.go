@@ -11,6 +11,7 @@ type time_t long_t
 type suseconds_t long_t
 type clockid_t int32
 type off_t long_t
+type loff_t int64
 type dev_t ulong_t
 type ino_t ulong_t
 type mode_t uint32
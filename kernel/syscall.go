@@ -2,6 +2,8 @@ package kernel
 
 import (
 	"math"
+	"sync"
+	"sync/atomic"
 
 	linux "github.com/wnxd/microdbg-linux"
 	"github.com/wnxd/microdbg/emulator"
@@ -16,6 +18,14 @@ type Syscall struct {
 	network
 	mman
 	sched
+	mu      sync.Mutex
+	builtin [linux.NR_max]syscallEntry
+	table   [linux.NR_max]atomic.Pointer[syscallEntry]
+}
+
+type syscallEntry struct {
+	call   linux.SyscallHandler
+	status linux.SyscallStatus
 }
 
 func NewSyscall() *Syscall {
@@ -28,178 +38,696 @@ func (sys *Syscall) ctor() {
 	sys.fcntl.ctor()
 	sys.futex.ctor()
 	sys.signal.ctor()
+	sys.sched.futex = &sys.futex
+	sys.fcntl.sched = &sys.sched
+	sys.fcntl.signal = &sys.signal
+	sys.signal.sched = &sys.sched
+	sys.mman.fcntl = &sys.fcntl
+	sys.network.fcntl = &sys.fcntl
+	sys.implement(linux.NR_dup, sys.Emulate_dup)
+	sys.implement(linux.NR_dup2, sys.Emulate_dup2)
+	sys.implement(linux.NR_dup3, sys.Emulate_dup3)
+	sys.implement(linux.NR_fcntl, sys.Emulate_fcntl)
+	sys.implement(linux.NR_fcntl64, sys.Emulate_fcntl64)
+	sys.implement(linux.NR_flock, sys.Emulate_flock)
+	sys.implement(linux.NR_ioctl, sys.Emulate_ioctl)
+	sys.implement(linux.NR_faccessat, sys.Emulate_faccessat)
+	sys.implement(linux.NR_faccessat2, sys.Emulate_faccessat2)
+	sys.implement(linux.NR_access, sys.Emulate_access)
+	sys.implement(linux.NR_getuid, sys.Emulate_getuid)
+	sys.implement(linux.NR_geteuid, sys.Emulate_geteuid)
+	sys.implement(linux.NR_getgid, sys.Emulate_getgid)
+	sys.implement(linux.NR_getegid, sys.Emulate_getegid)
+	sys.implement(linux.NR_getuid16, sys.Emulate_getuid16)
+	sys.implement(linux.NR_geteuid16, sys.Emulate_geteuid16)
+	sys.implement(linux.NR_getgid16, sys.Emulate_getgid16)
+	sys.implement(linux.NR_getegid16, sys.Emulate_getegid16)
+	sys.implement(linux.NR_open, sys.Emulate_open)
+	sys.implement(linux.NR_openat, sys.Emulate_openat)
+	sys.implement(linux.NR_openat2, sys.Emulate_openat2)
+	sys.implement(linux.NR_creat, sys.Emulate_creat)
+	sys.implement(linux.NR_close, sys.Emulate_close)
+	sys.implement(linux.NR_pipe2, sys.Emulate_pipe2)
+	sys.implement(linux.NR_epoll_create, sys.Emulate_epoll_create)
+	sys.implement(linux.NR_epoll_create1, sys.Emulate_epoll_create1)
+	sys.implement(linux.NR_epoll_ctl, sys.Emulate_epoll_ctl)
+	sys.implement(linux.NR_epoll_wait, sys.Emulate_epoll_wait)
+	sys.implement(linux.NR_epoll_pwait, sys.Emulate_epoll_pwait)
+	sys.implement(linux.NR_epoll_pwait2, sys.Emulate_epoll_pwait2)
+	sys.implement(linux.NR_lseek, sys.Emulate_lseek)
+	sys.implement(linux.NR_read, sys.Emulate_read)
+	sys.implement(linux.NR_write, sys.Emulate_write)
+	sys.implement(linux.NR_writev, sys.Emulate_writev)
+	sys.implement(linux.NR_readv, sys.Emulate_readv)
+	sys.implement(linux.NR_pread64, sys.Emulate_pread64)
+	sys.implement(linux.NR_pwrite64, sys.Emulate_pwrite64)
+	sys.implement(linux.NR_preadv, sys.Emulate_preadv)
+	sys.implement(linux.NR_pwritev, sys.Emulate_pwritev)
+	sys.implement(linux.NR_preadv2, sys.Emulate_preadv2)
+	sys.implement(linux.NR_pwritev2, sys.Emulate_pwritev2)
+	sys.implement(linux.NR_sendfile, sys.Emulate_sendfile)
+	sys.implement(linux.NR_sendfile64, sys.Emulate_sendfile64)
+	sys.implement(linux.NR_copy_file_range, sys.Emulate_copy_file_range)
+	sys.implement(linux.NR_splice, sys.Emulate_splice)
+	sys.implement(linux.NR_tee, sys.Emulate_tee)
+	sys.implement(linux.NR_truncate, sys.Emulate_truncate)
+	sys.implement(linux.NR_truncate64, sys.Emulate_truncate64)
+	sys.implement(linux.NR_ftruncate, sys.Emulate_ftruncate)
+	sys.implement(linux.NR_ftruncate64, sys.Emulate_ftruncate64)
+	sys.implement(linux.NR_fallocate, sys.Emulate_fallocate)
+	sys.implement(linux.NR_fsync, sys.Emulate_fsync)
+	sys.implement(linux.NR_fdatasync, sys.Emulate_fdatasync)
+	sys.implement(linux.NR_sync_file_range, sys.Emulate_sync_file_range)
+	sys.implement(linux.NR_sync_file_range2, sys.Emulate_sync_file_range2)
+	sys.implement(linux.NR_syncfs, sys.Emulate_syncfs)
+	sys.implement(linux.NR_readlinkat, sys.Emulate_readlinkat)
+	sys.implement(linux.NR_getdents64, sys.Emulate_getdents64)
+	sys.implement(linux.NR_mkdirat, sys.Emulate_mkdirat)
+	sys.implement(linux.NR_mkdir, sys.Emulate_mkdir)
+	sys.implement(linux.NR_unlinkat, sys.Emulate_unlinkat)
+	sys.implement(linux.NR_unlink, sys.Emulate_unlink)
+	sys.implement(linux.NR_rmdir, sys.Emulate_rmdir)
+	sys.implement(linux.NR_renameat, sys.Emulate_renameat)
+	sys.implement(linux.NR_renameat2, sys.Emulate_renameat2)
+	sys.implement(linux.NR_rename, sys.Emulate_rename)
+	sys.implement(linux.NR_symlinkat, sys.Emulate_symlinkat)
+	sys.implement(linux.NR_symlink, sys.Emulate_symlink)
+	sys.implement(linux.NR_linkat, sys.Emulate_linkat)
+	sys.implement(linux.NR_link, sys.Emulate_link)
+	sys.implement(linux.NR_getcwd, sys.Emulate_getcwd)
+	sys.implement(linux.NR_chdir, sys.Emulate_chdir)
+	sys.implement(linux.NR_fchdir, sys.Emulate_fchdir)
+	sys.implement(linux.NR_chroot, sys.Emulate_chroot)
+	sys.implement(linux.NR_umask, sys.Emulate_umask)
+	sys.implement(linux.NR_fchmod, sys.Emulate_fchmod)
+	sys.implement(linux.NR_fchmodat, sys.Emulate_fchmodat)
+	sys.implement(linux.NR_fchmodat2, sys.Emulate_fchmodat2)
+	sys.implement(linux.NR_chmod, sys.Emulate_chmod)
+	sys.implement(linux.NR_fchown, sys.Emulate_fchown)
+	sys.implement(linux.NR_fchownat, sys.Emulate_fchownat)
+	sys.implement(linux.NR_chown, sys.Emulate_chown)
+	sys.implement(linux.NR_lchown, sys.Emulate_lchown)
+	sys.implement(linux.NR_utimensat, sys.Emulate_utimensat)
+	sys.implement(linux.NR_utimensat_time64, sys.Emulate_utimensat_time64)
+	sys.implement(linux.NR_fstatat64, sys.Emulate_fstatat64)
+	sys.implement(linux.NR_fstat64, sys.Emulate_fstat64)
+	sys.implement(linux.NR_statx, sys.Emulate_statx)
+	sys.implement(linux.NR_exit, sys.Emulate_exit)
+	sys.implement(linux.NR_exit_group, sys.Emulate_exit_group)
+	sys.implement(linux.NR_set_tid_address, sys.Emulate_set_tid_address)
+	sys.implement(linux.NR_futex, sys.Emulate_futex)
+	sys.implement(linux.NR_clock_gettime, sys.Emulate_clock_gettime)
+	sys.implement(linux.NR_rt_sigaction, sys.Emulate_rt_sigaction)
+	sys.implement(linux.NR_rt_sigprocmask, sys.Emulate_rt_sigprocmask)
+	sys.implement(linux.NR_getrlimit, sys.Emulate_getrlimit)
+	sys.implement(linux.NR_setrlimit, sys.Emulate_setrlimit)
+	sys.implement(linux.NR_prctl, sys.Emulate_prctl)
+	sys.implement(linux.NR_gettimeofday, sys.Emulate_gettimeofday)
+	sys.implement(linux.NR_getpid, sys.Emulate_getpid)
+	sys.implement(linux.NR_gettid, sys.Emulate_gettid)
+	sys.implement(linux.NR_sysinfo, sys.Emulate_sysinfo)
+	sys.implement(linux.NR_socket, sys.Emulate_socket)
+	sys.implement(linux.NR_munmap, sys.Emulate_munmap)
+	sys.implement(linux.NR_clone, sys.Emulate_clone)
+	sys.implement(linux.NR_execve, sys.Emulate_execve)
+	sys.implement(linux.NR_set_tls, sys.Emulate_set_tls)
+	sys.implement(linux.NR_mmap, sys.Emulate_mmap)
+	sys.implement(linux.NR_mmap2, sys.Emulate_mmap2)
+	sys.implement(linux.NR_mprotect, sys.Emulate_mprotect)
+	sys.implement(linux.NR_rt_tgsigqueueinfo, sys.Emulate_rt_tgsigqueueinfo)
+	sys.implement(linux.NR_getrandom, sys.Emulate_getrandom)
+	sys.reject(linux.NR_reject, linux.NR_madvise)
+	sys.ignore(linux.NR_ignore, linux.NR_sync, linux.NR_sigaltstack)
 }
 
 func (sys *Syscall) Close() error {
 	sys.signal.dtor()
 	sys.futex.dtor()
 	sys.fcntl.dtor()
+	for nr := range sys.table {
+		sys.table[nr].Store(nil)
+	}
 	return nil
 }
 
-func (sys *Syscall) Get(nr linux.NR) func(linux.Context, ...uint64) uint64 {
+func (sys *Syscall) Get(nr linux.NR) linux.SyscallHandler {
+	return sys.dispatch(nr)
+}
+
+func (sys *Syscall) Builtin(nr linux.NR) linux.SyscallHandler {
+	if nr < 0 || nr >= linux.NR_max {
+		return nil
+	}
+	return sys.builtin[nr].call
+}
+
+func (sys *Syscall) Register(nr linux.NR, handler linux.SyscallHandler) {
+	if nr < 0 || nr >= linux.NR_max {
+		return
+	}
+	status := linux.SyscallStatus_Implemented
+	if handler == nil {
+		status = linux.SyscallStatus_None
+	}
+	sys.mu.Lock()
+	sys.table[nr].Store(&syscallEntry{handler, status})
+	sys.mu.Unlock()
+}
+
+func (sys *Syscall) Unregister(nr linux.NR) {
+	if nr < 0 || nr >= linux.NR_max {
+		return
+	}
+	sys.mu.Lock()
+	sys.table[nr].Store(&sys.builtin[nr])
+	sys.mu.Unlock()
+}
+
+func (sys *Syscall) Wrap(nr linux.NR, middleware linux.SyscallMiddleware) bool {
+	if nr < 0 || nr >= linux.NR_max {
+		return false
+	}
+	sys.mu.Lock()
+	defer sys.mu.Unlock()
+	entry := sys.table[nr].Load()
+	if entry == nil || entry.call == nil {
+		return false
+	}
+	sys.table[nr].Store(&syscallEntry{middleware(entry.call), entry.status})
+	return true
+}
+
+func (sys *Syscall) Status(nr linux.NR) linux.SyscallStatus {
+	if nr < 0 || nr >= linux.NR_max {
+		return linux.SyscallStatus_None
+	}
+	if entry := sys.table[nr].Load(); entry != nil {
+		return entry.status
+	}
+	return linux.SyscallStatus_None
+}
+
+func (sys *Syscall) List(status linux.SyscallStatus) []linux.NR {
+	var list []linux.NR
+	for nr := range sys.table {
+		if entry := sys.table[nr].Load(); entry != nil && entry.status == status {
+			list = append(list, linux.NR(nr))
+		}
+	}
+	return list
+}
+
+func (sys *Syscall) dispatch(nr linux.NR) linux.SyscallHandler {
+	if nr < 0 || nr >= linux.NR_max {
+		return nil
+	}
+	entry := sys.table[nr].Load()
+	if entry == nil || entry.call == nil {
+		return nil
+	}
 	switch nr {
-	case linux.NR_reject:
-		return sys.Reject
-	case linux.NR_ignore:
-		return sys.Ignore
-	case linux.NR_dup3:
-		return sys.Emulate_dup3
-	case linux.NR_fcntl:
-		return sys.Emulate_fcntl
-	case linux.NR_ioctl:
-		return sys.Emulate_ioctl
-	case linux.NR_faccessat:
-		return sys.Emulate_faccessat
-	case linux.NR_open:
-		return sys.Emulate_open
-	case linux.NR_openat:
-		return sys.Emulate_openat
-	case linux.NR_close:
-		return sys.Emulate_close
-	case linux.NR_pipe2:
-		return sys.Emulate_pipe2
-	case linux.NR_lseek:
-		return sys.Emulate_lseek
-	case linux.NR_read:
-		return sys.Emulate_read
-	case linux.NR_write:
-		return sys.Emulate_write
-	case linux.NR_writev:
-		return sys.Emulate_writev
-	case linux.NR_readlinkat:
-		return sys.Emulate_readlinkat
-	case linux.NR_fstatat64:
-		return sys.Emulate_fstatat64
-	case linux.NR_fstat64:
-		return sys.Emulate_fstat64
-	case linux.NR_exit, linux.NR_exit_group:
-		return sys.Emulate_exit
-	case linux.NR_futex:
-		return sys.Emulate_futex
-	case linux.NR_clock_gettime:
-		return sys.Emulate_clock_gettime
-	case linux.NR_sigaltstack:
-		return sys.Ignore
-	case linux.NR_rt_sigaction:
-		return sys.Emulate_rt_sigaction
-	case linux.NR_rt_sigprocmask:
-		return sys.Emulate_rt_sigprocmask
-	case linux.NR_getrlimit:
-		return sys.Emulate_getrlimit
-	case linux.NR_setrlimit:
-		return sys.Emulate_setrlimit
-	case linux.NR_prctl:
-		return sys.Emulate_prctl
-	case linux.NR_gettimeofday:
-		return sys.Emulate_gettimeofday
-	case linux.NR_getpid:
-		return sys.Emulate_getpid
-	case linux.NR_getuid, linux.NR_geteuid:
-		return sys.Ignore
-	case linux.NR_gettid:
-		return sys.Emulate_gettid
-	case linux.NR_sysinfo:
-		return sys.Emulate_sysinfo
-	case linux.NR_socket:
-		return sys.Emulate_socket
-	case linux.NR_munmap:
-		return sys.Emulate_munmap
-	case linux.NR_clone:
-		return sys.Emulate_clone
-	case linux.NR_execve:
-		return sys.Emulate_execve
-	case linux.NR_mmap:
-		return sys.Emulate_mmap
-	case linux.NR_mmap2:
-		return sys.Emulate_mmap2
-	case linux.NR_mprotect:
-		return sys.Emulate_mprotect
-	case linux.NR_madvise:
-		return sys.Reject
-	case linux.NR_rt_tgsigqueueinfo:
-		return sys.Emulate_rt_tgsigqueueinfo
-	case linux.NR_getrandom:
-		return sys.Emulate_getrandom
+	case linux.NR_execve, linux.NR_execveat:
+		return sys.execHandler(entry.call)
+	}
+	return entry.call
+}
+
+func (sys *Syscall) execHandler(call linux.SyscallHandler) linux.SyscallHandler {
+	return func(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+		r := call(ctx, args)
+		if ctx.Errno() == 0 {
+			sys.fcntl.closeOnExec(ctx)
+		}
+		return r
+	}
+}
+
+func (sys *Syscall) implement(nr linux.NR, call linux.SyscallHandler) {
+	sys.builtin[nr] = syscallEntry{call, linux.SyscallStatus_Implemented}
+	sys.table[nr].Store(&sys.builtin[nr])
+}
+
+func (sys *Syscall) reject(nrs ...linux.NR) {
+	for _, nr := range nrs {
+		sys.builtin[nr] = syscallEntry{sys.Reject, linux.SyscallStatus_Rejected}
+		sys.table[nr].Store(&sys.builtin[nr])
 	}
-	return nil
 }
 
-func (sys *Syscall) Reject(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) ignore(nrs ...linux.NR) {
+	for _, nr := range nrs {
+		sys.builtin[nr] = syscallEntry{sys.Ignore, linux.SyscallStatus_Ignored}
+		sys.table[nr].Store(&sys.builtin[nr])
+	}
+}
+
+func (sys *Syscall) Reject(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	ctx.SetErrno(linux.ENOSYS)
 	return math.MaxUint64
 }
 
-func (sys *Syscall) Ignore(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Ignore(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	return 0
 }
 
-func (sys *Syscall) Emulate_dup3(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_dup(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.dup(ctx, uint32(args[0]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_dup2(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.dup2(ctx, uint32(args[0]), uint32(args[1]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_dup3(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.fcntl.dup3(ctx, uint32(args[0]), uint32(args[1]), int32(args[2]))
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_fcntl(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_fcntl(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.fcntl.fcntl(ctx, uint32(args[0]), uint32(args[1]), ulong_t(args[2]))
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_ioctl(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_fcntl64(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.fcntl64(ctx, uint32(args[0]), uint32(args[1]), ulong_t(args[2]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_flock(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.flock(ctx, uint32(args[0]), uint32(args[1]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_ioctl(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.ioctl(ctx, uint32(args[0]), uint32(args[1]), args[2])
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_faccessat(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_faccessat(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.fcntl.faccessat(ctx, int32(args[0]), args[1], int32(args[2]))
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_open(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_faccessat2(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.faccessat2(ctx, int32(args[0]), args[1], int32(args[2]), int32(args[3]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_getuid(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.getCred().uid
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_geteuid(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.getCred().euid
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_getgid(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.getCred().gid
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_getegid(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.getCred().egid
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_getuid16(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := lowUID(sys.fcntl.getCred().uid)
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_geteuid16(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := lowUID(sys.fcntl.getCred().euid)
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_getgid16(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := lowGID(sys.fcntl.getCred().gid)
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_getegid16(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := lowGID(sys.fcntl.getCred().egid)
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_access(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.faccessat(ctx, AT_FDCWD, args[0], int32(args[1]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_open(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.fcntl.open(ctx, args[0], int32(args[1]), int32(args[2]))
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_openat(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_openat(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.fcntl.openat(ctx, int32(args[0]), args[1], int32(args[2]), int32(args[3]))
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_close(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_openat2(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.openat2(ctx, int32(args[0]), args[1], args[2], size_t(args[3]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_creat(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.creat(ctx, args[0], mode_t(args[1]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_close(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.fcntl.close(ctx, uint32(args[0]))
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_pipe2(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_pipe2(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.fcntl.pipe2(ctx, args[0], int32(args[1]))
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_lseek(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_epoll_create(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.epoll_create(ctx, int32(args[0]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_epoll_create1(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.epoll_create1(ctx, int32(args[0]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_epoll_ctl(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.epoll_ctl(ctx, int32(args[0]), int32(args[1]), int32(args[2]), args[3])
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_epoll_wait(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.epoll_wait(ctx, int32(args[0]), args[1], int32(args[2]), int32(args[3]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_epoll_pwait(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.epoll_pwait(ctx, int32(args[0]), args[1], int32(args[2]), int32(args[3]), args[4], size_t(args[5]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_epoll_pwait2(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.epoll_pwait2(ctx, int32(args[0]), args[1], int32(args[2]), args[3], args[4], size_t(args[5]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_lseek(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.fcntl.lseek(ctx, uint32(args[0]), off_t(args[1]), int32(args[2]))
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_read(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_read(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.fcntl.read(ctx, uint32(args[0]), args[1], size_t(args[2]))
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_write(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_write(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.fcntl.write(ctx, uint32(args[0]), args[1], size_t(args[2]))
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_writev(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_writev(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.fcntl.writev(ctx, uint32(args[0]), args[1], int32(args[2]))
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_readlinkat(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_readv(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.readv(ctx, uint32(args[0]), args[1], int32(args[2]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_pread64(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.pread64(ctx, uint32(args[0]), args[1], size_t(args[2]), argLoff(ctx, args, 3))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_pwrite64(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.pwrite64(ctx, uint32(args[0]), args[1], size_t(args[2]), argLoff(ctx, args, 3))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_preadv(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.preadv(ctx, uint32(args[0]), args[1], int32(args[2]), argPos(ctx, args, 3))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_pwritev(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.pwritev(ctx, uint32(args[0]), args[1], int32(args[2]), argPos(ctx, args, 3))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_preadv2(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.preadv2(ctx, uint32(args[0]), args[1], int32(args[2]), argPos(ctx, args, 3), int32(args[5]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_pwritev2(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.pwritev2(ctx, uint32(args[0]), args[1], int32(args[2]), argPos(ctx, args, 3), int32(args[5]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_sendfile(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.sendfile(ctx, uint32(args[0]), uint32(args[1]), args[2], size_t(args[3]), false)
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_sendfile64(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.sendfile(ctx, uint32(args[0]), uint32(args[1]), args[2], size_t(args[3]), true)
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_copy_file_range(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.copy_file_range(ctx, uint32(args[0]), args[1], uint32(args[2]), args[3], size_t(args[4]), uint32(args[5]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_splice(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.splice(ctx, uint32(args[0]), args[1], uint32(args[2]), args[3], size_t(args[4]), uint32(args[5]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_tee(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.tee(ctx, uint32(args[0]), uint32(args[1]), size_t(args[2]), uint32(args[3]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_truncate(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.truncate(ctx, args[0], loff_t(off_t(args[1])))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_truncate64(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.truncate(ctx, args[0], argLoff(ctx, args, 1))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_ftruncate(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.ftruncate(ctx, uint32(args[0]), loff_t(off_t(args[1])))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_ftruncate64(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.ftruncate(ctx, uint32(args[0]), argLoff(ctx, args, 1))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_fallocate(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	var r int32
+	switch ctx.Debugger().Arch() {
+	case emulator.ARCH_ARM, emulator.ARCH_X86:
+		r = sys.fcntl.fallocate(ctx, uint32(args[0]), int32(args[1]), argPos(ctx, args, 2), argPos(ctx, args, 4))
+	default:
+		r = sys.fcntl.fallocate(ctx, uint32(args[0]), int32(args[1]), loff_t(args[2]), loff_t(args[3]))
+	}
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_fsync(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.fsync(ctx, uint32(args[0]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_fdatasync(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.fdatasync(ctx, uint32(args[0]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_sync_file_range(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	var r int32
+	switch ctx.Debugger().Arch() {
+	case emulator.ARCH_ARM, emulator.ARCH_X86:
+		r = sys.fcntl.sync_file_range(ctx, uint32(args[0]), argPos(ctx, args, 1), argPos(ctx, args, 3), uint32(args[5]))
+	default:
+		r = sys.fcntl.sync_file_range(ctx, uint32(args[0]), loff_t(args[1]), loff_t(args[2]), uint32(args[3]))
+	}
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_sync_file_range2(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.sync_file_range(ctx, uint32(args[0]), argPos(ctx, args, 2), argPos(ctx, args, 4), uint32(args[1]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_syncfs(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.syncfs(ctx, uint32(args[0]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_readlinkat(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.fcntl.readlinkat(ctx, int32(args[0]), args[1], args[2], size_t(args[3]))
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_fstatat64(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_getdents64(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.getdents64(ctx, uint32(args[0]), args[1], size_t(args[2]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_mkdirat(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.mkdirat(ctx, int32(args[0]), args[1], mode_t(args[2]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_mkdir(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.mkdirat(ctx, AT_FDCWD, args[0], mode_t(args[1]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_unlinkat(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.unlinkat(ctx, int32(args[0]), args[1], int32(args[2]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_unlink(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.unlinkat(ctx, AT_FDCWD, args[0], 0)
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_rmdir(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.unlinkat(ctx, AT_FDCWD, args[0], AT_REMOVEDIR)
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_renameat(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.renameat2(ctx, int32(args[0]), args[1], int32(args[2]), args[3], 0)
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_renameat2(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.renameat2(ctx, int32(args[0]), args[1], int32(args[2]), args[3], uint32(args[4]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_rename(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.renameat2(ctx, AT_FDCWD, args[0], AT_FDCWD, args[1], 0)
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_symlinkat(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.symlinkat(ctx, args[0], int32(args[1]), args[2])
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_symlink(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.symlinkat(ctx, args[0], AT_FDCWD, args[1])
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_linkat(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.linkat(ctx, int32(args[0]), args[1], int32(args[2]), args[3], int32(args[4]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_link(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.linkat(ctx, AT_FDCWD, args[0], AT_FDCWD, args[1], 0)
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_getcwd(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.getcwd(ctx, args[0], ulong_t(args[1]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_chdir(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.chdir(ctx, args[0])
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_fchdir(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.fchdir(ctx, uint32(args[0]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_chroot(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.chroot(ctx, args[0])
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_umask(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.umask(ctx, int32(args[0]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_fchmod(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.fchmod(ctx, uint32(args[0]), mode_t(args[1]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_fchmodat(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.fchmodat2(ctx, int32(args[0]), args[1], mode_t(args[2]), 0)
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_fchmodat2(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.fchmodat2(ctx, int32(args[0]), args[1], mode_t(args[2]), int32(args[3]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_chmod(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.fchmodat2(ctx, AT_FDCWD, args[0], mode_t(args[1]), 0)
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_fchown(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.fchown(ctx, uint32(args[0]), uid_t(args[1]), gid_t(args[2]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_fchownat(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.fchownat(ctx, int32(args[0]), args[1], uid_t(args[2]), gid_t(args[3]), int32(args[4]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_chown(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.fchownat(ctx, AT_FDCWD, args[0], uid_t(args[1]), gid_t(args[2]), 0)
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_lchown(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.fchownat(ctx, AT_FDCWD, args[0], uid_t(args[1]), gid_t(args[2]), AT_SYMLINK_NOFOLLOW)
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_utimensat(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.utimensat(ctx, int32(args[0]), args[1], args[2], int32(args[3]), false)
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_utimensat_time64(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.utimensat(ctx, int32(args[0]), args[1], args[2], int32(args[3]), true)
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_fstatat64(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	var r int32
 	switch ctx.Debugger().Arch() {
 	case emulator.ARCH_ARM, emulator.ARCH_X86:
@@ -213,7 +741,7 @@ func (sys *Syscall) Emulate_fstatat64(ctx linux.Context, args ...uint64) uint64
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_fstat64(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_fstat64(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	var r int32
 	switch ctx.Debugger().Arch() {
 	case emulator.ARCH_ARM, emulator.ARCH_X86:
@@ -227,106 +755,146 @@ func (sys *Syscall) Emulate_fstat64(ctx linux.Context, args ...uint64) uint64 {
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_exit(ctx linux.Context, args ...uint64) uint64 {
-	panic("syscall exit")
+func (sys *Syscall) Emulate_statx(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.fcntl.statx(ctx, int32(args[0]), args[1], int32(args[2]), uint32(args[3]), args[4])
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_exit(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.sched.exit(ctx, int32(args[0]))
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_exit_group(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.sched.exit_group(ctx, int32(args[0]))
+	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_futex(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_set_tid_address(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.sched.set_tid_address(ctx, args[0])
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_futex(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.futex.futex(ctx, args[0], int32(args[1]), uint32(args[2]), args[3], args[4], uint32(args[5]))
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_clock_gettime(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_clock_gettime(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.clock_gettime(ctx, clockid_t(args[0]), args[1])
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_rt_sigaction(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_rt_sigaction(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.signal.rt_sigaction(ctx, int32(args[0]), args[1], args[2], size_t(args[3]))
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_rt_sigprocmask(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_rt_sigprocmask(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.signal.rt_sigprocmask(ctx, int32(args[0]), args[1], args[2], size_t(args[3]))
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_getrlimit(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_getrlimit(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.resource.getrlimit(ctx, int32(args[0]), args[1])
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_setrlimit(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_setrlimit(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.resource.setrlimit(ctx, int32(args[0]), args[1])
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_prctl(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_prctl(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.prctl.prctl(ctx, int32(args[0]), ulong_t(args[1]), ulong_t(args[2]), ulong_t(args[3]), ulong_t(args[4]))
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_gettimeofday(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_gettimeofday(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.gettimeofday(ctx, args[0], args[1])
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_getpid(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_getpid(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.prctl.getpid(ctx)
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_gettid(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_gettid(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.prctl.gettid(ctx)
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_sysinfo(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_sysinfo(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.sysinfo(ctx, args[0])
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_socket(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_socket(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.network.socket(ctx, int32(args[0]), int32(args[1]), int32(args[2]))
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_munmap(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_munmap(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.mman.munmap(ctx, args[0], size_t(args[1]))
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_clone(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_clone(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.sched.clone(ctx, int32(args[0]), args[1], args[2], args[3], args[4])
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_execve(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_execve(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.sched.execve(ctx, args[0], args[1], args[2])
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_mmap(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_set_tls(ctx linux.Context, args *linux.SyscallArgs) uint64 {
+	r := sys.sched.set_tls(ctx, args[0])
+	return uint64(r)
+}
+
+func (sys *Syscall) Emulate_mmap(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.mman.mmap(ctx, args[0], size_t(args[1]), emulator.MemProt(args[2]), int32(args[3]), int32(args[4]), off_t(args[5]))
 	return r
 }
 
-func (sys *Syscall) Emulate_mmap2(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_mmap2(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.mman.mmap2(ctx, args[0], size_t(args[1]), emulator.MemProt(args[2]), int32(args[3]), int32(args[4]), size_t(args[5]))
 	return r
 }
 
-func (sys *Syscall) Emulate_mprotect(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_mprotect(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.mman.mprotect(ctx, args[0], size_t(args[1]), emulator.MemProt(args[2]))
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_rt_tgsigqueueinfo(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_rt_tgsigqueueinfo(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.signal.rt_tgsigqueueinfo(ctx, int32(args[0]), int32(args[1]), int32(args[2]), args[3])
 	return uint64(r)
 }
 
-func (sys *Syscall) Emulate_getrandom(ctx linux.Context, args ...uint64) uint64 {
+func (sys *Syscall) Emulate_getrandom(ctx linux.Context, args *linux.SyscallArgs) uint64 {
 	r := sys.getrandom(ctx, args[0], size_t(args[1]), uint32(args[2]))
 	return uint64(r)
 }
+
+func argLoff(ctx linux.Context, args *linux.SyscallArgs, i int) loff_t {
+	switch ctx.Debugger().Arch() {
+	case emulator.ARCH_ARM:
+		i = alignUp(i, 2)
+		fallthrough
+	case emulator.ARCH_X86:
+		return loff_t(uint64(uint32(args[i])) | args[i+1]<<32)
+	}
+	return loff_t(args[i])
+}
+
+func argPos(ctx linux.Context, args *linux.SyscallArgs, i int) loff_t {
+	switch ctx.Debugger().Arch() {
+	case emulator.ARCH_ARM, emulator.ARCH_X86:
+		return loff_t(uint64(uint32(args[i])) | args[i+1]<<32)
+	}
+	return loff_t(args[i])
+}
@@ -17,13 +17,28 @@ type sysinfo struct {
 	totalswap ulong_t
 	freeswap  ulong_t
 	procs     uint16
-	_f        [22]byte
+	totalhigh ulong_t
+	freehigh  ulong_t
+	mem_unit  uint32
 }
 
-var (
-	_ = sysinfo{}.loads
-	_ = sysinfo{}._f
-)
+func (info *sysinfo) ctype(c *ccodec) {
+	cLong(c, &info.uptime)
+	for i := range info.loads {
+		cULong(c, &info.loads[i])
+	}
+	cULong(c, &info.totalram)
+	cULong(c, &info.freeram)
+	cULong(c, &info.sharedram)
+	cULong(c, &info.bufferram)
+	cULong(c, &info.totalswap)
+	cULong(c, &info.freeswap)
+	cUint16(c, &info.procs)
+	cULong(c, &info.totalhigh)
+	cULong(c, &info.freehigh)
+	cUint32(c, &info.mem_unit)
+	c.pad(20 - 2*c.model.long - 4)
+}
 
 func (*Syscall) sysinfo(ctx linux.Context, info emuptr) int32 {
 	uptime, err := host.Uptime()
@@ -46,7 +61,7 @@ func (*Syscall) sysinfo(ctx linux.Context, info emuptr) int32 {
 		ctx.SetErrno(linux.EINVAL)
 		return -1
 	}
-	_, err = ctx.Debugger().MemWrite(info, sysinfo{
+	err = memWrite(ctx, info, &sysinfo{
 		uptime:    long_t(uptime),
 		totalram:  ulong_t(vm.Total),
 		freeram:   ulong_t(vm.Free),
@@ -55,9 +70,10 @@ func (*Syscall) sysinfo(ctx linux.Context, info emuptr) int32 {
 		totalswap: ulong_t(sm.Total),
 		freeswap:  ulong_t(sm.Free),
 		procs:     uint16(len(pids)),
+		mem_unit:  1,
 	})
 	if err != nil {
-		ctx.SetErrno(linux.EINVAL)
+		ctx.SetErrno(linux.EFAULT)
 		return -1
 	}
 	return 0
@@ -16,6 +16,11 @@ type timespec struct {
 	tv_nsec long_t
 }
 
+type timespec64 struct {
+	tv_sec  int64
+	tv_nsec int64
+}
+
 type timeval struct {
 	tv_sec  time_t
 	tv_usec suseconds_t
@@ -26,29 +31,52 @@ type timezone struct {
 	tz_dsttime     int32
 }
 
+func (ts *timespec) ctype(c *ccodec) {
+	cLong(c, &ts.tv_sec)
+	cLong(c, &ts.tv_nsec)
+}
+
+func (ts *timespec64) ctype(c *ccodec) {
+	cInt64(c, &ts.tv_sec)
+	cInt64(c, &ts.tv_nsec)
+}
+
+func (tv *timeval) ctype(c *ccodec) {
+	cLong(c, &tv.tv_sec)
+	cLong(c, &tv.tv_usec)
+}
+
+func (tz *timezone) ctype(c *ccodec) {
+	cInt32(c, &tz.tz_minuteswest)
+	cInt32(c, &tz.tz_dsttime)
+}
+
 func (sys *Syscall) clock_gettime(ctx linux.Context, clock clockid_t, ts emuptr) int32 {
 	var st C.struct_timespec
 	r := C.clock_gettime(C.clockid_t(clock), &st)
-	if r == 0 {
-		ctx.Debugger().MemWrite(ts, timespec{
-			tv_sec:  time_t(st.tv_sec),
-			tv_nsec: long_t(st.tv_nsec),
-		})
+	if r != 0 {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
 	}
-	return int32(r)
+	memWrite(ctx, ts, &timespec{
+		tv_sec:  time_t(st.tv_sec),
+		tv_nsec: long_t(st.tv_nsec),
+	})
+	return 0
 }
 
 func (sys *Syscall) gettimeofday(ctx linux.Context, tv, tz emuptr) int32 {
-	dbg := ctx.Debugger()
 	now := time.Now()
-	dbg.MemWrite(tv, timeval{
+	memWrite(ctx, tv, &timeval{
 		tv_sec:  time_t(now.Unix()),
 		tv_usec: suseconds_t(now.Nanosecond() / 1e3),
 	})
-	_, offset := now.Zone()
-	dbg.MemWrite(tz, timezone{
-		tz_minuteswest: int32(offset / 60),
-		tz_dsttime:     0,
-	})
+	if tz != emunullptr {
+		_, offset := now.Zone()
+		memWrite(ctx, tz, &timezone{
+			tz_minuteswest: int32(offset / 60),
+			tz_dsttime:     0,
+		})
+	}
 	return 0
 }
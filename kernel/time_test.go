@@ -0,0 +1,58 @@
+package kernel
+
+import (
+	"testing"
+	"time"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/emulator"
+)
+
+func TestClockGettime(t *testing.T) {
+	const CLOCK_REALTIME = 0
+
+	for _, arch := range []emulator.Arch{emulator.ARCH_ARM, emulator.ARCH_ARM64, emulator.ARCH_X86, emulator.ARCH_X86_64} {
+		tk := newTestKernel(t, arch)
+		buf := tk.alloc(16)
+		before := time.Now()
+		if _, errno := tk.call(linux.NR_clock_gettime, CLOCK_REALTIME, buf); errno != 0 {
+			t.Fatalf("%v: clock_gettime errno = %d", arch, errno)
+		}
+		var ts timespec
+		tk.decode(buf, &ts)
+		got := time.Unix(int64(ts.tv_sec), int64(ts.tv_nsec))
+		if got.Before(before.Truncate(time.Second)) || got.Sub(before) > time.Minute {
+			t.Fatalf("%v: clock_gettime = %v, want about %v", arch, got, before)
+		}
+		var bad = int64(-1)
+		if _, errno := tk.call(linux.NR_clock_gettime, uint64(bad), buf); errno != linux.EINVAL {
+			t.Fatalf("%v: clock_gettime(-1) errno = %d, want EINVAL", arch, errno)
+		}
+	}
+}
+
+func TestGettimeofday(t *testing.T) {
+	for _, arch := range []emulator.Arch{emulator.ARCH_ARM, emulator.ARCH_ARM64} {
+		tk := newTestKernel(t, arch)
+		tv := tk.alloc(16)
+		tz := tk.alloc(8)
+		tk.store32(tz, 0xdeadbeef)
+		if _, errno := tk.call(linux.NR_gettimeofday, tv, 0); errno != 0 {
+			t.Fatalf("%v: gettimeofday errno = %d", arch, errno)
+		}
+		var val timeval
+		tk.decode(tv, &val)
+		if d := time.Since(time.Unix(int64(val.tv_sec), 0)); d < 0 || d > time.Minute || val.tv_usec >= 1e6 {
+			t.Fatalf("%v: gettimeofday = %+v", arch, val)
+		}
+		if _, errno := tk.call(linux.NR_gettimeofday, tv, tz); errno != 0 {
+			t.Fatalf("%v: gettimeofday errno = %d", arch, errno)
+		}
+		var zone timezone
+		tk.decode(tz, &zone)
+		_, offset := time.Now().Zone()
+		if zone.tz_minuteswest != int32(offset/60) || zone.tz_dsttime != 0 {
+			t.Fatalf("%v: timezone = %+v", arch, zone)
+		}
+	}
+}
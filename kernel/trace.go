@@ -0,0 +1,688 @@
+package kernel
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+)
+
+type TraceFormat int
+
+const (
+	TraceFormat_Text TraceFormat = iota
+	TraceFormat_JSON
+)
+
+const (
+	traceStringMax = 4096
+	traceBufMax    = 32
+	traceIovMax    = 8
+)
+
+type Tracer struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format TraceFormat
+}
+
+type traceKind uint8
+
+const (
+	traceInt traceKind = iota
+	traceLong
+	traceLoff
+	tracePos
+	traceULong
+	traceHex
+	traceFD
+	traceDirFD
+	tracePath
+	traceInBuf
+	traceOutBuf
+	traceOpenFlags
+	traceFileFlags
+	traceMode
+	traceProt
+	traceMapFlags
+	traceAtFlags
+	traceFcntlCmd
+	traceFutexOp
+	traceSignal
+	traceSigHow
+	traceClockID
+	traceWhence
+	traceTimespec
+	traceTimespecOut
+	traceTimevalOut
+	traceStatOut
+	traceSigaction
+	traceSigactionOut
+	traceSigset
+	traceSigsetOut
+	traceIovec
+	traceIovecOut
+	traceRlimit
+	traceRlimitOut
+)
+
+type traceSig struct {
+	args []traceKind
+	ret  traceKind
+}
+
+type traceFlag struct {
+	bit  uint64
+	name string
+}
+
+type traceEvent struct {
+	TaskID    int      `json:"tid"`
+	No        uint64   `json:"no"`
+	NR        string   `json:"nr"`
+	Args      []string `json:"args"`
+	Ret       int64    `json:"ret"`
+	Errno     string   `json:"errno,omitempty"`
+	Unhandled bool     `json:"unhandled,omitempty"`
+
+	sig   *traceSig
+	model *dataModel
+}
+
+var traceSigs = map[linux.NR]*traceSig{
+	linux.NR_read:              {args: []traceKind{traceFD, traceOutBuf, traceULong}},
+	linux.NR_write:             {args: []traceKind{traceFD, traceInBuf, traceULong}},
+	linux.NR_readv:             {args: []traceKind{traceFD, traceIovecOut, traceInt}},
+	linux.NR_writev:            {args: []traceKind{traceFD, traceIovec, traceInt}},
+	linux.NR_pread64:           {args: []traceKind{traceFD, traceOutBuf, traceULong, traceLoff}},
+	linux.NR_pwrite64:          {args: []traceKind{traceFD, traceInBuf, traceULong, traceLoff}},
+	linux.NR_open:              {args: []traceKind{tracePath, traceOpenFlags, traceMode}},
+	linux.NR_openat:            {args: []traceKind{traceDirFD, tracePath, traceOpenFlags, traceMode}},
+	linux.NR_close:             {args: []traceKind{traceFD}},
+	linux.NR_dup:               {args: []traceKind{traceFD}},
+	linux.NR_dup2:              {args: []traceKind{traceFD, traceFD}},
+	linux.NR_dup3:              {args: []traceKind{traceFD, traceFD, traceFileFlags}},
+	linux.NR_fcntl:             {args: []traceKind{traceFD, traceFcntlCmd, traceHex}},
+	linux.NR_fcntl64:           {args: []traceKind{traceFD, traceFcntlCmd, traceHex}},
+	linux.NR_ioctl:             {args: []traceKind{traceFD, traceHex, traceHex}},
+	linux.NR_lseek:             {args: []traceKind{traceFD, traceLong, traceWhence}},
+	linux.NR_pipe2:             {args: []traceKind{traceHex, traceFileFlags}},
+	linux.NR_access:            {args: []traceKind{tracePath, traceInt}},
+	linux.NR_faccessat:         {args: []traceKind{traceDirFD, tracePath, traceInt}},
+	linux.NR_faccessat2:        {args: []traceKind{traceDirFD, tracePath, traceInt, traceAtFlags}},
+	linux.NR_readlink:          {args: []traceKind{tracePath, traceOutBuf, traceULong}},
+	linux.NR_readlinkat:        {args: []traceKind{traceDirFD, tracePath, traceOutBuf, traceULong}},
+	linux.NR_stat64:            {args: []traceKind{tracePath, traceStatOut}},
+	linux.NR_lstat64:           {args: []traceKind{tracePath, traceStatOut}},
+	linux.NR_fstat64:           {args: []traceKind{traceFD, traceStatOut}},
+	linux.NR_fstatat64:         {args: []traceKind{traceDirFD, tracePath, traceStatOut, traceAtFlags}},
+	linux.NR_statx:             {args: []traceKind{traceDirFD, tracePath, traceAtFlags, traceHex, traceHex}},
+	linux.NR_getdents64:        {args: []traceKind{traceFD, traceHex, traceULong}},
+	linux.NR_mkdirat:           {args: []traceKind{traceDirFD, tracePath, traceMode}},
+	linux.NR_unlinkat:          {args: []traceKind{traceDirFD, tracePath, traceAtFlags}},
+	linux.NR_symlinkat:         {args: []traceKind{tracePath, traceDirFD, tracePath}},
+	linux.NR_linkat:            {args: []traceKind{traceDirFD, tracePath, traceDirFD, tracePath, traceAtFlags}},
+	linux.NR_renameat:          {args: []traceKind{traceDirFD, tracePath, traceDirFD, tracePath}},
+	linux.NR_renameat2:         {args: []traceKind{traceDirFD, tracePath, traceDirFD, tracePath, traceHex}},
+	linux.NR_chdir:             {args: []traceKind{tracePath}},
+	linux.NR_fchdir:            {args: []traceKind{traceFD}},
+	linux.NR_getcwd:            {args: []traceKind{traceHex, traceULong}},
+	linux.NR_mmap:              {args: []traceKind{traceHex, traceULong, traceProt, traceMapFlags, traceFD, traceHex}, ret: traceHex},
+	linux.NR_mmap2:             {args: []traceKind{traceHex, traceULong, traceProt, traceMapFlags, traceFD, traceHex}, ret: traceHex},
+	linux.NR_old_mmap:          {args: []traceKind{traceHex}, ret: traceHex},
+	linux.NR_munmap:            {args: []traceKind{traceHex, traceULong}},
+	linux.NR_mprotect:          {args: []traceKind{traceHex, traceULong, traceProt}},
+	linux.NR_madvise:           {args: []traceKind{traceHex, traceULong, traceInt}},
+	linux.NR_brk:               {args: []traceKind{traceHex}, ret: traceHex},
+	linux.NR_futex:             {args: []traceKind{traceHex, traceFutexOp, traceInt, traceTimespec, traceHex, traceInt}},
+	linux.NR_clock_gettime:     {args: []traceKind{traceClockID, traceTimespecOut}},
+	linux.NR_clock_getres:      {args: []traceKind{traceClockID, traceTimespecOut}},
+	linux.NR_gettimeofday:      {args: []traceKind{traceTimevalOut, traceHex}},
+	linux.NR_nanosleep:         {args: []traceKind{traceTimespec, traceTimespecOut}},
+	linux.NR_rt_sigaction:      {args: []traceKind{traceSignal, traceSigaction, traceSigactionOut, traceULong}},
+	linux.NR_rt_sigprocmask:    {args: []traceKind{traceSigHow, traceSigset, traceSigsetOut, traceULong}},
+	linux.NR_rt_tgsigqueueinfo: {args: []traceKind{traceInt, traceInt, traceSignal, traceHex}},
+	linux.NR_sigaltstack:       {args: []traceKind{traceHex, traceHex}},
+	linux.NR_kill:              {args: []traceKind{traceInt, traceSignal}},
+	linux.NR_tgkill:            {args: []traceKind{traceInt, traceInt, traceSignal}},
+	linux.NR_getrlimit:         {args: []traceKind{traceInt, traceRlimitOut}},
+	linux.NR_setrlimit:         {args: []traceKind{traceInt, traceRlimit}},
+	linux.NR_prlimit64:         {args: []traceKind{traceInt, traceInt, traceRlimit, traceRlimitOut}},
+	linux.NR_prctl:             {args: []traceKind{traceInt, traceHex, traceHex, traceHex, traceHex}},
+	linux.NR_getpid:            {},
+	linux.NR_getppid:           {},
+	linux.NR_gettid:            {},
+	linux.NR_getuid:            {},
+	linux.NR_geteuid:           {},
+	linux.NR_getgid:            {},
+	linux.NR_getegid:           {},
+	linux.NR_sched_yield:       {},
+	linux.NR_set_tid_address:   {args: []traceKind{traceHex}},
+	linux.NR_exit:              {args: []traceKind{traceInt}},
+	linux.NR_exit_group:        {args: []traceKind{traceInt}},
+	linux.NR_clone:             {args: []traceKind{traceHex, traceHex, traceHex, traceHex, traceHex}},
+	linux.NR_execve:            {args: []traceKind{tracePath, traceHex, traceHex}},
+	linux.NR_sysinfo:           {args: []traceKind{traceHex}},
+	linux.NR_socket:            {args: []traceKind{traceInt, traceInt, traceInt}},
+	linux.NR_connect:           {args: []traceKind{traceFD, traceHex, traceInt}},
+	linux.NR_getrandom:         {args: []traceKind{traceOutBuf, traceULong, traceHex}},
+	linux.NR_set_robust_list:   {args: []traceKind{traceHex, traceULong}},
+	linux.NR_epoll_create1:     {args: []traceKind{traceFileFlags}},
+	linux.NR_epoll_ctl:         {args: []traceKind{traceFD, traceInt, traceFD, traceHex}},
+	linux.NR_epoll_pwait:       {args: []traceKind{traceFD, traceHex, traceInt, traceInt, traceHex, traceULong}},
+	linux.NR_sendfile:          {args: []traceKind{traceFD, traceFD, traceHex, traceULong}},
+	linux.NR_sendfile64:        {args: []traceKind{traceFD, traceFD, traceHex, traceULong}},
+	linux.NR_ftruncate:         {args: []traceKind{traceFD, traceLong}},
+	linux.NR_truncate:          {args: []traceKind{tracePath, traceLong}},
+	linux.NR_ftruncate64:       {args: []traceKind{traceFD, traceLoff}},
+	linux.NR_truncate64:        {args: []traceKind{tracePath, traceLoff}},
+	linux.NR_fsync:             {args: []traceKind{traceFD}},
+	linux.NR_fdatasync:         {args: []traceKind{traceFD}},
+	linux.NR_fchmod:            {args: []traceKind{traceFD, traceMode}},
+	linux.NR_fchmodat:          {args: []traceKind{traceDirFD, tracePath, traceMode}},
+	linux.NR_fchmodat2:         {args: []traceKind{traceDirFD, tracePath, traceMode, traceAtFlags}},
+	linux.NR_fchownat:          {args: []traceKind{traceDirFD, tracePath, traceInt, traceInt, traceAtFlags}},
+	linux.NR_chown:             {args: []traceKind{tracePath, traceInt, traceInt}},
+	linux.NR_lchown:            {args: []traceKind{tracePath, traceInt, traceInt}},
+	linux.NR_umask:             {args: []traceKind{traceMode}, ret: traceMode},
+	linux.NR_flock:             {args: []traceKind{traceFD, traceInt}},
+	linux.NR_clock_gettime64:   {args: []traceKind{traceClockID, traceHex}},
+	linux.NR_futex_time64:      {args: []traceKind{traceHex, traceFutexOp, traceInt, traceHex, traceHex, traceInt}},
+	linux.NR_sync_file_range:   {args: []traceKind{traceFD, traceLoff, traceLoff, traceHex}},
+	linux.NR_sync_file_range2:  {args: []traceKind{traceFD, traceHex, traceLoff, traceLoff}},
+	linux.NR_membarrier:        {args: []traceKind{traceInt, traceInt}},
+	linux.NR_sched_getaffinity: {args: []traceKind{traceInt, traceULong, traceHex}},
+	linux.NR_memfd_create:      {args: []traceKind{tracePath, traceHex}},
+	linux.NR_process_vm_readv:  {args: []traceKind{traceInt, traceHex, traceULong, traceHex, traceULong, traceHex}},
+	linux.NR_process_vm_writev: {args: []traceKind{traceInt, traceHex, traceULong, traceHex, traceULong, traceHex}},
+	linux.NR_inotify_add_watch: {args: []traceKind{traceFD, tracePath, traceHex}},
+	linux.NR_inotify_rm_watch:  {args: []traceKind{traceFD, traceInt}},
+	linux.NR_inotify_init1:     {args: []traceKind{traceFileFlags}},
+	linux.NR_eventfd2:          {args: []traceKind{traceInt, traceFileFlags}},
+	linux.NR_set_thread_area:   {args: []traceKind{traceHex}},
+	linux.NR_arch_prctl:        {args: []traceKind{traceHex, traceHex}},
+	linux.NR_set_tls:           {args: []traceKind{traceHex}},
+	linux.NR_rseq:              {args: []traceKind{traceHex, traceULong, traceHex, traceHex}},
+	linux.NR_close_range:       {args: []traceKind{traceFD, traceFD, traceHex}},
+	linux.NR_clock_nanosleep:   {args: []traceKind{traceClockID, traceHex, traceTimespec, traceTimespecOut}},
+	linux.NR_sched_setaffinity: {args: []traceKind{traceInt, traceULong, traceHex}},
+	linux.NR_restart_syscall:   {},
+	linux.NR_rt_sigreturn:      {},
+	linux.NR_rt_sigsuspend:     {args: []traceKind{traceSigset, traceULong}},
+	linux.NR_rt_sigtimedwait:   {args: []traceKind{traceSigset, traceHex, traceTimespec, traceULong}},
+	linux.NR_rt_sigpending:     {args: []traceKind{traceSigsetOut, traceULong}},
+	linux.NR_rt_sigqueueinfo:   {args: []traceKind{traceInt, traceSignal, traceHex}},
+	linux.NR_getpriority:       {args: []traceKind{traceInt, traceInt}},
+	linux.NR_setpriority:       {args: []traceKind{traceInt, traceInt, traceInt}},
+	linux.NR_personality:       {args: []traceKind{traceHex}},
+	linux.NR_uname:             {args: []traceKind{traceHex}},
+	linux.NR_wait4:             {args: []traceKind{traceInt, traceHex, traceHex, traceHex}},
+	linux.NR_getsockopt:        {args: []traceKind{traceFD, traceInt, traceInt, traceHex, traceHex}},
+	linux.NR_setsockopt:        {args: []traceKind{traceFD, traceInt, traceInt, traceHex, traceInt}},
+	linux.NR_ppoll:             {args: []traceKind{traceHex, traceInt, traceTimespec, traceSigset, traceULong}},
+	linux.NR_pselect6:          {args: []traceKind{traceInt, traceHex, traceHex, traceHex, traceTimespec, traceHex}},
+	linux.NR_mremap:            {args: []traceKind{traceHex, traceULong, traceULong, traceHex, traceHex}, ret: traceHex},
+	linux.NR_msync:             {args: []traceKind{traceHex, traceULong, traceHex}},
+	linux.NR_mlock:             {args: []traceKind{traceHex, traceULong}},
+	linux.NR_munlock:           {args: []traceKind{traceHex, traceULong}},
+	linux.NR_mincore:           {args: []traceKind{traceHex, traceULong, traceHex}},
+	linux.NR_get_robust_list:   {args: []traceKind{traceInt, traceHex, traceHex}},
+	linux.NR_getresuid:         {args: []traceKind{traceHex, traceHex, traceHex}},
+	linux.NR_getresgid:         {args: []traceKind{traceHex, traceHex, traceHex}},
+	linux.NR_capget:            {args: []traceKind{traceHex, traceHex}},
+	linux.NR_statfs:            {args: []traceKind{tracePath, traceHex}},
+	linux.NR_fstatfs:           {args: []traceKind{traceFD, traceHex}},
+	linux.NR_fallocate:         {args: []traceKind{traceFD, traceHex, traceLoff, traceLoff}},
+	linux.NR_copy_file_range:   {args: []traceKind{traceFD, traceHex, traceFD, traceHex, traceULong, traceHex}},
+	linux.NR_splice:            {args: []traceKind{traceFD, traceHex, traceFD, traceHex, traceULong, traceHex}},
+	linux.NR_tee:               {args: []traceKind{traceFD, traceFD, traceULong, traceHex}},
+	linux.NR_utimensat:         {args: []traceKind{traceDirFD, tracePath, traceHex, traceAtFlags}},
+	linux.NR_utimensat_time64:  {args: []traceKind{traceDirFD, tracePath, traceHex, traceAtFlags}},
+	linux.NR_fchown:            {args: []traceKind{traceFD, traceInt, traceInt}},
+	linux.NR_openat2:           {args: []traceKind{traceDirFD, tracePath, traceHex, traceULong}},
+	linux.NR_preadv:            {args: []traceKind{traceFD, traceIovecOut, traceInt, tracePos}},
+	linux.NR_pwritev:           {args: []traceKind{traceFD, traceIovec, traceInt, tracePos}},
+	linux.NR_preadv2:           {args: []traceKind{traceFD, traceIovecOut, traceInt, tracePos, traceHex}},
+	linux.NR_pwritev2:          {args: []traceKind{traceFD, traceIovec, traceInt, tracePos, traceHex}},
+	linux.NR_chroot:            {args: []traceKind{tracePath}},
+	linux.NR_sync:              {},
+	linux.NR_syncfs:            {args: []traceKind{traceFD}},
+	linux.NR_epoll_create:      {args: []traceKind{traceInt}},
+	linux.NR_epoll_wait:        {args: []traceKind{traceFD, traceHex, traceInt, traceInt}},
+	linux.NR_pipe:              {args: []traceKind{traceHex}},
+	linux.NR_mkdir:             {args: []traceKind{tracePath, traceMode}},
+	linux.NR_rmdir:             {args: []traceKind{tracePath}},
+	linux.NR_unlink:            {args: []traceKind{tracePath}},
+	linux.NR_rename:            {args: []traceKind{tracePath, tracePath}},
+	linux.NR_symlink:           {args: []traceKind{tracePath, tracePath}},
+	linux.NR_link:              {args: []traceKind{tracePath, tracePath}},
+	linux.NR_chmod:             {args: []traceKind{tracePath, traceMode}},
+	linux.NR_creat:             {args: []traceKind{tracePath, traceMode}},
+}
+
+var (
+	traceOpenFlagNames = []traceFlag{
+		{0x40, "O_CREAT"}, {0x80, "O_EXCL"}, {0x100, "O_NOCTTY"}, {0x200, "O_TRUNC"},
+		{0x400, "O_APPEND"}, {0x800, "O_NONBLOCK"}, {0x1000, "O_DSYNC"}, {0x2000, "O_ASYNC"},
+		{0x4000, "O_DIRECT"}, {0x8000, "O_LARGEFILE"}, {0x10000, "O_DIRECTORY"}, {0x20000, "O_NOFOLLOW"},
+		{0x40000, "O_NOATIME"}, {0x80000, "O_CLOEXEC"}, {0x100000, "O_SYNC"}, {0x200000, "O_PATH"},
+		{0x400000, "O_TMPFILE"},
+	}
+	traceProtNames = []traceFlag{
+		{0x1, "PROT_READ"}, {0x2, "PROT_WRITE"}, {0x4, "PROT_EXEC"},
+	}
+	traceMapFlagNames = []traceFlag{
+		{0x01, "MAP_SHARED"}, {0x02, "MAP_PRIVATE"}, {0x10, "MAP_FIXED"}, {0x20, "MAP_ANONYMOUS"},
+		{0x100, "MAP_GROWSDOWN"}, {0x800, "MAP_DENYWRITE"}, {0x1000, "MAP_EXECUTABLE"}, {0x2000, "MAP_LOCKED"},
+		{0x4000, "MAP_NORESERVE"}, {0x8000, "MAP_POPULATE"}, {0x10000, "MAP_NONBLOCK"}, {0x20000, "MAP_STACK"},
+		{0x100000, "MAP_FIXED_NOREPLACE"},
+	}
+	traceAtFlagNames = []traceFlag{
+		{0x100, "AT_SYMLINK_NOFOLLOW"}, {0x200, "AT_REMOVEDIR"}, {0x400, "AT_SYMLINK_FOLLOW"},
+		{0x800, "AT_NO_AUTOMOUNT"}, {0x1000, "AT_EMPTY_PATH"},
+	}
+	traceFutexFlagNames = []traceFlag{
+		{0x80, "FUTEX_PRIVATE_FLAG"}, {0x100, "FUTEX_CLOCK_REALTIME"},
+	}
+	traceFcntlCmds = map[uint64]string{
+		0: "F_DUPFD", 1: "F_GETFD", 2: "F_SETFD", 3: "F_GETFL", 4: "F_SETFL", 5: "F_GETLK", 6: "F_SETLK",
+		7: "F_SETLKW", 8: "F_SETOWN", 9: "F_GETOWN", 10: "F_SETSIG", 11: "F_GETSIG", 12: "F_GETLK64",
+		13: "F_SETLK64", 14: "F_SETLKW64", 36: "F_OFD_GETLK", 37: "F_OFD_SETLK", 38: "F_OFD_SETLKW",
+		1024: "F_SETLEASE", 1025: "F_GETLEASE", 1030: "F_DUPFD_CLOEXEC", 1031: "F_SETPIPE_SZ",
+		1032: "F_GETPIPE_SZ", 1033: "F_ADD_SEALS", 1034: "F_GET_SEALS",
+	}
+	traceFutexCmds = map[uint64]string{
+		0: "FUTEX_WAIT", 1: "FUTEX_WAKE", 2: "FUTEX_FD", 3: "FUTEX_REQUEUE", 4: "FUTEX_CMP_REQUEUE",
+		5: "FUTEX_WAKE_OP", 6: "FUTEX_LOCK_PI", 7: "FUTEX_UNLOCK_PI", 8: "FUTEX_TRYLOCK_PI",
+		9: "FUTEX_WAIT_BITSET", 10: "FUTEX_WAKE_BITSET", 11: "FUTEX_WAIT_REQUEUE_PI", 12: "FUTEX_CMP_REQUEUE_PI",
+	}
+	traceSignals = map[uint64]string{
+		1: "SIGHUP", 2: "SIGINT", 3: "SIGQUIT", 4: "SIGILL", 5: "SIGTRAP", 6: "SIGABRT", 7: "SIGBUS",
+		8: "SIGFPE", 9: "SIGKILL", 10: "SIGUSR1", 11: "SIGSEGV", 12: "SIGUSR2", 13: "SIGPIPE", 14: "SIGALRM",
+		15: "SIGTERM", 16: "SIGSTKFLT", 17: "SIGCHLD", 18: "SIGCONT", 19: "SIGSTOP", 20: "SIGTSTP",
+		21: "SIGTTIN", 22: "SIGTTOU", 23: "SIGURG", 24: "SIGXCPU", 25: "SIGXFSZ", 26: "SIGVTALRM",
+		27: "SIGPROF", 28: "SIGWINCH", 29: "SIGIO", 30: "SIGPWR", 31: "SIGSYS",
+	}
+	traceSigHows = map[uint64]string{
+		0: "SIG_BLOCK", 1: "SIG_UNBLOCK", 2: "SIG_SETMASK",
+	}
+	traceClockIDs = map[uint64]string{
+		0: "CLOCK_REALTIME", 1: "CLOCK_MONOTONIC", 2: "CLOCK_PROCESS_CPUTIME_ID", 3: "CLOCK_THREAD_CPUTIME_ID",
+		4: "CLOCK_MONOTONIC_RAW", 5: "CLOCK_REALTIME_COARSE", 6: "CLOCK_MONOTONIC_COARSE", 7: "CLOCK_BOOTTIME",
+		8: "CLOCK_REALTIME_ALARM", 9: "CLOCK_BOOTTIME_ALARM", 11: "CLOCK_TAI",
+	}
+	traceWhences = map[uint64]string{
+		0: "SEEK_SET", 1: "SEEK_CUR", 2: "SEEK_END", 3: "SEEK_DATA", 4: "SEEK_HOLE",
+	}
+	traceFileTypes = map[mode_t]string{
+		S_IFIFO: "S_IFIFO", S_IFCHR: "S_IFCHR", S_IFDIR: "S_IFDIR", S_IFBLK: "S_IFBLK",
+		S_IFREG: "S_IFREG", S_IFLNK: "S_IFLNK", S_IFSOCK: "S_IFSOCK",
+	}
+)
+
+func NewTracer(w io.Writer, format TraceFormat) *Tracer {
+	return &Tracer{w: w, format: format}
+}
+
+func (t *Tracer) enter(ctx debugger.Context, nr linux.NR, no uint64, args *linux.SyscallArgs) *traceEvent {
+	model := modelOf(ctx.Debugger().Arch())
+	ev := &traceEvent{TaskID: ctx.TaskID(), No: no, NR: nr.String(), model: model}
+	sig, ok := traceSigs[nr]
+	if !ok {
+		if nr <= linux.NR_ignore {
+			ev.NR = "syscall_" + strconv.FormatUint(no, 10)
+		}
+		ev.Args = make([]string, len(args))
+		for i := range args {
+			ev.Args[i] = traceRaw(model.word(args[i]))
+		}
+		return ev
+	}
+	ev.sig = sig
+	ev.Args = make([]string, len(sig.args))
+	for i, kind := range sig.args {
+		if !kind.out() {
+			ev.Args[i] = traceArg(ctx, model, kind, args, sig.reg(model, i), 0)
+		}
+	}
+	return ev
+}
+
+func (t *Tracer) exit(ctx debugger.Context, ev *traceEvent, args *linux.SyscallArgs, ret uint64, errno linux.Errno) {
+	ev.Ret = ev.model.signed(ret)
+	if errno != 0 {
+		ev.Errno = errno.String()
+	}
+	if ev.sig != nil {
+		for i, kind := range ev.sig.args {
+			if !kind.out() {
+				continue
+			} else if reg := ev.sig.reg(ev.model, i); errno != 0 {
+				ev.Args[i] = traceHexString(ev.model.word(args[reg]))
+			} else {
+				ev.Args[i] = traceArg(ctx, ev.model, kind, args, reg, ret)
+			}
+		}
+	}
+	t.emit(ev)
+}
+
+func (t *Tracer) unhandled(ctx debugger.Context, nr linux.NR, no uint64, abi *syscallABI) {
+	var args linux.SyscallArgs
+	for i, reg := range abi.args {
+		args[i], _ = ctx.RegRead(reg)
+	}
+	ev := t.enter(ctx, nr, no, &args)
+	ev.Unhandled = true
+	for i, arg := range ev.Args {
+		if arg == "" {
+			ev.Args[i] = traceRaw(ev.model.word(args[ev.sig.reg(ev.model, i)]))
+		}
+	}
+	t.emit(ev)
+}
+
+func (t *Tracer) emit(ev *traceEvent) {
+	var line []byte
+	switch t.format {
+	case TraceFormat_JSON:
+		line, _ = json.Marshal(ev)
+		line = append(line, '\n')
+	default:
+		line = []byte(ev.String() + "\n")
+	}
+	t.mu.Lock()
+	t.w.Write(line)
+	t.mu.Unlock()
+}
+
+func (ev *traceEvent) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[%d] %s(%s) = ", ev.TaskID, ev.NR, strings.Join(ev.Args, ", "))
+	switch {
+	case ev.Unhandled:
+		sb.WriteString("? <unhandled>")
+	case ev.Errno != "":
+		fmt.Fprintf(&sb, "-1 %s", ev.Errno)
+	case ev.sig != nil && ev.sig.ret == traceHex:
+		sb.WriteString(traceHexString(uint64(ev.Ret)))
+	case ev.sig != nil && ev.sig.ret == traceMode:
+		sb.WriteString(traceOctal(uint64(ev.Ret)))
+	default:
+		sb.WriteString(strconv.FormatInt(ev.Ret, 10))
+	}
+	return sb.String()
+}
+
+// reg returns the register holding the i-th argument, as 32-bit ABIs pass
+// 64-bit offsets in register pairs.
+func (sig *traceSig) reg(model *dataModel, i int) int {
+	var reg int
+	for _, kind := range sig.args[:i] {
+		reg = kind.align(model, reg) + kind.words(model)
+	}
+	return sig.args[i].align(model, reg)
+}
+
+func (kind traceKind) out() bool {
+	switch kind {
+	case traceOutBuf, traceTimespecOut, traceTimevalOut, traceStatOut, traceSigactionOut, traceSigsetOut, traceIovecOut, traceRlimitOut:
+		return true
+	}
+	return false
+}
+
+func (kind traceKind) align(model *dataModel, reg int) int {
+	if kind == traceLoff && model.arch == emulator.ARCH_ARM {
+		return alignUp(reg, 2)
+	}
+	return reg
+}
+
+func (kind traceKind) words(model *dataModel) int {
+	if kind == tracePos || kind == traceLoff && model.long == 4 {
+		return 2
+	}
+	return 1
+}
+
+func (model *dataModel) word(v uint64) uint64 {
+	if model.long == 4 {
+		return uint64(uint32(v))
+	}
+	return v
+}
+
+func (model *dataModel) signed(v uint64) int64 {
+	if model.long == 4 {
+		return int64(int32(v))
+	}
+	return int64(v)
+}
+
+func traceArg(ctx debugger.Context, model *dataModel, kind traceKind, args *linux.SyscallArgs, i int, ret uint64) string {
+	v := model.word(args[i])
+	switch kind {
+	case traceInt, traceFD:
+		return strconv.Itoa(int(int32(v)))
+	case traceLong:
+		return strconv.FormatInt(model.signed(v), 10)
+	case traceLoff, tracePos:
+		if model.long == 4 {
+			v |= model.word(args[i+1]) << 32
+		}
+		return strconv.FormatInt(int64(v), 10)
+	case traceULong:
+		return strconv.FormatUint(v, 10)
+	case traceDirFD:
+		if int32(v) == AT_FDCWD {
+			return "AT_FDCWD"
+		}
+		return strconv.Itoa(int(int32(v)))
+	case tracePath:
+		if v == emunullptr {
+			return "NULL"
+		}
+		s, err := ctx.ToPointer(v).MemReadString()
+		if err != nil {
+			return traceHexString(v)
+		}
+		if len(s) > traceStringMax {
+			return strconv.Quote(s[:traceStringMax]) + "..."
+		}
+		return strconv.Quote(s)
+	case traceInBuf:
+		return traceBuf(ctx, v, model.word(args[i+1]))
+	case traceOutBuf:
+		return traceBuf(ctx, v, uint64(model.signed(ret)))
+	case traceOpenFlags:
+		v = uint64(uint32(openFlags(model.arch, int32(v))))
+		var acc string
+		switch v & 3 {
+		case 0:
+			acc = "O_RDONLY"
+		case 1:
+			acc = "O_WRONLY"
+		case 2:
+			acc = "O_RDWR"
+		default:
+			acc = "O_ACCMODE"
+		}
+		if rest := v &^ 3; rest != 0 {
+			return acc + "|" + traceFlags(rest, traceOpenFlagNames)
+		}
+		return acc
+	case traceFileFlags:
+		return traceFlags(uint64(uint32(openFlags(model.arch, int32(v)))), traceOpenFlagNames)
+	case traceMode:
+		return traceOctal(v)
+	case traceProt:
+		if v == 0 {
+			return "PROT_NONE"
+		}
+		return traceFlags(v, traceProtNames)
+	case traceMapFlags:
+		return traceFlags(v, traceMapFlagNames)
+	case traceAtFlags:
+		return traceFlags(v, traceAtFlagNames)
+	case traceFcntlCmd:
+		return traceEnum(v, traceFcntlCmds)
+	case traceFutexOp:
+		const FUTEX_CMD_MASK = 0x7f
+		s := traceEnum(v&FUTEX_CMD_MASK, traceFutexCmds)
+		if rest := v &^ FUTEX_CMD_MASK; rest != 0 {
+			s += "|" + traceFlags(rest, traceFutexFlagNames)
+		}
+		return s
+	case traceSignal:
+		return traceEnum(v, traceSignals)
+	case traceSigHow:
+		return traceEnum(v, traceSigHows)
+	case traceClockID:
+		return traceEnum(v, traceClockIDs)
+	case traceWhence:
+		return traceEnum(v, traceWhences)
+	case traceTimespec, traceTimespecOut:
+		var ts timespec
+		return traceStruct(ctx, v, &ts, func() string {
+			return fmt.Sprintf("{tv_sec=%d, tv_nsec=%d}", ts.tv_sec, ts.tv_nsec)
+		})
+	case traceTimevalOut:
+		var tv timeval
+		return traceStruct(ctx, v, &tv, func() string {
+			return fmt.Sprintf("{tv_sec=%d, tv_usec=%d}", tv.tv_sec, tv.tv_usec)
+		})
+	case traceStatOut:
+		if model.long == 4 {
+			var st stat3264
+			return traceStruct(ctx, v, &st, func() string {
+				return fmt.Sprintf("{st_mode=%s, st_size=%d}", traceFileMode(st.st_mode), st.st_size)
+			})
+		}
+		var st stat64
+		return traceStruct(ctx, v, &st, func() string {
+			return fmt.Sprintf("{st_mode=%s, st_size=%d}", traceFileMode(st.st_mode), st.st_size)
+		})
+	case traceSigaction, traceSigactionOut:
+		var act sigaction
+		return traceStruct(ctx, v, &act, func() string {
+			return fmt.Sprintf("{sa_handler=%s, sa_mask=%#x, sa_flags=%#x, sa_restorer=%s}", traceHexString(act.sa_handler), uint64(act.sa_mask), uint64(act.sa_flags), traceHexString(act.sa_restorer))
+		})
+	case traceSigset, traceSigsetOut:
+		var set sigset_t
+		return traceStruct(ctx, v, &set, func() string {
+			return fmt.Sprintf("[%#x]", uint64(set))
+		})
+	case traceRlimit, traceRlimitOut:
+		var rl rlimit
+		return traceStruct(ctx, v, &rl, func() string {
+			return fmt.Sprintf("{rlim_cur=%d, rlim_max=%d}", rl.rlim_cur, rl.rlim_max)
+		})
+	case traceIovec, traceIovecOut:
+		n := min(int(int32(model.word(args[i+1]))), traceIovMax)
+		if v == emunullptr || n < 0 {
+			return traceHexString(v)
+		}
+		arr := make([]iovec, n)
+		if memExtractArray(ctx, v, arr) != nil {
+			return traceHexString(v)
+		}
+		left := ^uint64(0)
+		if kind == traceIovecOut {
+			left = uint64(model.signed(ret))
+		}
+		items := make([]string, n)
+		for j := range arr {
+			size := min(uint64(arr[j].iov_len), left)
+			left -= size
+			items[j] = fmt.Sprintf("{iov_base=%s, iov_len=%d}", traceBuf(ctx, arr[j].iov_base, size), arr[j].iov_len)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return traceHexString(v)
+}
+
+func traceHexString(v uint64) string {
+	if v == 0 {
+		return "NULL"
+	}
+	return "0x" + strconv.FormatUint(v, 16)
+}
+
+func traceRaw(v uint64) string {
+	if v == 0 {
+		return "0"
+	}
+	return "0x" + strconv.FormatUint(v, 16)
+}
+
+func traceOctal(v uint64) string {
+	if v == 0 {
+		return "0"
+	}
+	return "0" + strconv.FormatUint(v, 8)
+}
+
+func traceBuf(ctx debugger.Context, addr, size uint64) string {
+	if addr == emunullptr {
+		return "NULL"
+	}
+	n := min(size, traceBufMax)
+	b, err := ctx.ToPointer(addr).MemRead(n)
+	if err != nil {
+		return traceHexString(addr)
+	}
+	s := strconv.Quote(string(b))
+	if n < size {
+		s += "..."
+	}
+	return s
+}
+
+func traceStruct(ctx debugger.Context, addr emuptr, v ctype, format func() string) string {
+	if addr == emunullptr {
+		return "NULL"
+	} else if memExtract(ctx, addr, v) != nil {
+		return traceHexString(addr)
+	}
+	return format()
+}
+
+func traceFlags(v uint64, names []traceFlag) string {
+	if v == 0 {
+		return "0"
+	}
+	var parts []string
+	for _, flag := range names {
+		if v&flag.bit != 0 {
+			parts = append(parts, flag.name)
+			v &^= flag.bit
+		}
+	}
+	if v != 0 {
+		parts = append(parts, "0x"+strconv.FormatUint(v, 16))
+	}
+	return strings.Join(parts, "|")
+}
+
+func traceEnum(v uint64, names map[uint64]string) string {
+	if name, ok := names[v]; ok {
+		return name
+	}
+	return strconv.FormatUint(v, 10)
+}
+
+func traceFileMode(mode mode_t) string {
+	const S_IFMT = 0xF000
+	perm := traceOctal(uint64(mode &^ S_IFMT))
+	if name, ok := traceFileTypes[mode&S_IFMT]; ok {
+		return name + "|" + perm
+	}
+	return perm
+}
@@ -0,0 +1,122 @@
+package kernel
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/emulator"
+	emu_arm "github.com/wnxd/microdbg/emulator/arm"
+	emu_arm64 "github.com/wnxd/microdbg/emulator/arm64"
+)
+
+func (tk *testKernel) trap(nr linux.NR, args ...uint64) uint64 {
+	tk.tb.Helper()
+	no, ok := tk.No(nr)
+	if !ok {
+		tk.tb.Fatalf("%v: no syscall number", nr)
+	}
+	ctx := tk.ctx
+	ctx.regs[emu_arm64.ARM64_REG_X8] = no
+	for i := range 6 {
+		ctx.regs[emu_arm64.ARM64_REG_X0+emulator.Reg(i)] = 0
+		if i < len(args) {
+			ctx.regs[emu_arm64.ARM64_REG_X0+emulator.Reg(i)] = args[i]
+		}
+	}
+	tk.handleIntr(ctx, emu_arm.ARM_INTR_EXCP_SWI, nil)
+	return ctx.regs[emu_arm64.ARM64_REG_X0]
+}
+
+func TestTracerText(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	var buf bytes.Buffer
+	tk.SetTracer(NewTracer(&buf, TraceFormat_Text))
+	tk.trap(linux.NR_openat, uint64(testAT_FDCWD), tk.cstring("missing"), 0x80000, 0)
+	data := tk.cstring("hi\n")
+	tk.trap(linux.NR_write, 1000, data, 3)
+	tk.trap(linux.NR_bpf, 1, 2)
+	tk.SetTracer(nil)
+	tk.trap(linux.NR_gettid)
+	want := []string{
+		`[1] openat(AT_FDCWD, "missing", O_RDONLY|O_CLOEXEC, 0) = -1 ENOENT`,
+		`[1] write(1000, "hi\n", 3) = -1 EBADF`,
+		`[1] bpf(0x1, 0x2, 0, 0, 0, 0) = ? <unhandled>`,
+	}
+	if got := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("trace =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestTracerJSON(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	var buf bytes.Buffer
+	tk.SetTracer(NewTracer(&buf, TraceFormat_JSON))
+	ts := tk.alloc(16)
+	tk.trap(linux.NR_clock_gettime, 1, ts)
+	var ev struct {
+		TaskID int      `json:"tid"`
+		No     uint64   `json:"no"`
+		NR     string   `json:"nr"`
+		Args   []string `json:"args"`
+		Ret    int64    `json:"ret"`
+		Errno  string   `json:"errno"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &ev); err != nil {
+		t.Fatal(err)
+	}
+	no, _ := tk.No(linux.NR_clock_gettime)
+	if ev.TaskID != 1 || ev.No != no || ev.NR != "clock_gettime" || ev.Ret != 0 || ev.Errno != "" || len(ev.Args) != 2 {
+		t.Fatalf("event = %+v", ev)
+	}
+	if ev.Args[0] != "CLOCK_MONOTONIC" || !strings.HasPrefix(ev.Args[1], "{tv_sec=") {
+		t.Fatalf("args = %q", ev.Args)
+	}
+}
+
+func TestTracerRegPairs(t *testing.T) {
+	tests := []struct {
+		arch emulator.Arch
+		nr   linux.NR
+		args linux.SyscallArgs
+		want string
+	}{
+		{emulator.ARCH_ARM, linux.NR_pread64, linux.SyscallArgs{3, 0, 0, 0, 0x10, 0x1}, "pread64(3, NULL, 0, 4294967312)"},
+		{emulator.ARCH_ARM, linux.NR_ftruncate64, linux.SyscallArgs{3, 0, 0x10, 0x1}, "ftruncate64(3, 4294967312)"},
+		{emulator.ARCH_ARM, linux.NR_fallocate, linux.SyscallArgs{3, 0, 0x10, 0x1, 0x20, 0}, "fallocate(3, NULL, 4294967312, 32)"},
+		{emulator.ARCH_ARM, linux.NR_sync_file_range2, linux.SyscallArgs{3, 2, 0x10, 0x1, 0x20, 0}, "sync_file_range2(3, 0x2, 4294967312, 32)"},
+		{emulator.ARCH_X86, linux.NR_pwrite64, linux.SyscallArgs{3, 0, 0, 0x10, 0x1}, "pwrite64(3, NULL, 0, 4294967312)"},
+		{emulator.ARCH_X86, linux.NR_sync_file_range, linux.SyscallArgs{3, 0x10, 0x1, 0x20, 0, 4}, "sync_file_range(3, 4294967312, 32, 0x4)"},
+		{emulator.ARCH_ARM, linux.NR_pwritev2, linux.SyscallArgs{3, 0, 0, 0x10, 0x1, 8}, "pwritev2(3, NULL, 0, 4294967312, 0x8)"},
+		{emulator.ARCH_ARM64, linux.NR_pwritev2, linux.SyscallArgs{3, 0, 0, 0x100000010, 0, 8}, "pwritev2(3, NULL, 0, 4294967312, 0x8)"},
+		{emulator.ARCH_ARM64, linux.NR_pread64, linux.SyscallArgs{3, 0, 0, 0x100000010}, "pread64(3, NULL, 0, 4294967312)"},
+	}
+	for _, tt := range tests {
+		tk := newTestKernel(t, tt.arch)
+		tr := NewTracer(io.Discard, TraceFormat_Text)
+		ev := tr.enter(tk.ctx, tt.nr, 0, &tt.args)
+		tr.exit(tk.ctx, ev, &tt.args, 0, 0)
+		if got := strings.TrimPrefix(ev.String(), "[1] "); got != tt.want+" = 0" {
+			t.Errorf("%v: trace = %s, want %s = 0", tt.arch, got, tt.want)
+		}
+	}
+}
+
+func TestTracerReadv(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	fd := tk.create("file", []byte("abcdefgh"))
+	tk.call(linux.NR_lseek, uint64(fd), 0, 0)
+	var buf bytes.Buffer
+	tk.SetTracer(NewTracer(&buf, TraceFormat_Text))
+	a, b := tk.alloc(3), tk.alloc(16)
+	iov := tk.iovecs(iovec{a, 3}, iovec{b, 16})
+	tk.trap(linux.NR_readv, uint64(fd), iov, 2)
+	want := fmt.Sprintf(`[1] readv(%d, [{iov_base="abc", iov_len=3}, {iov_base="defgh", iov_len=16}], 2) = 8`, fd)
+	if got := strings.TrimSuffix(buf.String(), "\n"); got != want {
+		t.Fatalf("trace = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,254 @@
+package kernel
+
+import (
+	"io"
+	"io/fs"
+	"math"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+const (
+	FALLOC_FL_KEEP_SIZE      = 0x01
+	FALLOC_FL_PUNCH_HOLE     = 0x02
+	FALLOC_FL_NO_HIDE_STALE  = 0x04
+	FALLOC_FL_COLLAPSE_RANGE = 0x08
+	FALLOC_FL_ZERO_RANGE     = 0x10
+	FALLOC_FL_INSERT_RANGE   = 0x20
+	FALLOC_FL_UNSHARE_RANGE  = 0x40
+
+	SYNC_FILE_RANGE_WAIT_BEFORE = 0x1
+	SYNC_FILE_RANGE_WRITE       = 0x2
+	SYNC_FILE_RANGE_WAIT_AFTER  = 0x4
+)
+
+type TruncateFile interface {
+	filesystem.File
+	Truncate(size int64) error
+}
+
+type SyncFile interface {
+	filesystem.File
+	Sync() error
+}
+
+func (f *fcntl) truncate(ctx linux.Context, pathname emuptr, length loff_t) int32 {
+	if length < 0 {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	dir, name, errno := f.pathat(ctx, AT_FDCWD, pathname)
+	if errno == 0 {
+		name, errno = f.followLink(dir, name)
+	}
+	if errno != 0 {
+		ctx.SetErrno(errno)
+		return -1
+	}
+	if info, err := stat(dir, name); err != nil {
+		ctx.SetErrno(linux.ToErrno(err))
+		return -1
+	} else if info.IsDir() {
+		ctx.SetErrno(linux.EISDIR)
+		return -1
+	} else if !info.Mode().IsRegular() {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	file, err := dir.OpenFile(name, filesystem.O_WRONLY, 0)
+	if err != nil {
+		ctx.SetErrno(linux.ToErrno(err))
+		return -1
+	}
+	defer file.Close()
+	trunc, ok := file.(TruncateFile)
+	if !ok {
+		ctx.SetErrno(linux.EROFS)
+		return -1
+	}
+	err = trunc.Truncate(int64(length))
+	if err != nil {
+		ctx.SetErrno(linux.ToErrno(err))
+		return -1
+	}
+	return 0
+}
+
+func (f *fcntl) ftruncate(ctx linux.Context, fd uint32, length loff_t) int32 {
+	if length < 0 {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	f.rw.RLock()
+	status := f.flags[int(fd)]
+	f.rw.RUnlock()
+	file, err := f.getFile(ctx.Debugger(), int(fd))
+	if err != nil || status&O_PATH != 0 {
+		ctx.SetErrno(linux.EBADF)
+		return -1
+	}
+	if info, err := file.Stat(); err != nil {
+		ctx.SetErrno(linux.ToErrno(err))
+		return -1
+	} else if !info.Mode().IsRegular() || !f.writable(int(fd)) {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	trunc, ok := file.(TruncateFile)
+	if !ok {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	err = trunc.Truncate(int64(length))
+	if err != nil {
+		ctx.SetErrno(linux.ToErrno(err))
+		return -1
+	}
+	return 0
+}
+
+func (f *fcntl) fallocate(ctx linux.Context, fd uint32, mode int32, offset, length loff_t) int32 {
+	switch {
+	case mode&^(FALLOC_FL_KEEP_SIZE|FALLOC_FL_PUNCH_HOLE) != 0:
+		ctx.SetErrno(linux.EOPNOTSUPP)
+		return -1
+	case offset < 0 || length <= 0:
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	case mode&FALLOC_FL_PUNCH_HOLE != 0 && mode&FALLOC_FL_KEEP_SIZE == 0:
+		ctx.SetErrno(linux.EOPNOTSUPP)
+		return -1
+	}
+	file, err := f.getFile(ctx.Debugger(), int(fd))
+	if err != nil || !f.writable(int(fd)) {
+		ctx.SetErrno(linux.EBADF)
+		return -1
+	}
+	info, err := file.Stat()
+	switch {
+	case err != nil:
+		ctx.SetErrno(linux.ToErrno(err))
+		return -1
+	case info.IsDir():
+		ctx.SetErrno(linux.EISDIR)
+		return -1
+	case info.Mode()&fs.ModeNamedPipe != 0:
+		ctx.SetErrno(linux.ESPIPE)
+		return -1
+	case !info.Mode().IsRegular():
+		ctx.SetErrno(linux.ENODEV)
+		return -1
+	case offset > math.MaxInt64-length:
+		ctx.SetErrno(linux.EFBIG)
+		return -1
+	}
+	end := int64(offset + length)
+	switch {
+	case mode&FALLOC_FL_PUNCH_HOLE != 0:
+		w, ok := file.(io.WriterAt)
+		if !ok {
+			ctx.SetErrno(linux.EOPNOTSUPP)
+			return -1
+		}
+		err = zeroRange(w, int64(offset), min(end, info.Size()))
+	case mode&FALLOC_FL_KEEP_SIZE != 0, end <= info.Size():
+	default:
+		trunc, ok := file.(TruncateFile)
+		if !ok {
+			ctx.SetErrno(linux.EOPNOTSUPP)
+			return -1
+		}
+		err = trunc.Truncate(end)
+	}
+	if err != nil {
+		ctx.SetErrno(linux.ToErrno(err))
+		return -1
+	}
+	return 0
+}
+
+func (f *fcntl) fsync(ctx linux.Context, fd uint32) int32 {
+	f.rw.RLock()
+	status := f.flags[int(fd)]
+	f.rw.RUnlock()
+	file, err := f.getFile(ctx.Debugger(), int(fd))
+	if err != nil || status&O_PATH != 0 {
+		ctx.SetErrno(linux.EBADF)
+		return -1
+	}
+	sync, ok := file.(SyncFile)
+	if !ok {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	err = sync.Sync()
+	if err != nil {
+		ctx.SetErrno(linux.ToErrno(err))
+		return -1
+	}
+	return 0
+}
+
+func (f *fcntl) fdatasync(ctx linux.Context, fd uint32) int32 {
+	return f.fsync(ctx, fd)
+}
+
+func (f *fcntl) sync_file_range(ctx linux.Context, fd uint32, offset, nbytes loff_t, flags uint32) int32 {
+	if flags&^(SYNC_FILE_RANGE_WAIT_BEFORE|SYNC_FILE_RANGE_WRITE|SYNC_FILE_RANGE_WAIT_AFTER) != 0 || offset < 0 || nbytes < 0 || offset > math.MaxInt64-nbytes {
+		ctx.SetErrno(linux.EINVAL)
+		return -1
+	}
+	file, err := f.getFile(ctx.Debugger(), int(fd))
+	if err != nil {
+		ctx.SetErrno(linux.EBADF)
+		return -1
+	}
+	info, err := file.Stat()
+	if err != nil {
+		ctx.SetErrno(linux.ToErrno(err))
+		return -1
+	} else if !info.Mode().IsRegular() && !info.IsDir() && info.Mode()&(fs.ModeSymlink|fs.ModeDevice) == 0 {
+		ctx.SetErrno(linux.ESPIPE)
+		return -1
+	}
+	if sync, ok := file.(SyncFile); ok && flags&SYNC_FILE_RANGE_WRITE != 0 {
+		err = sync.Sync()
+		if err != nil {
+			ctx.SetErrno(linux.ToErrno(err))
+			return -1
+		}
+	}
+	return 0
+}
+
+func (f *fcntl) syncfs(ctx linux.Context, fd uint32) int32 {
+	file, err := f.getFile(ctx.Debugger(), int(fd))
+	if err != nil {
+		ctx.SetErrno(linux.EBADF)
+		return -1
+	}
+	if sync, ok := file.(SyncFile); ok {
+		err = sync.Sync()
+		if err != nil {
+			ctx.SetErrno(linux.ToErrno(err))
+			return -1
+		}
+	}
+	return 0
+}
+
+func zeroRange(w io.WriterAt, off, end int64) error {
+	if off >= end {
+		return nil
+	}
+	zero := make([]byte, min(end-off, spliceChunk))
+	for off < end {
+		n, err := w.WriteAt(zero[:min(end-off, int64(len(zero)))], off)
+		if err != nil {
+			return err
+		}
+		off += int64(n)
+	}
+	return nil
+}
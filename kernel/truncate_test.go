@@ -0,0 +1,198 @@
+package kernel
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	linux "github.com/wnxd/microdbg-linux"
+	"github.com/wnxd/microdbg/emulator"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+type plainFS struct {
+	filesystem.FS
+}
+
+type plainFile struct {
+	filesystem.File
+}
+
+func (p plainFS) OpenFile(name string, flag filesystem.FileFlag, perm fs.FileMode) (filesystem.File, error) {
+	file, err := p.FS.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return plainFile{file}, nil
+}
+
+func (tk *testKernel) size(name string) int64 {
+	tk.tb.Helper()
+	info, err := os.Stat(filepath.Join(tk.dbg.root, name))
+	if err != nil {
+		tk.tb.Fatal(err)
+	}
+	return info.Size()
+}
+
+func TestTruncate(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	at := uint64(testAT_FDCWD)
+	fd := uint64(tk.create("file", []byte("0123456789")))
+	if _, errno := tk.call(linux.NR_ftruncate, fd, 4); errno != 0 || tk.readFile("file") != "0123" {
+		t.Fatalf("ftruncate shrink errno = %v, file = %q", errno, tk.readFile("file"))
+	}
+	if _, errno := tk.call(linux.NR_truncate, tk.cstring("file"), 8); errno != 0 || tk.readFile("file") != "0123\x00\x00\x00\x00" {
+		t.Fatalf("truncate extend errno = %v, file = %q", errno, tk.readFile("file"))
+	}
+	tk.call(linux.NR_mkdirat, at, tk.cstring("dir"), 0755)
+	rdonly, _ := tk.call(linux.NR_openat, at, tk.cstring("file"), 0, 0)
+	r, _ := tk.pipe()
+	opath, errno := tk.call(linux.NR_openat, at, tk.cstring("file"), O_PATH, 0)
+	if errno != 0 {
+		t.Fatalf("openat(O_PATH) errno = %v", errno)
+	}
+	tests := []struct {
+		name  string
+		nr    linux.NR
+		args  []uint64
+		errno linux.Errno
+	}{
+		{"ftruncate negative", linux.NR_ftruncate, []uint64{fd, ^uint64(0)}, linux.EINVAL},
+		{"ftruncate rdonly", linux.NR_ftruncate, []uint64{rdonly, 0}, linux.EINVAL},
+		{"ftruncate pipe", linux.NR_ftruncate, []uint64{r, 0}, linux.EINVAL},
+		{"ftruncate badf", linux.NR_ftruncate, []uint64{1000, 0}, linux.EBADF},
+		{"ftruncate O_PATH", linux.NR_ftruncate, []uint64{opath, 0}, linux.EBADF},
+		{"fsync O_PATH", linux.NR_fsync, []uint64{opath}, linux.EBADF},
+		{"truncate dir", linux.NR_truncate, []uint64{tk.cstring("dir"), 0}, linux.EISDIR},
+		{"truncate missing", linux.NR_truncate, []uint64{tk.cstring("missing"), 0}, linux.ENOENT},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, errno := tk.call(tt.nr, tt.args...); errno != tt.errno {
+				t.Fatalf("errno = %v, want %v", errno, tt.errno)
+			}
+		})
+	}
+}
+
+func TestTruncate64(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM)
+	fd := uint64(tk.create("file", []byte("0123456789")))
+	if _, errno := tk.call(linux.NR_ftruncate64, fd, 0, 1<<32|2, 0); errno != 0 || tk.size("file") != 2 {
+		t.Fatalf("ftruncate64 errno = %v, size = %d", errno, tk.size("file"))
+	}
+	if _, errno := tk.call(linux.NR_truncate64, tk.cstring("file"), 0, 6, 0); errno != 0 || tk.size("file") != 6 {
+		t.Fatalf("truncate64 errno = %v, size = %d", errno, tk.size("file"))
+	}
+}
+
+func TestFallocate(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	at := uint64(testAT_FDCWD)
+	fd := uint64(tk.create("file", []byte("0123456789")))
+	if _, errno := tk.call(linux.NR_fallocate, fd, 0, 4, 16); errno != 0 || tk.size("file") != 20 {
+		t.Fatalf("fallocate errno = %v, size = %d", errno, tk.size("file"))
+	}
+	if _, errno := tk.call(linux.NR_fallocate, fd, 0, 0, 8); errno != 0 || tk.size("file") != 20 {
+		t.Fatalf("fallocate inside errno = %v, size = %d", errno, tk.size("file"))
+	}
+	if _, errno := tk.call(linux.NR_fallocate, fd, FALLOC_FL_KEEP_SIZE, 0, 64); errno != 0 || tk.size("file") != 20 {
+		t.Fatalf("fallocate KEEP_SIZE errno = %v, size = %d", errno, tk.size("file"))
+	}
+	if _, errno := tk.call(linux.NR_fallocate, fd, FALLOC_FL_PUNCH_HOLE|FALLOC_FL_KEEP_SIZE, 2, 3); errno != 0 || tk.readFile("file")[:10] != "01\x00\x00\x0056789" {
+		t.Fatalf("fallocate PUNCH_HOLE errno = %v, file = %q", errno, tk.readFile("file"))
+	}
+	if _, errno := tk.call(linux.NR_fallocate, fd, FALLOC_FL_PUNCH_HOLE|FALLOC_FL_KEEP_SIZE, 18, 10); errno != 0 || tk.size("file") != 20 {
+		t.Fatalf("fallocate PUNCH_HOLE past EOF errno = %v, size = %d", errno, tk.size("file"))
+	}
+	tk.call(linux.NR_mkdirat, at, tk.cstring("dir"), 0755)
+	dir, _ := tk.call(linux.NR_openat, at, tk.cstring("dir"), 0, 0)
+	rdonly, _ := tk.call(linux.NR_openat, at, tk.cstring("file"), 0, 0)
+	_, w := tk.pipe()
+	tests := []struct {
+		name  string
+		args  []uint64
+		errno linux.Errno
+	}{
+		{"punch without keep", []uint64{fd, FALLOC_FL_PUNCH_HOLE, 0, 1}, linux.EOPNOTSUPP},
+		{"zero range", []uint64{fd, FALLOC_FL_ZERO_RANGE, 0, 1}, linux.EOPNOTSUPP},
+		{"zero length", []uint64{fd, 0, 0, 0}, linux.EINVAL},
+		{"negative offset", []uint64{fd, 0, ^uint64(0), 1}, linux.EINVAL},
+		{"overflow", []uint64{fd, 0, 1 << 62, 1 << 62}, linux.EFBIG},
+		{"rdonly", []uint64{rdonly, 0, 0, 1}, linux.EBADF},
+		{"dir", []uint64{dir, 0, 0, 1}, linux.EBADF},
+		{"pipe", []uint64{w, 0, 0, 1}, linux.ESPIPE},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, errno := tk.call(linux.NR_fallocate, tt.args...); errno != tt.errno {
+				t.Fatalf("errno = %v, want %v", errno, tt.errno)
+			}
+		})
+	}
+}
+
+func TestFsync(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_X86)
+	at := uint64(testAT_FDCWD)
+	fd := uint64(tk.create("file", []byte("data")))
+	tk.call(linux.NR_mkdirat, at, tk.cstring("dir"), 0755)
+	dir, _ := tk.call(linux.NR_openat, at, tk.cstring("dir"), 0, 0)
+	r, _ := tk.pipe()
+	tests := []struct {
+		name  string
+		nr    linux.NR
+		args  []uint64
+		errno linux.Errno
+	}{
+		{"fsync", linux.NR_fsync, []uint64{fd}, 0},
+		{"fsync dir", linux.NR_fsync, []uint64{dir}, 0},
+		{"fsync badf", linux.NR_fsync, []uint64{1000}, linux.EBADF},
+		{"fdatasync", linux.NR_fdatasync, []uint64{fd}, 0},
+		{"sync_file_range", linux.NR_sync_file_range, []uint64{fd, 0, 0, 4, 0, SYNC_FILE_RANGE_WRITE}, 0},
+		{"sync_file_range flags", linux.NR_sync_file_range, []uint64{fd, 0, 0, 0, 0, 8}, linux.EINVAL},
+		{"sync_file_range negative", linux.NR_sync_file_range, []uint64{fd, 0, 0x80000000, 0, 0, 0}, linux.EINVAL},
+		{"sync_file_range overflow", linux.NR_sync_file_range, []uint64{fd, 0, 0x7FFFFFFF, 0, 0x7FFFFFFF, 0}, linux.EINVAL},
+		{"sync_file_range pipe", linux.NR_sync_file_range, []uint64{r, 0, 0, 0, 0, 0}, linux.ESPIPE},
+		{"syncfs", linux.NR_syncfs, []uint64{fd}, 0},
+		{"syncfs badf", linux.NR_syncfs, []uint64{1000}, linux.EBADF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, errno := tk.call(tt.nr, tt.args...); errno != tt.errno {
+				t.Fatalf("errno = %v, want %v", errno, tt.errno)
+			}
+		})
+	}
+}
+
+func TestTruncateUnsupported(t *testing.T) {
+	tk := newTestKernel(t, emulator.ARCH_ARM64)
+	tk.create("file", nil)
+	tk.dbg.fs = plainFS{tk.dbg.fs}
+	fd, errno := tk.call(linux.NR_openat, uint64(testAT_FDCWD), tk.cstring("file"), testO_RDWR, 0)
+	if errno != 0 {
+		t.Fatalf("openat errno = %v", errno)
+	}
+	tests := []struct {
+		name  string
+		nr    linux.NR
+		args  []uint64
+		errno linux.Errno
+	}{
+		{"truncate", linux.NR_truncate, []uint64{tk.cstring("file"), 0}, linux.EROFS},
+		{"ftruncate", linux.NR_ftruncate, []uint64{fd, 0}, linux.EINVAL},
+		{"fallocate", linux.NR_fallocate, []uint64{fd, 0, 0, 8}, linux.EOPNOTSUPP},
+		{"fsync", linux.NR_fsync, []uint64{fd}, linux.EINVAL},
+		{"syncfs", linux.NR_syncfs, []uint64{fd}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, errno := tk.call(tt.nr, tt.args...); errno != tt.errno {
+				t.Fatalf("errno = %v, want %v", errno, tt.errno)
+			}
+		})
+	}
+}
@@ -0,0 +1,385 @@
+package kernel
+
+import linux "github.com/wnxd/microdbg-linux"
+
+var x86_64NR = [...]linux.NR{
+	0:   linux.NR_read,
+	1:   linux.NR_write,
+	2:   linux.NR_open,
+	3:   linux.NR_close,
+	4:   linux.NR_stat64,
+	5:   linux.NR_fstat64,
+	6:   linux.NR_lstat64,
+	7:   linux.NR_poll,
+	8:   linux.NR_lseek,
+	9:   linux.NR_mmap,
+	10:  linux.NR_mprotect,
+	11:  linux.NR_munmap,
+	12:  linux.NR_brk,
+	13:  linux.NR_rt_sigaction,
+	14:  linux.NR_rt_sigprocmask,
+	15:  linux.NR_rt_sigreturn,
+	16:  linux.NR_ioctl,
+	17:  linux.NR_pread64,
+	18:  linux.NR_pwrite64,
+	19:  linux.NR_readv,
+	20:  linux.NR_writev,
+	21:  linux.NR_access,
+	22:  linux.NR_pipe,
+	23:  linux.NR_select,
+	24:  linux.NR_sched_yield,
+	25:  linux.NR_mremap,
+	26:  linux.NR_msync,
+	27:  linux.NR_mincore,
+	28:  linux.NR_madvise,
+	29:  linux.NR_shmget,
+	30:  linux.NR_shmat,
+	31:  linux.NR_shmctl,
+	32:  linux.NR_dup,
+	33:  linux.NR_dup2,
+	34:  linux.NR_pause,
+	35:  linux.NR_nanosleep,
+	36:  linux.NR_getitimer,
+	37:  linux.NR_alarm,
+	38:  linux.NR_setitimer,
+	39:  linux.NR_getpid,
+	40:  linux.NR_sendfile,
+	41:  linux.NR_socket,
+	42:  linux.NR_connect,
+	43:  linux.NR_accept,
+	44:  linux.NR_sendto,
+	45:  linux.NR_recvfrom,
+	46:  linux.NR_sendmsg,
+	47:  linux.NR_recvmsg,
+	48:  linux.NR_shutdown,
+	49:  linux.NR_bind,
+	50:  linux.NR_listen,
+	51:  linux.NR_getsockname,
+	52:  linux.NR_getpeername,
+	53:  linux.NR_socketpair,
+	54:  linux.NR_setsockopt,
+	55:  linux.NR_getsockopt,
+	56:  linux.NR_clone,
+	57:  linux.NR_fork,
+	58:  linux.NR_vfork,
+	59:  linux.NR_execve,
+	60:  linux.NR_exit,
+	61:  linux.NR_wait4,
+	62:  linux.NR_kill,
+	63:  linux.NR_uname,
+	64:  linux.NR_semget,
+	65:  linux.NR_semop,
+	66:  linux.NR_semctl,
+	67:  linux.NR_shmdt,
+	68:  linux.NR_msgget,
+	69:  linux.NR_msgsnd,
+	70:  linux.NR_msgrcv,
+	71:  linux.NR_msgctl,
+	72:  linux.NR_fcntl,
+	73:  linux.NR_flock,
+	74:  linux.NR_fsync,
+	75:  linux.NR_fdatasync,
+	76:  linux.NR_truncate,
+	77:  linux.NR_ftruncate,
+	78:  linux.NR_getdents,
+	79:  linux.NR_getcwd,
+	80:  linux.NR_chdir,
+	81:  linux.NR_fchdir,
+	82:  linux.NR_rename,
+	83:  linux.NR_mkdir,
+	84:  linux.NR_rmdir,
+	85:  linux.NR_creat,
+	86:  linux.NR_link,
+	87:  linux.NR_unlink,
+	88:  linux.NR_symlink,
+	89:  linux.NR_readlink,
+	90:  linux.NR_chmod,
+	91:  linux.NR_fchmod,
+	92:  linux.NR_chown,
+	93:  linux.NR_fchown,
+	94:  linux.NR_lchown,
+	95:  linux.NR_umask,
+	96:  linux.NR_gettimeofday,
+	97:  linux.NR_getrlimit,
+	98:  linux.NR_getrusage,
+	99:  linux.NR_sysinfo,
+	100: linux.NR_times,
+	101: linux.NR_ptrace,
+	102: linux.NR_getuid,
+	103: linux.NR_syslog,
+	104: linux.NR_getgid,
+	105: linux.NR_setuid,
+	106: linux.NR_setgid,
+	107: linux.NR_geteuid,
+	108: linux.NR_getegid,
+	109: linux.NR_setpgid,
+	110: linux.NR_getppid,
+	111: linux.NR_getpgrp,
+	112: linux.NR_setsid,
+	113: linux.NR_setreuid,
+	114: linux.NR_setregid,
+	115: linux.NR_getgroups,
+	116: linux.NR_setgroups,
+	117: linux.NR_setresuid,
+	118: linux.NR_getresuid,
+	119: linux.NR_setresgid,
+	120: linux.NR_getresgid,
+	121: linux.NR_getpgid,
+	122: linux.NR_setfsuid,
+	123: linux.NR_setfsgid,
+	124: linux.NR_getsid,
+	125: linux.NR_capget,
+	126: linux.NR_capset,
+	127: linux.NR_rt_sigpending,
+	128: linux.NR_rt_sigtimedwait,
+	129: linux.NR_rt_sigqueueinfo,
+	130: linux.NR_rt_sigsuspend,
+	131: linux.NR_sigaltstack,
+	132: linux.NR_utime,
+	133: linux.NR_mknod,
+	134: linux.NR_uselib,
+	135: linux.NR_personality,
+	136: linux.NR_ustat,
+	137: linux.NR_statfs,
+	138: linux.NR_fstatfs,
+	139: linux.NR_sysfs,
+	140: linux.NR_getpriority,
+	141: linux.NR_setpriority,
+	142: linux.NR_sched_setparam,
+	143: linux.NR_sched_getparam,
+	144: linux.NR_sched_setscheduler,
+	145: linux.NR_sched_getscheduler,
+	146: linux.NR_sched_get_priority_max,
+	147: linux.NR_sched_get_priority_min,
+	148: linux.NR_sched_rr_get_interval,
+	149: linux.NR_mlock,
+	150: linux.NR_munlock,
+	151: linux.NR_mlockall,
+	152: linux.NR_munlockall,
+	153: linux.NR_vhangup,
+	154: linux.NR_modify_ldt,
+	155: linux.NR_pivot_root,
+	156: linux.NR_sysctl,
+	157: linux.NR_prctl,
+	158: linux.NR_arch_prctl,
+	159: linux.NR_adjtimex,
+	160: linux.NR_setrlimit,
+	161: linux.NR_chroot,
+	162: linux.NR_sync,
+	163: linux.NR_acct,
+	164: linux.NR_settimeofday,
+	165: linux.NR_mount,
+	166: linux.NR_umount2,
+	167: linux.NR_swapon,
+	168: linux.NR_swapoff,
+	169: linux.NR_reboot,
+	170: linux.NR_sethostname,
+	171: linux.NR_setdomainname,
+	172: linux.NR_iopl,
+	173: linux.NR_ioperm,
+	174: linux.NR_create_module,
+	175: linux.NR_init_module,
+	176: linux.NR_delete_module,
+	177: linux.NR_get_kernel_syms,
+	178: linux.NR_query_module,
+	179: linux.NR_quotactl,
+	180: linux.NR_nfsservctl,
+	181: linux.NR_getpmsg,
+	182: linux.NR_putpmsg,
+	183: linux.NR_afs_syscall,
+	184: linux.NR_tuxcall,
+	185: linux.NR_security,
+	186: linux.NR_gettid,
+	187: linux.NR_readahead,
+	188: linux.NR_setxattr,
+	189: linux.NR_lsetxattr,
+	190: linux.NR_fsetxattr,
+	191: linux.NR_getxattr,
+	192: linux.NR_lgetxattr,
+	193: linux.NR_fgetxattr,
+	194: linux.NR_listxattr,
+	195: linux.NR_llistxattr,
+	196: linux.NR_flistxattr,
+	197: linux.NR_removexattr,
+	198: linux.NR_lremovexattr,
+	199: linux.NR_fremovexattr,
+	200: linux.NR_tkill,
+	201: linux.NR_time,
+	202: linux.NR_futex,
+	203: linux.NR_sched_setaffinity,
+	204: linux.NR_sched_getaffinity,
+	205: linux.NR_set_thread_area,
+	206: linux.NR_io_setup,
+	207: linux.NR_io_destroy,
+	208: linux.NR_io_getevents,
+	209: linux.NR_io_submit,
+	210: linux.NR_io_cancel,
+	211: linux.NR_get_thread_area,
+	212: linux.NR_lookup_dcookie,
+	213: linux.NR_epoll_create,
+	214: linux.NR_epoll_ctl_old,
+	215: linux.NR_epoll_wait_old,
+	216: linux.NR_remap_file_pages,
+	217: linux.NR_getdents64,
+	218: linux.NR_set_tid_address,
+	219: linux.NR_restart_syscall,
+	220: linux.NR_semtimedop,
+	221: linux.NR_fadvise64,
+	222: linux.NR_timer_create,
+	223: linux.NR_timer_settime,
+	224: linux.NR_timer_gettime,
+	225: linux.NR_timer_getoverrun,
+	226: linux.NR_timer_delete,
+	227: linux.NR_clock_settime,
+	228: linux.NR_clock_gettime,
+	229: linux.NR_clock_getres,
+	230: linux.NR_clock_nanosleep,
+	231: linux.NR_exit_group,
+	232: linux.NR_epoll_wait,
+	233: linux.NR_epoll_ctl,
+	234: linux.NR_tgkill,
+	235: linux.NR_utimes,
+	236: linux.NR_vserver,
+	237: linux.NR_mbind,
+	238: linux.NR_set_mempolicy,
+	239: linux.NR_get_mempolicy,
+	240: linux.NR_mq_open,
+	241: linux.NR_mq_unlink,
+	242: linux.NR_mq_timedsend,
+	243: linux.NR_mq_timedreceive,
+	244: linux.NR_mq_notify,
+	245: linux.NR_mq_getsetattr,
+	246: linux.NR_kexec_load,
+	247: linux.NR_waitid,
+	248: linux.NR_add_key,
+	249: linux.NR_request_key,
+	250: linux.NR_keyctl,
+	251: linux.NR_ioprio_set,
+	252: linux.NR_ioprio_get,
+	253: linux.NR_inotify_init,
+	254: linux.NR_inotify_add_watch,
+	255: linux.NR_inotify_rm_watch,
+	256: linux.NR_migrate_pages,
+	257: linux.NR_openat,
+	258: linux.NR_mkdirat,
+	259: linux.NR_mknodat,
+	260: linux.NR_fchownat,
+	261: linux.NR_futimesat,
+	262: linux.NR_fstatat64,
+	263: linux.NR_unlinkat,
+	264: linux.NR_renameat,
+	265: linux.NR_linkat,
+	266: linux.NR_symlinkat,
+	267: linux.NR_readlinkat,
+	268: linux.NR_fchmodat,
+	269: linux.NR_faccessat,
+	270: linux.NR_pselect6,
+	271: linux.NR_ppoll,
+	272: linux.NR_unshare,
+	273: linux.NR_set_robust_list,
+	274: linux.NR_get_robust_list,
+	275: linux.NR_splice,
+	276: linux.NR_tee,
+	277: linux.NR_sync_file_range,
+	278: linux.NR_vmsplice,
+	279: linux.NR_move_pages,
+	280: linux.NR_utimensat,
+	281: linux.NR_epoll_pwait,
+	282: linux.NR_signalfd,
+	283: linux.NR_timerfd_create,
+	284: linux.NR_eventfd,
+	285: linux.NR_fallocate,
+	286: linux.NR_timerfd_settime,
+	287: linux.NR_timerfd_gettime,
+	288: linux.NR_accept4,
+	289: linux.NR_signalfd4,
+	290: linux.NR_eventfd2,
+	291: linux.NR_epoll_create1,
+	292: linux.NR_dup3,
+	293: linux.NR_pipe2,
+	294: linux.NR_inotify_init1,
+	295: linux.NR_preadv,
+	296: linux.NR_pwritev,
+	297: linux.NR_rt_tgsigqueueinfo,
+	298: linux.NR_perf_event_open,
+	299: linux.NR_recvmmsg,
+	300: linux.NR_fanotify_init,
+	301: linux.NR_fanotify_mark,
+	302: linux.NR_prlimit64,
+	303: linux.NR_name_to_handle_at,
+	304: linux.NR_open_by_handle_at,
+	305: linux.NR_clock_adjtime,
+	306: linux.NR_syncfs,
+	307: linux.NR_sendmmsg,
+	308: linux.NR_setns,
+	309: linux.NR_getcpu,
+	310: linux.NR_process_vm_readv,
+	311: linux.NR_process_vm_writev,
+	312: linux.NR_kcmp,
+	313: linux.NR_finit_module,
+	314: linux.NR_sched_setattr,
+	315: linux.NR_sched_getattr,
+	316: linux.NR_renameat2,
+	317: linux.NR_seccomp,
+	318: linux.NR_getrandom,
+	319: linux.NR_memfd_create,
+	320: linux.NR_kexec_file_load,
+	321: linux.NR_bpf,
+	322: linux.NR_execveat,
+	323: linux.NR_userfaultfd,
+	324: linux.NR_membarrier,
+	325: linux.NR_mlock2,
+	326: linux.NR_copy_file_range,
+	327: linux.NR_preadv2,
+	328: linux.NR_pwritev2,
+	329: linux.NR_pkey_mprotect,
+	330: linux.NR_pkey_alloc,
+	331: linux.NR_pkey_free,
+	332: linux.NR_statx,
+	333: linux.NR_io_pgetevents,
+	334: linux.NR_rseq,
+	335: linux.NR_uretprobe,
+	424: linux.NR_pidfd_send_signal,
+	425: linux.NR_io_uring_setup,
+	426: linux.NR_io_uring_enter,
+	427: linux.NR_io_uring_register,
+	428: linux.NR_open_tree,
+	429: linux.NR_move_mount,
+	430: linux.NR_fsopen,
+	431: linux.NR_fsconfig,
+	432: linux.NR_fsmount,
+	433: linux.NR_fspick,
+	434: linux.NR_pidfd_open,
+	435: linux.NR_clone3,
+	436: linux.NR_close_range,
+	437: linux.NR_openat2,
+	438: linux.NR_pidfd_getfd,
+	439: linux.NR_faccessat2,
+	440: linux.NR_process_madvise,
+	441: linux.NR_epoll_pwait2,
+	442: linux.NR_mount_setattr,
+	443: linux.NR_quotactl_fd,
+	444: linux.NR_landlock_create_ruleset,
+	445: linux.NR_landlock_add_rule,
+	446: linux.NR_landlock_restrict_self,
+	447: linux.NR_memfd_secret,
+	448: linux.NR_process_mrelease,
+	449: linux.NR_futex_waitv,
+	450: linux.NR_set_mempolicy_home_node,
+	451: linux.NR_cachestat,
+	452: linux.NR_fchmodat2,
+	453: linux.NR_map_shadow_stack,
+	454: linux.NR_futex_wake,
+	455: linux.NR_futex_wait,
+	456: linux.NR_futex_requeue,
+	457: linux.NR_statmount,
+	458: linux.NR_listmount,
+	459: linux.NR_lsm_get_self_attr,
+	460: linux.NR_lsm_set_self_attr,
+	461: linux.NR_lsm_list_modules,
+	462: linux.NR_mseal,
+	463: linux.NR_setxattrat,
+	464: linux.NR_getxattrat,
+	465: linux.NR_listxattrat,
+	466: linux.NR_removexattrat,
+}
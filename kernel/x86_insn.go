@@ -0,0 +1,257 @@
+package kernel
+
+const x86MaxInsnLen = 15
+
+// x86InsnLen returns the length of the x86-64 instruction at the start of b,
+// or 0 when it is truncated or uses an encoding that is not recognised.
+func x86InsnLen(b []byte) int {
+	var i int
+	var opsize, adsize, rexW bool
+prefixes:
+	for ; i < len(b); i++ {
+		switch b[i] {
+		case 0x66:
+			opsize = true
+		case 0x67:
+			adsize = true
+		case 0xf0, 0xf2, 0xf3, 0x26, 0x2e, 0x36, 0x3e, 0x64, 0x65:
+		default:
+			break prefixes
+		}
+	}
+	if i < len(b) && b[i]&0xf0 == 0x40 {
+		rexW = b[i]&8 != 0
+		i++
+	}
+	if i >= len(b) {
+		return 0
+	}
+	immZ := 4
+	if opsize {
+		immZ = 2
+	}
+	op := b[i]
+	i++
+	var modrm, ok bool
+	var imm int
+	switch op {
+	case 0x0f:
+		if i >= len(b) {
+			return 0
+		}
+		op = b[i]
+		i++
+		switch {
+		case (op == 0x38 || op == 0x3a) && i >= len(b):
+			return 0
+		case op == 0x38:
+			i++
+			modrm, ok = true, true
+		case op == 0x3a:
+			i++
+			modrm, imm, ok = true, 1, true
+		default:
+			modrm, imm, ok = x86Map1(op)
+		}
+	case 0xc4, 0xc5, 0x62:
+		var n int
+		modrm, imm, n, ok = x86VEX(b[i:], op)
+		i += n
+	default:
+		modrm, imm, ok = x86Map0(op, immZ, adsize, rexW)
+	}
+	if !ok {
+		return 0
+	}
+	if modrm {
+		n := x86ModRMLen(b[i:])
+		if n == 0 {
+			return 0
+		} else if imm < 0 {
+			imm = 0
+			if b[i]>>3&7 < 2 && op == 0xf6 {
+				imm = 1
+			} else if b[i]>>3&7 < 2 {
+				imm = immZ
+			}
+		}
+		i += n
+	}
+	i += imm
+	if i > len(b) || i > x86MaxInsnLen {
+		return 0
+	}
+	return i
+}
+
+func x86Map0(op byte, immZ int, adsize, rexW bool) (modrm bool, imm int, ok bool) {
+	switch {
+	case op < 0x40:
+		switch op & 7 {
+		case 0, 1, 2, 3:
+			return true, 0, true
+		case 4:
+			return false, 1, true
+		case 5:
+			return false, immZ, true
+		}
+		return false, 0, false
+	case op < 0x50:
+		return false, 0, false
+	case op < 0x60:
+		return false, 0, true
+	case op == 0x63:
+		return true, 0, true
+	case op == 0x68:
+		return false, immZ, true
+	case op == 0x69:
+		return true, immZ, true
+	case op == 0x6a:
+		return false, 1, true
+	case op == 0x6b:
+		return true, 1, true
+	case op < 0x6c:
+		return false, 0, false
+	case op < 0x70:
+		return false, 0, true
+	case op < 0x80:
+		return false, 1, true
+	case op == 0x80, op == 0x83:
+		return true, 1, true
+	case op == 0x81:
+		return true, immZ, true
+	case op == 0x82:
+		return false, 0, false
+	case op < 0x90:
+		return true, 0, true
+	case op == 0x9a:
+		return false, 0, false
+	case op < 0xa0:
+		return false, 0, true
+	case op < 0xa4:
+		if adsize {
+			return false, 4, true
+		}
+		return false, 8, true
+	case op == 0xa8:
+		return false, 1, true
+	case op == 0xa9:
+		return false, immZ, true
+	case op < 0xb0:
+		return false, 0, true
+	case op < 0xb8:
+		return false, 1, true
+	case op < 0xc0:
+		if rexW {
+			return false, 8, true
+		}
+		return false, immZ, true
+	case op == 0xc0, op == 0xc1, op == 0xc6:
+		return true, 1, true
+	case op == 0xc2, op == 0xca:
+		return false, 2, true
+	case op == 0xc7:
+		return true, immZ, true
+	case op == 0xc8:
+		return false, 3, true
+	case op == 0xcd:
+		return false, 1, true
+	case op == 0xce, op == 0xd4, op == 0xd5, op == 0xd6, op == 0xea:
+		return false, 0, false
+	case op < 0xd0:
+		return false, 0, true
+	case op < 0xd4:
+		return true, 0, true
+	case op == 0xd7:
+		return false, 0, true
+	case op < 0xe0:
+		return true, 0, true
+	case op < 0xe8, op == 0xeb:
+		return false, 1, true
+	case op < 0xea:
+		return false, 4, true
+	case op < 0xf0:
+		return false, 0, true
+	case op == 0xf6, op == 0xf7:
+		return true, -1, true
+	case op == 0xfe, op == 0xff:
+		return true, 0, true
+	case op == 0xf0, op == 0xf2, op == 0xf3:
+		return false, 0, false
+	}
+	return false, 0, true
+}
+
+func x86Map1(op byte) (modrm bool, imm int, ok bool) {
+	switch {
+	case op == 0x04, op == 0x0a, op == 0x0c, op >= 0x24 && op < 0x28, op == 0x36, op == 0x39, op >= 0x3b && op < 0x40, op == 0xa6, op == 0xa7:
+		return false, 0, false
+	case op >= 0x05 && op < 0x0c, op == 0x0e, op >= 0x30 && op < 0x38, op == 0x77, op >= 0xa0 && op < 0xa3, op >= 0xa8 && op < 0xab, op >= 0xc8 && op < 0xd0:
+		return false, 0, true
+	case op >= 0x80 && op < 0x90:
+		return false, 4, true
+	case op == 0x0f, op >= 0x70 && op < 0x74, op == 0xa4, op == 0xac, op == 0xba, op == 0xc2, op >= 0xc4 && op < 0xc7:
+		return true, 1, true
+	}
+	return true, 0, true
+}
+
+// x86VEX decodes the VEX or EVEX prefix starting after esc and returns the
+// operand layout of the opcode that follows, plus the prefix and opcode size.
+func x86VEX(b []byte, esc byte) (modrm bool, imm, size int, ok bool) {
+	var n, mmm int
+	switch esc {
+	case 0xc5:
+		n, mmm = 1, 1
+	case 0xc4:
+		n = 2
+	case 0x62:
+		n = 3
+	}
+	if len(b) <= n {
+		return false, 0, 0, false
+	}
+	switch esc {
+	case 0xc4:
+		mmm = int(b[0] & 0x1f)
+	case 0x62:
+		mmm = int(b[0] & 7)
+	}
+	switch mmm {
+	case 1:
+		modrm, imm, ok = x86Map1(b[n])
+	case 2, 5, 6:
+		modrm, ok = true, true
+	case 3:
+		modrm, imm, ok = true, 1, true
+	}
+	return modrm, imm, n + 1, ok
+}
+
+func x86ModRMLen(b []byte) int {
+	if len(b) == 0 {
+		return 0
+	}
+	mod, rm := b[0]>>6, b[0]&7
+	n := 1
+	if mod == 3 {
+		return n
+	} else if rm == 4 {
+		if len(b) < 2 {
+			return 0
+		}
+		n++
+		if mod == 0 && b[1]&7 == 5 {
+			n += 4
+		}
+	} else if mod == 0 && rm == 5 {
+		n += 4
+	}
+	switch mod {
+	case 1:
+		n++
+	case 2:
+		n += 4
+	}
+	return n
+}
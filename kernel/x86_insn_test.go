@@ -0,0 +1,40 @@
+package kernel
+
+import "testing"
+
+func TestX86InsnLen(t *testing.T) {
+	tests := []struct {
+		name string
+		insn string
+		n    int
+	}{
+		{"syscall", "\x0f\x05", 2},
+		{"nop", "\x90", 1},
+		{"mov eax, imm32", "\xb8\x27\x00\x00\x00", 5},
+		{"movabs rax, imm64", "\x48\xb8\x01\x02\x03\x04\x05\x06\x07\x08", 10},
+		{"mov ax, imm16", "\x66\xb8\x01\x02", 4},
+		{"jmp rel32", "\xe9\x11\x22\x0f\x05", 5},
+		{"call rel32", "\xe8\x00\x00\x00\x00", 5},
+		{"mov [rip+disp32], imm32", "\xc7\x05\x00\x00\x00\x00\x0f\x05\x00\x00", 10},
+		{"lea rsi, [rsp+rax*8+8]", "\x48\x8d\x74\xc4\x08", 5},
+		{"test byte [rdi], imm8", "\xf6\x07\x01", 3},
+		{"neg qword [rdi]", "\x48\xf7\x1f", 3},
+		{"jne rel32", "\x0f\x85\x00\x01\x00\x00", 6},
+		{"pshufd", "\x66\x0f\x70\xc1\x1b", 5},
+		{"pshufb", "\x66\x0f\x38\x00\xc1", 5},
+		{"palignr", "\x66\x0f\x3a\x0f\xc1\x08", 6},
+		{"vzeroupper", "\xc5\xf8\x77", 3},
+		{"vpxor", "\xc5\xf1\xef\xc0", 4},
+		{"vpermq", "\xc4\xe3\xfd\x00\xc0\x4e", 6},
+		{"vmovdqu64 zmm", "\x62\xf1\xfe\x48\x6f\x07", 6},
+		{"lock cmpxchg", "\xf0\x48\x0f\xb1\x17", 5},
+		{"truncated", "\xe8\x00\x00", 0},
+		{"truncated 0f38", "\x0f\x38", 0},
+		{"invalid", "\x06", 0},
+	}
+	for _, tt := range tests {
+		if n := x86InsnLen([]byte(tt.insn)); n != tt.n {
+			t.Errorf("%s: len = %d, want %d", tt.name, n, tt.n)
+		}
+	}
+}
@@ -288,4 +288,198 @@ const (
 	NR_io_pgetevents
 	NR_rseq
 	NR_kexec_file_load
+	NR_pidfd_send_signal
+	NR_io_uring_setup
+	NR_io_uring_enter
+	NR_io_uring_register
+	NR_open_tree
+	NR_move_mount
+	NR_fsopen
+	NR_fsconfig
+	NR_fsmount
+	NR_fspick
+	NR_pidfd_open
+	NR_clone3
+	NR_close_range
+	NR_openat2
+	NR_pidfd_getfd
+	NR_faccessat2
+	NR_process_madvise
+	NR_epoll_pwait2
+	NR_mount_setattr
+	NR_quotactl_fd
+	NR_landlock_create_ruleset
+	NR_landlock_add_rule
+	NR_landlock_restrict_self
+	NR_memfd_secret
+	NR_process_mrelease
+	NR_futex_waitv
+	NR_set_mempolicy_home_node
+	NR_cachestat
+	NR_fchmodat2
+	NR_map_shadow_stack
+	NR_futex_wake
+	NR_futex_wait
+	NR_futex_requeue
+	NR_statmount
+	NR_listmount
+	NR_lsm_get_self_attr
+	NR_lsm_set_self_attr
+	NR_lsm_list_modules
+	NR_mseal
+	NR_setxattrat
+	NR_getxattrat
+	NR_listxattrat
+	NR_removexattrat
+	NR_fork
+	NR_creat
+	NR_link
+	NR_unlink
+	NR_mknod
+	NR_chmod
+	NR_lchown16
+	NR_setuid16
+	NR_getuid16
+	NR_pause
+	NR_access
+	NR_nice
+	NR_rename
+	NR_mkdir
+	NR_rmdir
+	NR_pipe
+	NR_setgid16
+	NR_getgid16
+	NR_geteuid16
+	NR_getegid16
+	NR_ustat
+	NR_dup2
+	NR_getpgrp
+	NR_sigaction
+	NR_setreuid16
+	NR_setregid16
+	NR_sigsuspend
+	NR_sigpending
+	NR_getgroups16
+	NR_setgroups16
+	NR_symlink
+	NR_readlink
+	NR_uselib
+	NR_fchown16
+	NR_stat
+	NR_lstat
+	NR_fstat
+	NR_sigreturn
+	NR_sigprocmask
+	NR_bdflush
+	NR_sysfs
+	NR_setfsuid16
+	NR_setfsgid16
+	NR_llseek
+	NR_getdents
+	NR_newselect
+	NR_sysctl
+	NR_setresuid16
+	NR_getresuid16
+	NR_poll
+	NR_setresgid16
+	NR_getresgid16
+	NR_chown16
+	NR_vfork
+	NR_truncate64
+	NR_ftruncate64
+	NR_stat64
+	NR_lstat64
+	NR_lchown
+	NR_chown
+	NR_fcntl64
+	NR_sendfile64
+	NR_epoll_create
+	NR_epoll_wait
+	NR_statfs64
+	NR_fstatfs64
+	NR_utimes
+	NR_fadvise64_64
+	NR_pciconfig_iobase
+	NR_pciconfig_read
+	NR_pciconfig_write
+	NR_send
+	NR_recv
+	NR_vserver
+	NR_inotify_init
+	NR_futimesat
+	NR_sync_file_range2
+	NR_signalfd
+	NR_eventfd
+	NR_clock_gettime64
+	NR_clock_settime64
+	NR_clock_adjtime64
+	NR_clock_getres_time64
+	NR_clock_nanosleep_time64
+	NR_timer_gettime64
+	NR_timer_settime64
+	NR_timerfd_gettime64
+	NR_timerfd_settime64
+	NR_utimensat_time64
+	NR_pselect6_time64
+	NR_ppoll_time64
+	NR_io_pgetevents_time64
+	NR_recvmmsg_time64
+	NR_mq_timedsend_time64
+	NR_mq_timedreceive_time64
+	NR_semtimedop_time64
+	NR_rt_sigtimedwait_time64
+	NR_futex_time64
+	NR_sched_rr_get_interval_time64
+	NR_waitpid
+	NR_time
+	NR_break
+	NR_oldstat
+	NR_umount
+	NR_stime
+	NR_alarm
+	NR_oldfstat
+	NR_utime
+	NR_stty
+	NR_gtty
+	NR_ftime
+	NR_prof
+	NR_signal
+	NR_lock
+	NR_mpx
+	NR_ulimit
+	NR_oldolduname
+	NR_sgetmask
+	NR_ssetmask
+	NR_old_getrlimit
+	NR_old_select
+	NR_oldlstat
+	NR_readdir
+	NR_old_mmap
+	NR_profil
+	NR_ioperm
+	NR_socketcall
+	NR_olduname
+	NR_iopl
+	NR_idle
+	NR_vm86old
+	NR_ipc
+	NR_modify_ldt
+	NR_create_module
+	NR_get_kernel_syms
+	NR_afs_syscall
+	NR_vm86
+	NR_query_module
+	NR_getpmsg
+	NR_putpmsg
+	NR_set_thread_area
+	NR_get_thread_area
+	NR_arch_prctl
+	NR_set_tls
+	NR_select
+	NR_tuxcall
+	NR_security
+	NR_epoll_ctl_old
+	NR_epoll_wait_old
+	NR_uretprobe
+	NR_max
 )
@@ -0,0 +1,491 @@
+package linux
+
+import "strconv"
+
+var nrNames = [...]string{
+	NR_none:                         "none",
+	NR_reject:                       "reject",
+	NR_ignore:                       "ignore",
+	NR_io_setup:                     "io_setup",
+	NR_io_destroy:                   "io_destroy",
+	NR_io_submit:                    "io_submit",
+	NR_io_cancel:                    "io_cancel",
+	NR_io_getevents:                 "io_getevents",
+	NR_setxattr:                     "setxattr",
+	NR_lsetxattr:                    "lsetxattr",
+	NR_fsetxattr:                    "fsetxattr",
+	NR_getxattr:                     "getxattr",
+	NR_lgetxattr:                    "lgetxattr",
+	NR_fgetxattr:                    "fgetxattr",
+	NR_listxattr:                    "listxattr",
+	NR_llistxattr:                   "llistxattr",
+	NR_flistxattr:                   "flistxattr",
+	NR_removexattr:                  "removexattr",
+	NR_lremovexattr:                 "lremovexattr",
+	NR_fremovexattr:                 "fremovexattr",
+	NR_getcwd:                       "getcwd",
+	NR_lookup_dcookie:               "lookup_dcookie",
+	NR_eventfd2:                     "eventfd2",
+	NR_epoll_create1:                "epoll_create1",
+	NR_epoll_ctl:                    "epoll_ctl",
+	NR_epoll_pwait:                  "epoll_pwait",
+	NR_dup:                          "dup",
+	NR_dup3:                         "dup3",
+	NR_fcntl:                        "fcntl",
+	NR_inotify_init1:                "inotify_init1",
+	NR_inotify_add_watch:            "inotify_add_watch",
+	NR_inotify_rm_watch:             "inotify_rm_watch",
+	NR_ioctl:                        "ioctl",
+	NR_ioprio_set:                   "ioprio_set",
+	NR_ioprio_get:                   "ioprio_get",
+	NR_flock:                        "flock",
+	NR_mknodat:                      "mknodat",
+	NR_mkdirat:                      "mkdirat",
+	NR_unlinkat:                     "unlinkat",
+	NR_symlinkat:                    "symlinkat",
+	NR_linkat:                       "linkat",
+	NR_renameat:                     "renameat",
+	NR_umount2:                      "umount2",
+	NR_mount:                        "mount",
+	NR_pivot_root:                   "pivot_root",
+	NR_nfsservctl:                   "nfsservctl",
+	NR_statfs:                       "statfs",
+	NR_fstatfs:                      "fstatfs",
+	NR_truncate:                     "truncate",
+	NR_ftruncate:                    "ftruncate",
+	NR_fallocate:                    "fallocate",
+	NR_faccessat:                    "faccessat",
+	NR_chdir:                        "chdir",
+	NR_fchdir:                       "fchdir",
+	NR_chroot:                       "chroot",
+	NR_fchmod:                       "fchmod",
+	NR_fchmodat:                     "fchmodat",
+	NR_fchownat:                     "fchownat",
+	NR_fchown:                       "fchown",
+	NR_open:                         "open",
+	NR_openat:                       "openat",
+	NR_close:                        "close",
+	NR_vhangup:                      "vhangup",
+	NR_pipe2:                        "pipe2",
+	NR_quotactl:                     "quotactl",
+	NR_getdents64:                   "getdents64",
+	NR_lseek:                        "lseek",
+	NR_read:                         "read",
+	NR_write:                        "write",
+	NR_readv:                        "readv",
+	NR_writev:                       "writev",
+	NR_pread64:                      "pread64",
+	NR_pwrite64:                     "pwrite64",
+	NR_preadv:                       "preadv",
+	NR_pwritev:                      "pwritev",
+	NR_sendfile:                     "sendfile",
+	NR_pselect6:                     "pselect6",
+	NR_ppoll:                        "ppoll",
+	NR_signalfd4:                    "signalfd4",
+	NR_vmsplice:                     "vmsplice",
+	NR_splice:                       "splice",
+	NR_tee:                          "tee",
+	NR_readlinkat:                   "readlinkat",
+	NR_fstatat64:                    "fstatat64",
+	NR_fstat64:                      "fstat64",
+	NR_sync:                         "sync",
+	NR_fsync:                        "fsync",
+	NR_fdatasync:                    "fdatasync",
+	NR_sync_file_range:              "sync_file_range",
+	NR_timerfd_create:               "timerfd_create",
+	NR_timerfd_settime:              "timerfd_settime",
+	NR_timerfd_gettime:              "timerfd_gettime",
+	NR_utimensat:                    "utimensat",
+	NR_acct:                         "acct",
+	NR_capget:                       "capget",
+	NR_capset:                       "capset",
+	NR_personality:                  "personality",
+	NR_exit:                         "exit",
+	NR_exit_group:                   "exit_group",
+	NR_waitid:                       "waitid",
+	NR_set_tid_address:              "set_tid_address",
+	NR_unshare:                      "unshare",
+	NR_futex:                        "futex",
+	NR_set_robust_list:              "set_robust_list",
+	NR_get_robust_list:              "get_robust_list",
+	NR_nanosleep:                    "nanosleep",
+	NR_getitimer:                    "getitimer",
+	NR_setitimer:                    "setitimer",
+	NR_kexec_load:                   "kexec_load",
+	NR_init_module:                  "init_module",
+	NR_delete_module:                "delete_module",
+	NR_timer_create:                 "timer_create",
+	NR_timer_gettime:                "timer_gettime",
+	NR_timer_getoverrun:             "timer_getoverrun",
+	NR_timer_settime:                "timer_settime",
+	NR_timer_delete:                 "timer_delete",
+	NR_clock_settime:                "clock_settime",
+	NR_clock_gettime:                "clock_gettime",
+	NR_clock_getres:                 "clock_getres",
+	NR_clock_nanosleep:              "clock_nanosleep",
+	NR_syslog:                       "syslog",
+	NR_ptrace:                       "ptrace",
+	NR_sched_setparam:               "sched_setparam",
+	NR_sched_setscheduler:           "sched_setscheduler",
+	NR_sched_getscheduler:           "sched_getscheduler",
+	NR_sched_getparam:               "sched_getparam",
+	NR_sched_setaffinity:            "sched_setaffinity",
+	NR_sched_getaffinity:            "sched_getaffinity",
+	NR_sched_yield:                  "sched_yield",
+	NR_sched_get_priority_max:       "sched_get_priority_max",
+	NR_sched_get_priority_min:       "sched_get_priority_min",
+	NR_sched_rr_get_interval:        "sched_rr_get_interval",
+	NR_restart_syscall:              "restart_syscall",
+	NR_kill:                         "kill",
+	NR_tkill:                        "tkill",
+	NR_tgkill:                       "tgkill",
+	NR_sigaltstack:                  "sigaltstack",
+	NR_rt_sigsuspend:                "rt_sigsuspend",
+	NR_rt_sigaction:                 "rt_sigaction",
+	NR_rt_sigprocmask:               "rt_sigprocmask",
+	NR_rt_sigpending:                "rt_sigpending",
+	NR_rt_sigtimedwait:              "rt_sigtimedwait",
+	NR_rt_sigqueueinfo:              "rt_sigqueueinfo",
+	NR_rt_sigreturn:                 "rt_sigreturn",
+	NR_setpriority:                  "setpriority",
+	NR_getpriority:                  "getpriority",
+	NR_reboot:                       "reboot",
+	NR_setregid:                     "setregid",
+	NR_setgid:                       "setgid",
+	NR_setreuid:                     "setreuid",
+	NR_setuid:                       "setuid",
+	NR_setresuid:                    "setresuid",
+	NR_getresuid:                    "getresuid",
+	NR_setresgid:                    "setresgid",
+	NR_getresgid:                    "getresgid",
+	NR_setfsuid:                     "setfsuid",
+	NR_setfsgid:                     "setfsgid",
+	NR_times:                        "times",
+	NR_setpgid:                      "setpgid",
+	NR_getpgid:                      "getpgid",
+	NR_getsid:                       "getsid",
+	NR_setsid:                       "setsid",
+	NR_getgroups:                    "getgroups",
+	NR_setgroups:                    "setgroups",
+	NR_uname:                        "uname",
+	NR_sethostname:                  "sethostname",
+	NR_setdomainname:                "setdomainname",
+	NR_getrlimit:                    "getrlimit",
+	NR_setrlimit:                    "setrlimit",
+	NR_getrusage:                    "getrusage",
+	NR_umask:                        "umask",
+	NR_prctl:                        "prctl",
+	NR_getcpu:                       "getcpu",
+	NR_gettimeofday:                 "gettimeofday",
+	NR_settimeofday:                 "settimeofday",
+	NR_adjtimex:                     "adjtimex",
+	NR_getpid:                       "getpid",
+	NR_getppid:                      "getppid",
+	NR_getuid:                       "getuid",
+	NR_geteuid:                      "geteuid",
+	NR_getgid:                       "getgid",
+	NR_getegid:                      "getegid",
+	NR_gettid:                       "gettid",
+	NR_sysinfo:                      "sysinfo",
+	NR_mq_open:                      "mq_open",
+	NR_mq_unlink:                    "mq_unlink",
+	NR_mq_timedsend:                 "mq_timedsend",
+	NR_mq_timedreceive:              "mq_timedreceive",
+	NR_mq_notify:                    "mq_notify",
+	NR_mq_getsetattr:                "mq_getsetattr",
+	NR_msgget:                       "msgget",
+	NR_msgctl:                       "msgctl",
+	NR_msgrcv:                       "msgrcv",
+	NR_msgsnd:                       "msgsnd",
+	NR_semget:                       "semget",
+	NR_semctl:                       "semctl",
+	NR_semtimedop:                   "semtimedop",
+	NR_semop:                        "semop",
+	NR_shmget:                       "shmget",
+	NR_shmctl:                       "shmctl",
+	NR_shmat:                        "shmat",
+	NR_shmdt:                        "shmdt",
+	NR_socket:                       "socket",
+	NR_socketpair:                   "socketpair",
+	NR_bind:                         "bind",
+	NR_listen:                       "listen",
+	NR_accept:                       "accept",
+	NR_connect:                      "connect",
+	NR_getsockname:                  "getsockname",
+	NR_getpeername:                  "getpeername",
+	NR_sendto:                       "sendto",
+	NR_recvfrom:                     "recvfrom",
+	NR_setsockopt:                   "setsockopt",
+	NR_getsockopt:                   "getsockopt",
+	NR_shutdown:                     "shutdown",
+	NR_sendmsg:                      "sendmsg",
+	NR_recvmsg:                      "recvmsg",
+	NR_readahead:                    "readahead",
+	NR_brk:                          "brk",
+	NR_munmap:                       "munmap",
+	NR_mremap:                       "mremap",
+	NR_add_key:                      "add_key",
+	NR_request_key:                  "request_key",
+	NR_keyctl:                       "keyctl",
+	NR_clone:                        "clone",
+	NR_execve:                       "execve",
+	NR_mmap:                         "mmap",
+	NR_mmap2:                        "mmap2",
+	NR_fadvise64:                    "fadvise64",
+	NR_swapon:                       "swapon",
+	NR_swapoff:                      "swapoff",
+	NR_mprotect:                     "mprotect",
+	NR_msync:                        "msync",
+	NR_mlock:                        "mlock",
+	NR_munlock:                      "munlock",
+	NR_mlockall:                     "mlockall",
+	NR_munlockall:                   "munlockall",
+	NR_mincore:                      "mincore",
+	NR_madvise:                      "madvise",
+	NR_remap_file_pages:             "remap_file_pages",
+	NR_mbind:                        "mbind",
+	NR_get_mempolicy:                "get_mempolicy",
+	NR_set_mempolicy:                "set_mempolicy",
+	NR_migrate_pages:                "migrate_pages",
+	NR_move_pages:                   "move_pages",
+	NR_rt_tgsigqueueinfo:            "rt_tgsigqueueinfo",
+	NR_perf_event_open:              "perf_event_open",
+	NR_accept4:                      "accept4",
+	NR_recvmmsg:                     "recvmmsg",
+	NR_arch_specific_syscall:        "arch_specific_syscall",
+	NR_wait4:                        "wait4",
+	NR_prlimit64:                    "prlimit64",
+	NR_fanotify_init:                "fanotify_init",
+	NR_fanotify_mark:                "fanotify_mark",
+	NR_name_to_handle_at:            "name_to_handle_at",
+	NR_open_by_handle_at:            "open_by_handle_at",
+	NR_clock_adjtime:                "clock_adjtime",
+	NR_syncfs:                       "syncfs",
+	NR_setns:                        "setns",
+	NR_sendmmsg:                     "sendmmsg",
+	NR_process_vm_readv:             "process_vm_readv",
+	NR_process_vm_writev:            "process_vm_writev",
+	NR_kcmp:                         "kcmp",
+	NR_finit_module:                 "finit_module",
+	NR_sched_setattr:                "sched_setattr",
+	NR_sched_getattr:                "sched_getattr",
+	NR_renameat2:                    "renameat2",
+	NR_seccomp:                      "seccomp",
+	NR_getrandom:                    "getrandom",
+	NR_memfd_create:                 "memfd_create",
+	NR_bpf:                          "bpf",
+	NR_execveat:                     "execveat",
+	NR_userfaultfd:                  "userfaultfd",
+	NR_membarrier:                   "membarrier",
+	NR_mlock2:                       "mlock2",
+	NR_copy_file_range:              "copy_file_range",
+	NR_preadv2:                      "preadv2",
+	NR_pwritev2:                     "pwritev2",
+	NR_pkey_mprotect:                "pkey_mprotect",
+	NR_pkey_alloc:                   "pkey_alloc",
+	NR_pkey_free:                    "pkey_free",
+	NR_statx:                        "statx",
+	NR_io_pgetevents:                "io_pgetevents",
+	NR_rseq:                         "rseq",
+	NR_kexec_file_load:              "kexec_file_load",
+	NR_pidfd_send_signal:            "pidfd_send_signal",
+	NR_io_uring_setup:               "io_uring_setup",
+	NR_io_uring_enter:               "io_uring_enter",
+	NR_io_uring_register:            "io_uring_register",
+	NR_open_tree:                    "open_tree",
+	NR_move_mount:                   "move_mount",
+	NR_fsopen:                       "fsopen",
+	NR_fsconfig:                     "fsconfig",
+	NR_fsmount:                      "fsmount",
+	NR_fspick:                       "fspick",
+	NR_pidfd_open:                   "pidfd_open",
+	NR_clone3:                       "clone3",
+	NR_close_range:                  "close_range",
+	NR_openat2:                      "openat2",
+	NR_pidfd_getfd:                  "pidfd_getfd",
+	NR_faccessat2:                   "faccessat2",
+	NR_process_madvise:              "process_madvise",
+	NR_epoll_pwait2:                 "epoll_pwait2",
+	NR_mount_setattr:                "mount_setattr",
+	NR_quotactl_fd:                  "quotactl_fd",
+	NR_landlock_create_ruleset:      "landlock_create_ruleset",
+	NR_landlock_add_rule:            "landlock_add_rule",
+	NR_landlock_restrict_self:       "landlock_restrict_self",
+	NR_memfd_secret:                 "memfd_secret",
+	NR_process_mrelease:             "process_mrelease",
+	NR_futex_waitv:                  "futex_waitv",
+	NR_set_mempolicy_home_node:      "set_mempolicy_home_node",
+	NR_cachestat:                    "cachestat",
+	NR_fchmodat2:                    "fchmodat2",
+	NR_map_shadow_stack:             "map_shadow_stack",
+	NR_futex_wake:                   "futex_wake",
+	NR_futex_wait:                   "futex_wait",
+	NR_futex_requeue:                "futex_requeue",
+	NR_statmount:                    "statmount",
+	NR_listmount:                    "listmount",
+	NR_lsm_get_self_attr:            "lsm_get_self_attr",
+	NR_lsm_set_self_attr:            "lsm_set_self_attr",
+	NR_lsm_list_modules:             "lsm_list_modules",
+	NR_mseal:                        "mseal",
+	NR_setxattrat:                   "setxattrat",
+	NR_getxattrat:                   "getxattrat",
+	NR_listxattrat:                  "listxattrat",
+	NR_removexattrat:                "removexattrat",
+	NR_fork:                         "fork",
+	NR_creat:                        "creat",
+	NR_link:                         "link",
+	NR_unlink:                       "unlink",
+	NR_mknod:                        "mknod",
+	NR_chmod:                        "chmod",
+	NR_lchown16:                     "lchown16",
+	NR_setuid16:                     "setuid16",
+	NR_getuid16:                     "getuid16",
+	NR_pause:                        "pause",
+	NR_access:                       "access",
+	NR_nice:                         "nice",
+	NR_rename:                       "rename",
+	NR_mkdir:                        "mkdir",
+	NR_rmdir:                        "rmdir",
+	NR_pipe:                         "pipe",
+	NR_setgid16:                     "setgid16",
+	NR_getgid16:                     "getgid16",
+	NR_geteuid16:                    "geteuid16",
+	NR_getegid16:                    "getegid16",
+	NR_ustat:                        "ustat",
+	NR_dup2:                         "dup2",
+	NR_getpgrp:                      "getpgrp",
+	NR_sigaction:                    "sigaction",
+	NR_setreuid16:                   "setreuid16",
+	NR_setregid16:                   "setregid16",
+	NR_sigsuspend:                   "sigsuspend",
+	NR_sigpending:                   "sigpending",
+	NR_getgroups16:                  "getgroups16",
+	NR_setgroups16:                  "setgroups16",
+	NR_symlink:                      "symlink",
+	NR_readlink:                     "readlink",
+	NR_uselib:                       "uselib",
+	NR_fchown16:                     "fchown16",
+	NR_stat:                         "stat",
+	NR_lstat:                        "lstat",
+	NR_fstat:                        "fstat",
+	NR_sigreturn:                    "sigreturn",
+	NR_sigprocmask:                  "sigprocmask",
+	NR_bdflush:                      "bdflush",
+	NR_sysfs:                        "sysfs",
+	NR_setfsuid16:                   "setfsuid16",
+	NR_setfsgid16:                   "setfsgid16",
+	NR_llseek:                       "llseek",
+	NR_getdents:                     "getdents",
+	NR_newselect:                    "newselect",
+	NR_sysctl:                       "sysctl",
+	NR_setresuid16:                  "setresuid16",
+	NR_getresuid16:                  "getresuid16",
+	NR_poll:                         "poll",
+	NR_setresgid16:                  "setresgid16",
+	NR_getresgid16:                  "getresgid16",
+	NR_chown16:                      "chown16",
+	NR_vfork:                        "vfork",
+	NR_truncate64:                   "truncate64",
+	NR_ftruncate64:                  "ftruncate64",
+	NR_stat64:                       "stat64",
+	NR_lstat64:                      "lstat64",
+	NR_lchown:                       "lchown",
+	NR_chown:                        "chown",
+	NR_fcntl64:                      "fcntl64",
+	NR_sendfile64:                   "sendfile64",
+	NR_epoll_create:                 "epoll_create",
+	NR_epoll_wait:                   "epoll_wait",
+	NR_statfs64:                     "statfs64",
+	NR_fstatfs64:                    "fstatfs64",
+	NR_utimes:                       "utimes",
+	NR_fadvise64_64:                 "fadvise64_64",
+	NR_pciconfig_iobase:             "pciconfig_iobase",
+	NR_pciconfig_read:               "pciconfig_read",
+	NR_pciconfig_write:              "pciconfig_write",
+	NR_send:                         "send",
+	NR_recv:                         "recv",
+	NR_vserver:                      "vserver",
+	NR_inotify_init:                 "inotify_init",
+	NR_futimesat:                    "futimesat",
+	NR_sync_file_range2:             "sync_file_range2",
+	NR_signalfd:                     "signalfd",
+	NR_eventfd:                      "eventfd",
+	NR_clock_gettime64:              "clock_gettime64",
+	NR_clock_settime64:              "clock_settime64",
+	NR_clock_adjtime64:              "clock_adjtime64",
+	NR_clock_getres_time64:          "clock_getres_time64",
+	NR_clock_nanosleep_time64:       "clock_nanosleep_time64",
+	NR_timer_gettime64:              "timer_gettime64",
+	NR_timer_settime64:              "timer_settime64",
+	NR_timerfd_gettime64:            "timerfd_gettime64",
+	NR_timerfd_settime64:            "timerfd_settime64",
+	NR_utimensat_time64:             "utimensat_time64",
+	NR_pselect6_time64:              "pselect6_time64",
+	NR_ppoll_time64:                 "ppoll_time64",
+	NR_io_pgetevents_time64:         "io_pgetevents_time64",
+	NR_recvmmsg_time64:              "recvmmsg_time64",
+	NR_mq_timedsend_time64:          "mq_timedsend_time64",
+	NR_mq_timedreceive_time64:       "mq_timedreceive_time64",
+	NR_semtimedop_time64:            "semtimedop_time64",
+	NR_rt_sigtimedwait_time64:       "rt_sigtimedwait_time64",
+	NR_futex_time64:                 "futex_time64",
+	NR_sched_rr_get_interval_time64: "sched_rr_get_interval_time64",
+	NR_waitpid:                      "waitpid",
+	NR_time:                         "time",
+	NR_break:                        "break",
+	NR_oldstat:                      "oldstat",
+	NR_umount:                       "umount",
+	NR_stime:                        "stime",
+	NR_alarm:                        "alarm",
+	NR_oldfstat:                     "oldfstat",
+	NR_utime:                        "utime",
+	NR_stty:                         "stty",
+	NR_gtty:                         "gtty",
+	NR_ftime:                        "ftime",
+	NR_prof:                         "prof",
+	NR_signal:                       "signal",
+	NR_lock:                         "lock",
+	NR_mpx:                          "mpx",
+	NR_ulimit:                       "ulimit",
+	NR_oldolduname:                  "oldolduname",
+	NR_sgetmask:                     "sgetmask",
+	NR_ssetmask:                     "ssetmask",
+	NR_old_getrlimit:                "old_getrlimit",
+	NR_old_select:                   "old_select",
+	NR_oldlstat:                     "oldlstat",
+	NR_readdir:                      "readdir",
+	NR_old_mmap:                     "old_mmap",
+	NR_profil:                       "profil",
+	NR_ioperm:                       "ioperm",
+	NR_socketcall:                   "socketcall",
+	NR_olduname:                     "olduname",
+	NR_iopl:                         "iopl",
+	NR_idle:                         "idle",
+	NR_vm86old:                      "vm86old",
+	NR_ipc:                          "ipc",
+	NR_modify_ldt:                   "modify_ldt",
+	NR_create_module:                "create_module",
+	NR_get_kernel_syms:              "get_kernel_syms",
+	NR_afs_syscall:                  "afs_syscall",
+	NR_vm86:                         "vm86",
+	NR_query_module:                 "query_module",
+	NR_getpmsg:                      "getpmsg",
+	NR_putpmsg:                      "putpmsg",
+	NR_set_thread_area:              "set_thread_area",
+	NR_get_thread_area:              "get_thread_area",
+	NR_arch_prctl:                   "arch_prctl",
+	NR_set_tls:                      "set_tls",
+	NR_select:                       "select",
+	NR_tuxcall:                      "tuxcall",
+	NR_security:                     "security",
+	NR_epoll_ctl_old:                "epoll_ctl_old",
+	NR_epoll_wait_old:               "epoll_wait_old",
+	NR_uretprobe:                    "uretprobe",
+}
+
+func (nr NR) String() string {
+	if nr >= 0 && nr < NR(len(nrNames)) {
+		return nrNames[nr]
+	}
+	return "NR(" + strconv.Itoa(int(nr)) + ")"
+}